@@ -0,0 +1,138 @@
+package trie
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// accessBuckets is the number of buckets the window of the access statistics
+// is divided into. Reads leave the window one bucket at a time.
+const accessBuckets = 10
+
+// HotKey is a path and the number of times it has been read, see HotKeys.
+type HotKey struct {
+	Path string
+	Hits int
+}
+
+// accessStats counts the reads of each path in a sliding window, see
+// WithAccessStats.
+type accessStats struct {
+	clock func() time.Time
+	width time.Duration
+
+	lock sync.Mutex
+	// epochs contains the number of the interval of length width since the
+	// Unix epoch that is counted by the bucket at the same index.
+	epochs  [accessBuckets]int64
+	buckets [accessBuckets]map[string]int
+}
+
+// WithAccessStats makes the trie count how often each path is read by Get,
+// GetE, Lookup, GetSegments and GetBatch, see HotKeys. Only reads that find
+// the node are counted. The counts cover the given window, which advances in
+// steps of a tenth of its length. If clock is nil, time.Now is used. Every
+// counted read acquires a lock shared by the whole trie and the first read of
+// a path in each step allocates. It does not apply to Slice.
+func WithAccessStats(window time.Duration, clock func() time.Time) Option {
+	return func(o *options) {
+		o.accessWindow = window
+		o.accessClock = clock
+	}
+}
+
+func newAccessStats(window time.Duration, clock func() time.Time) *accessStats {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &accessStats{clock: clock, width: max(window/accessBuckets, 1)}
+}
+
+// epoch returns the number of the current interval.
+func (s *accessStats) epoch() int64 {
+	return s.clock().UnixNano() / int64(s.width)
+}
+
+func (s *accessStats) record(path string) {
+	e := s.epoch()
+	// The epoch is negative for times before 1970.
+	i := (e%accessBuckets + accessBuckets) % accessBuckets
+
+	s.lock.Lock()
+	if s.epochs[i] != e || s.buckets[i] == nil {
+		s.epochs[i] = e
+		s.buckets[i] = make(map[string]int)
+	}
+	s.buckets[i][path]++
+	s.lock.Unlock()
+}
+
+// hits returns the number of reads within the window of all paths accepted by
+// keep.
+func (s *accessStats) hits(keep func(path string) bool) map[string]int {
+	e := s.epoch()
+	hits := make(map[string]int)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, bucket := range s.buckets {
+		// Buckets of epochs after the current one are left over from
+		// before the clock has been set back.
+		if s.epochs[i] <= e-accessBuckets || s.epochs[i] > e {
+			continue
+		}
+		for path, n := range bucket {
+			if keep(path) {
+				hits[path] += n
+			}
+		}
+	}
+	return hits
+}
+
+// recordAccess counts a read of path, which must be normalized.
+func (t *stringTrie[V]) recordAccess(path string) {
+	t.access.record(t.cacheKey(path))
+}
+
+// recordSegments counts a read of the node at segments as passed to
+// GetSegments.
+func (t *stringTrie[V]) recordSegments(segments []string) {
+	if t.transform != nil {
+		transformed := make([]string, len(segments))
+		for i, key := range segments {
+			transformed[i] = t.transform(key)
+		}
+		segments = transformed
+	}
+	t.access.record(t.joinSegments(segments))
+}
+
+// HotKeys returns nil if access statistics are not enabled.
+func (t *stringTrie[V]) HotKeys(k int) []HotKey {
+	if t.access == nil {
+		return nil
+	}
+	return topHits(t.access.hits(func(string) bool { return true }), k)
+}
+
+// topHits returns the k entries of hits with the most hits, ordered by
+// descending hits and ascending path.
+func topHits(hits map[string]int, k int) []HotKey {
+	if k <= 0 {
+		return nil
+	}
+
+	keys := make([]HotKey, 0, len(hits))
+	for path, n := range hits {
+		keys = append(keys, HotKey{Path: path, Hits: n})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Hits == keys[j].Hits {
+			return keys[i].Path < keys[j].Path
+		}
+		return keys[i].Hits > keys[j].Hits
+	})
+	return keys[:min(k, len(keys))]
+}
@@ -0,0 +1,147 @@
+// Package acl implements access control lists for hierarchical resources.
+// Grants and denies are stored at resource paths in a String trie and are
+// inherited by all resources below them, a check combines the rules of all
+// nodes along the path of the resource.
+package acl
+
+import (
+	"slices"
+	"sync"
+
+	"moehl.dev/trie"
+)
+
+// Any matches every principal or action when used in a rule.
+const Any = "*"
+
+// Effect of a rule.
+type Effect uint8
+
+const (
+	// Allow grants the action to the principal.
+	Allow Effect = iota + 1
+	// Deny forbids the action to the principal.
+	Deny
+)
+
+func (e Effect) String() string {
+	switch e {
+	case Allow:
+		return "Allow"
+	case Deny:
+		return "Deny"
+	}
+	return "Effect(?)"
+}
+
+// Mode determines how inherited rules are combined.
+type Mode uint8
+
+const (
+	// DenyOverrides denies an action if any rule along the path denies it,
+	// no matter how specific the rules granting it are.
+	DenyOverrides Mode = iota
+	// NearestWins uses the rules of the node closest to the resource that
+	// has a rule for the principal and action, so a grant on a resource can
+	// lift a deny on one of its parents. If that node both grants and
+	// denies the action, it is denied.
+	NearestWins
+)
+
+// Rule grants or denies an action to a principal.
+type Rule struct {
+	Principal string
+	Action    string
+	Effect    Effect
+}
+
+func (r Rule) matches(principal, action string) bool {
+	return (r.Principal == Any || r.Principal == principal) && (r.Action == Any || r.Action == action)
+}
+
+// ACL contains the rules of all resources. Actions are denied unless a rule
+// grants them. An ACL is safe for concurrent use.
+type ACL struct {
+	mode Mode
+
+	// lock serializes changes, the slices stored in rules are never
+	// modified so checks do not need to lock.
+	lock  sync.Mutex
+	rules trie.String[[]Rule]
+}
+
+// New creates an ACL without any rules for resource paths delimited by
+// delimiter.
+func New(delimiter string, mode Mode) *ACL {
+	return &ACL{
+		mode:  mode,
+		rules: trie.New[[]Rule](delimiter),
+	}
+}
+
+// Grant the action on the resource at path and all resources below it to the
+// principal. Either may be Any.
+func (a *ACL) Grant(path, principal, action string) {
+	a.add(path, Rule{principal, action, Allow})
+}
+
+// Deny the action on the resource at path and all resources below it to the
+// principal. Either may be Any.
+func (a *ACL) Deny(path, principal, action string) {
+	a.add(path, Rule{principal, action, Deny})
+}
+
+func (a *ACL) add(path string, r Rule) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	rules, _ := a.rules.Get(path)
+	if slices.Contains(rules, r) {
+		return
+	}
+	a.rules.Put(path, append(rules[:len(rules):len(rules)], r))
+}
+
+// Revoke removes the grants and denies of the action to the principal at
+// path. Rules inherited from parents or using Any are not affected.
+func (a *ACL) Revoke(path, principal, action string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	rules, _ := a.rules.Get(path)
+	rules = slices.DeleteFunc(slices.Clone(rules), func(r Rule) bool {
+		return r.Principal == principal && r.Action == action
+	})
+	a.rules.Put(path, rules)
+}
+
+// Rules returns the rules set at path, without the inherited ones.
+func (a *ACL) Rules(path string) []Rule {
+	rules, _ := a.rules.Get(path)
+	return slices.Clone(rules)
+}
+
+// Check reports whether the principal may perform the action on the resource
+// at path.
+func (a *ACL) Check(principal, action, path string) bool {
+	var allowed, denied bool
+	a.rules.WalkPath(path, func(_ string, rules []Rule) bool {
+		var allow, deny bool
+		for _, r := range rules {
+			if r.matches(principal, action) {
+				allow = allow || r.Effect == Allow
+				deny = deny || r.Effect == Deny
+			}
+		}
+		switch {
+		case a.mode == DenyOverrides:
+			allowed, denied = allowed || allow, denied || deny
+			// Nothing can lift a deny.
+			return !denied
+		case allow || deny:
+			allowed, denied = !deny, deny
+		}
+		return true
+	})
+	return allowed && !denied
+}
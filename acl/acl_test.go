@@ -0,0 +1,67 @@
+package acl_test
+
+import (
+	"strings"
+	"testing"
+
+	"moehl.dev/trie/acl"
+)
+
+func TestACLCheck(t *testing.T) {
+	for _, tc := range []struct {
+		mode     acl.Mode
+		expected map[string]bool
+	}{
+		{acl.DenyOverrides, map[string]bool{
+			"alice read docs":               true,
+			"alice write docs":              false,
+			"alice read docs/secret":        false,
+			"alice read docs/secret/public": false,
+			"bob read docs":                 true,
+			"bob write docs/bob":            true,
+			"bob write docs/alice":          false,
+			"eve read other":                false,
+		}},
+		{acl.NearestWins, map[string]bool{
+			"alice read docs":               true,
+			"alice write docs":              false,
+			"alice read docs/secret":        false,
+			"alice read docs/secret/public": true,
+			"bob read docs":                 true,
+			"bob write docs/bob":            true,
+			"bob write docs/alice":          false,
+			"eve read other":                false,
+		}},
+	} {
+		a := acl.New("/", tc.mode)
+		a.Grant("docs", acl.Any, "read")
+		a.Deny("docs/secret", acl.Any, acl.Any)
+		a.Grant("docs/secret/public", acl.Any, "read")
+		a.Grant("docs/bob", "bob", acl.Any)
+
+		for check, expected := range tc.expected {
+			f := strings.Fields(check)
+			if got := a.Check(f[0], f[1], f[2]); got != expected {
+				t.Errorf("%d: %s: expected %v but got %v", tc.mode, check, expected, got)
+			}
+		}
+	}
+}
+
+func TestACLRevoke(t *testing.T) {
+	a := acl.New("/", acl.DenyOverrides)
+	a.Grant("", "alice", "read")
+	a.Grant("", "alice", "read")
+	a.Deny("projects/x", "alice", "read")
+	if rules := a.Rules(""); len(rules) != 1 {
+		t.Errorf("expected duplicate rules to be ignored but got %v", rules)
+	}
+	if a.Check("alice", "read", "projects/x/y") {
+		t.Errorf("expected read of projects/x/y to be denied")
+	}
+
+	a.Revoke("projects/x", "alice", "read")
+	if !a.Check("alice", "read", "projects/x/y") {
+		t.Errorf("expected read of projects/x/y to be allowed after the revoke")
+	}
+}
@@ -0,0 +1,45 @@
+package trie
+
+import (
+	"sync"
+)
+
+// Chunks allocated by an arena start small and double in size up to
+// maxArenaChunk nodes so that small tries do not waste memory.
+const (
+	minArenaChunk = 16
+	maxArenaChunk = 1024
+)
+
+// arena allocates nodes from chunks instead of allocating every node on its
+// own. Nodes are never freed individually, a chunk is only garbage collected
+// once none of its nodes are referenced anymore.
+type arena[K comparable, V any] struct {
+	lock  sync.Mutex
+	chunk []node[K, V]
+	size  int
+}
+
+// alloc returns a new zero node.
+func (a *arena[K, V]) alloc() *node[K, V] {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if len(a.chunk) == 0 {
+		a.size = min(max(2*a.size, minArenaChunk), maxArenaChunk)
+		a.chunk = make([]node[K, V], a.size)
+	}
+
+	n := &a.chunk[0]
+	a.chunk = a.chunk[1:]
+	return n
+}
+
+// reset drops the current chunk so that the nodes allocated so far can be
+// garbage collected as a whole.
+func (a *arena[K, V]) reset() {
+	a.lock.Lock()
+	a.chunk = nil
+	a.size = 0
+	a.lock.Unlock()
+}
@@ -0,0 +1,250 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Backend is a key-value store that a Persistent trie writes through to, e.g.
+// a bbolt bucket or a pebble database. Keys are the full paths of the trie in
+// their canonical form, i.e. normalized and with all segments escaped and
+// joined by the delimiter. Since many stores reject empty keys, the root is
+// stored under the key "\x00" and the keys of paths starting with a NUL byte
+// are prefixed with another one, no key is ever empty.
+type Backend interface {
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	// Iterate calls fn for every key that starts with prefix in ascending
+	// order until fn returns false.
+	Iterate(prefix []byte, fn func(key, value []byte) bool) error
+}
+
+// Persistent wraps a String trie and writes every Put, Delete and Clear through
+// to a Backend before it is applied to the trie. Reads are served from the trie
+// only.
+//
+// Operations that cannot be written to the backend are not applied to the
+// trie, the first error that occurred is returned by Err.
+type Persistent[V any] struct {
+	String[V]
+
+	// st is the trie at the bottom of String, it defines the canonical
+	// form of the paths.
+	st      *stringTrie[V]
+	lock    *sync.Mutex
+	backend Backend
+	err     error
+}
+
+// NewPersistent loads all entries of backend into t and returns a trie that
+// writes all changes through to backend.
+func NewPersistent[V any](t String[V], backend Backend) (*Persistent[V], error) {
+	codec := t.Codec()
+
+	var err error
+	iterErr := backend.Iterate(nil, func(key, value []byte) bool {
+		var v V
+		v, err = codec.Decode(value)
+		if err != nil {
+			err = fmt.Errorf("trie: decode value of '%s': %w", key, err)
+			return false
+		}
+		t.Put(backendPath(key), v)
+		return true
+	})
+	if iterErr != nil {
+		return nil, iterErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Persistent[V]{
+		String:  t,
+		st:      t.Root().t,
+		lock:    new(sync.Mutex),
+		backend: backend,
+	}, nil
+}
+
+// Err returns the first error that occurred while writing to the backend.
+func (p *Persistent[V]) Err() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.err
+}
+
+func (p *Persistent[V]) Put(path string, value V) {
+	if p.CheckPath(path) != nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	b, err := p.Codec().Encode(value)
+	if err != nil {
+		p.setErr(fmt.Errorf("trie: encode value: %w", err))
+		return
+	}
+
+	err = p.backend.Put(backendKey(p.st.canonical(path)), b)
+	if err != nil {
+		p.setErr(err)
+		return
+	}
+
+	p.String.Put(path, value)
+}
+
+// Rekey is applied as a sequence of Delete and Put operations which are
+// written through to the backend, unlike the Rekey of the String trie it is
+// not atomic.
+func (p *Persistent[V]) Rekey(fn func(oldPath string) (newPath string, keep bool)) error {
+	return rekeyEach[V](p, fn)
+}
+
+func (p *Persistent[V]) PutSegments(value V, segments ...string) {
+	p.Put(p.st.joinSegments(segments), value)
+}
+
+// PutE is like Put but returns the error of the key validator if the path is
+// rejected.
+func (p *Persistent[V]) PutE(path string, value V) error {
+	err := p.CheckPath(path)
+	if err != nil {
+		return err
+	}
+	p.Put(path, value)
+	return nil
+}
+
+// Delete removes path and all paths below it from the backend and the trie.
+func (p *Persistent[V]) Delete(path string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	err := p.deleteBackend(path)
+	if err != nil {
+		p.setErr(err)
+		return
+	}
+
+	p.String.Delete(path)
+}
+
+// DeleteE is like Delete but returns an error wrapping ErrNotFound if the
+// node does not exist.
+func (p *Persistent[V]) DeleteE(path string) error {
+	_, err := p.GetE(path)
+	if err != nil {
+		return err
+	}
+	p.Delete(path)
+	return nil
+}
+
+// Root returns a handle to the root of the trie, its Set writes to the
+// backend just like Put.
+func (p *Persistent[V]) Root() Node[V] {
+	return rootWithPut(p.String, p.Put)
+}
+
+// Clear removes all keys from the backend and the trie.
+func (p *Persistent[V]) Clear() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	err := p.deleteKeys(nil, func([]byte) bool { return true })
+	if err != nil {
+		p.setErr(err)
+		return
+	}
+
+	p.String.Clear()
+}
+
+// rootKey is the backend key of the root, see Backend.
+const rootKey = "\x00"
+
+// backendKey returns the backend key of the canonical path.
+func backendKey(path string) []byte {
+	if path == "" || path[0] == rootKey[0] {
+		return []byte(rootKey + path)
+	}
+	return []byte(path)
+}
+
+// backendPath is the inverse of backendKey.
+func backendPath(key []byte) string {
+	if len(key) > 0 && key[0] == rootKey[0] {
+		return string(key[1:])
+	}
+	return string(key)
+}
+
+func (p *Persistent[V]) deleteBackend(path string) error {
+	path = p.st.canonical(path)
+	key := backendKey(path)
+	if path == "" {
+		// Deleting the root only removes its value.
+		return p.deleteKeys(key, func(k []byte) bool {
+			return bytes.Equal(k, key)
+		})
+	}
+	// The keys of the paths below start with key as well.
+	below := append(bytes.Clone(key), p.Delimiter()...)
+	return p.deleteKeys(key, func(k []byte) bool {
+		return bytes.Equal(k, key) || bytes.HasPrefix(k, below)
+	})
+}
+
+// deleteKeys deletes all keys starting with prefix that are accepted by match
+// from the backend.
+func (p *Persistent[V]) deleteKeys(prefix []byte, match func(key []byte) bool) error {
+	// Collect the keys first as backends may not support modifications
+	// while iterating.
+	var keys [][]byte
+	err := p.backend.Iterate(prefix, func(k, _ []byte) bool {
+		if match(k) {
+			keys = append(keys, bytes.Clone(k))
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		err = p.backend.Delete(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFrom reads a trie in the binary format and puts all of its values into
+// the persistent trie one by one.
+func (p *Persistent[V]) ReadFrom(r io.Reader) (int64, error) {
+	t := newStringTrie[V](p.Delimiter())
+	t.codec = p.Codec()
+	n, err := t.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+
+	t.Walk(func(path string, value V) bool {
+		p.Put(path, value)
+		return true
+	})
+	return n, p.Err()
+}
+
+func (p *Persistent[V]) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
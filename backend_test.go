@@ -0,0 +1,161 @@
+package trie_test
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"moehl.dev/trie"
+)
+
+type mapBackend map[string][]byte
+
+func (b mapBackend) Put(key, value []byte) error {
+	b[string(key)] = value
+	return nil
+}
+
+func (b mapBackend) Delete(key []byte) error {
+	delete(b, string(key))
+	return nil
+}
+
+func (b mapBackend) Iterate(prefix []byte, fn func(key, value []byte) bool) error {
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		if strings.HasPrefix(k, string(prefix)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !fn([]byte(k), b[k]) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestPersistent(t *testing.T) {
+	backend := mapBackend{}
+
+	p, err := trie.NewPersistent(trie.New[int]("/"), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Put("foo/bar", 1)
+	p.Put("foo/baz", 2)
+	p.Put("foobar", 3)
+	p.Delete("foo")
+	p.Put("qux", 4)
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restored, err := trie.NewPersistent(trie.New[int]("/"), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]int{"foobar": 3, "qux": 4}
+	if got := restored.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestPersistentClear(t *testing.T) {
+	backend := mapBackend{}
+
+	p, err := trie.NewPersistent(trie.New[int]("/"), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Put("foo/bar", 1)
+	p.Put("baz", 2)
+	p.Clear()
+	p.Put("qux", 3)
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restored, err := trie.NewPersistent(trie.New[int]("/"), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]int{"qux": 3}
+	if got := restored.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestPersistentCanonicalKeys(t *testing.T) {
+	backend := mapBackend{}
+	newTrie := func() trie.String[int] {
+		return trie.New[int]("/", trie.WithTrimmedDelimiters(), trie.WithCaseFolding())
+	}
+
+	p, err := trie.NewPersistent(newTrie(), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Put("/Foo/Bar/", 1)
+	p.Put("foo/bar", 2)
+	p.Put("", 3)
+	p.Put("foo/baz", 4)
+	p.Delete("")
+	p.Delete("FOO/BAZ")
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	keys := make([]string, 0, len(backend))
+	for k := range backend {
+		keys = append(keys, k)
+	}
+	if expected := []string{"foo/bar"}; !reflect.DeepEqual(expected, keys) {
+		t.Errorf("expected keys '%v' but got '%v'", expected, keys)
+	}
+
+	restored, err := trie.NewPersistent(newTrie(), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]int{"foo/bar": 2}
+	if got := restored.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestPersistentRootKey(t *testing.T) {
+	backend := mapBackend{}
+	p, err := trie.NewPersistent(trie.New[int]("/"), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Put("", 1)
+	p.Put("\x00", 2)
+	p.Put("\x00/a", 3)
+	p.Put("a", 4)
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := backend[""]; ok {
+		t.Errorf("expected no empty key")
+	}
+
+	restored, err := trie.NewPersistent(trie.New[int]("/"), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]int{"": 1, "\x00": 2, "\x00/a": 3, "a": 4}
+	if got := restored.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+
+	restored.Delete("")
+	restored.Delete("\x00")
+	if len(backend) != 1 {
+		t.Errorf("expected only key 'a' to be left but got '%v'", backend)
+	}
+}
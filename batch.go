@@ -0,0 +1,124 @@
+package trie
+
+import (
+	"sort"
+	"strings"
+)
+
+// batchItem is a path of a batch lookup. rest contains the part of the path
+// that has not been followed yet.
+type batchItem struct {
+	i    int
+	rest string
+}
+
+func (t *stringTrie[V]) GetBatch(paths []string, out []V) []bool {
+	span := t.startSpan("trie.GetBatch")
+	defer span.End()
+	span.SetAttribute("trie.paths", len(paths))
+
+	found := make([]bool, len(paths))
+	var zero V
+	for i := range paths {
+		out[i] = zero
+	}
+
+	// Sorting the paths segment by segment ensures that all paths below a
+	// node are adjacent.
+	items := make([]batchItem, len(paths))
+	for i, path := range paths {
+		items[i] = batchItem{i: i, rest: t.normalize(path)}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return t.compareSegments(items[i].rest, items[j].rest) < 0
+	})
+
+	t.root.lock.RLock()
+	t.getBatch(t.root, nil, items, out, found)
+	t.root.lock.RUnlock()
+
+	if t.access != nil {
+		for i, path := range paths {
+			if found[i] {
+				t.recordAccess(t.normalize(path))
+			}
+		}
+	}
+
+	if t.tracer != nil {
+		hits := 0
+		for _, ok := range found {
+			if ok {
+				hits++
+			}
+		}
+		span.SetAttribute("trie.found", hits)
+	}
+	return found
+}
+
+// getBatch resolves items relative to the node that is reached by following
+// run from n. The caller must hold the read lock of n.
+func (t *stringTrie[V]) getBatch(n *node[string, V], run []string, items []batchItem, out []V, found []bool) {
+	for len(items) > 0 && items[0].rest == "" {
+		if len(run) == 0 {
+			out[items[0].i] = n.value
+		}
+		// The root is only found if it has a value, see String.Get.
+		found[items[0].i] = n != t.root || n.hasValue
+		items = items[1:]
+	}
+
+	for len(items) > 0 {
+		key, _ := t.cut(items[0].rest)
+
+		// Advance all items with the same key to the next segment.
+		end := 0
+		for end < len(items) {
+			k, rest := t.cut(items[end].rest)
+			if k != key {
+				break
+			}
+			items[end].rest = rest
+			end++
+		}
+		group := items[:end]
+		items = items[end:]
+
+		switch {
+		case len(run) > 0:
+			if run[0] == key {
+				t.getBatch(n, run[1:], group, out, found)
+			}
+		default:
+			child, ok := n.children.get(key)
+			if ok {
+				child.lock.RLock()
+				t.getBatch(child, child.segments[1:], group, out, found)
+				child.lock.RUnlock()
+			}
+		}
+	}
+}
+
+// compareSegments compares a and b segment by segment. A path sorts before
+// all paths that it is a prefix of.
+func (t *stringTrie[V]) compareSegments(a, b string) int {
+	for a != "" && b != "" {
+		var ka, kb string
+		ka, a = t.cut(a)
+		kb, b = t.cut(b)
+		if ka != kb {
+			return strings.Compare(ka, kb)
+		}
+	}
+
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,59 @@
+// Package bbolt implements trie.Backend on top of a bbolt bucket. It lives
+// in its own module to avoid forcing the bbolt dependency on users of the
+// trie package.
+package bbolt
+
+import (
+	"bytes"
+
+	bolt "go.etcd.io/bbolt"
+
+	"moehl.dev/trie"
+)
+
+// Backend stores the entries of a trie in a single bucket. Every Put and
+// Delete is executed in its own transaction.
+type Backend struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+var _ trie.Backend = (*Backend)(nil)
+
+// New creates the bucket if it does not exist yet and returns a backend
+// storing its entries in it.
+func New(db *bolt.DB, bucket string) (*Backend, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backend{db: db, bucket: []byte(bucket)}, nil
+}
+
+func (b *Backend) Put(key, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Put(key, value)
+	})
+}
+
+func (b *Backend) Delete(key []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Delete(key)
+	})
+}
+
+func (b *Backend) Iterate(prefix []byte, fn func(key, value []byte) bool) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(b.bucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if !fn(k, v) {
+				break
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,50 @@
+package bbolt_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"moehl.dev/trie"
+	"moehl.dev/trie/bbolt"
+)
+
+func TestBackend(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "bolt.db"), 0o600, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	backend, err := bbolt.New(db, "trie")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p, err := trie.NewPersistent(trie.New[int]("/"), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Put("", 0)
+	p.Put("foo/bar", 1)
+	p.Put("foo/baz", 2)
+	p.Delete("foo/bar")
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restored, err := trie.NewPersistent(trie.New[int]("/"), backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := restored.Get("foo/bar"); ok {
+		t.Errorf("expected 'foo/bar' to be deleted")
+	}
+	if gotValue, _ := restored.Get("foo/baz"); gotValue != 2 {
+		t.Errorf("expected value to be '2' but got '%v'", gotValue)
+	}
+	if _, ok := restored.Get(""); !ok {
+		t.Errorf("expected the root to be restored")
+	}
+}
@@ -0,0 +1,15 @@
+module moehl.dev/trie/bbolt
+
+go 1.21.4
+
+require (
+	go.etcd.io/bbolt v1.3.10
+	moehl.dev/trie v0.0.0
+)
+
+require (
+	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace moehl.dev/trie => ../
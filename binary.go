@@ -0,0 +1,387 @@
+package trie
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The binary format written by WriteTo and read by ReadFrom looks like this:
+//
+//	header:  magic "TRIE" | uvarint version | string delimiter
+//	node:    byte flags | [string value] | uvarint len(children) | child...
+//	child:   string segment | node
+//	string:  uvarint length | bytes
+//
+// The value of a node is only present if flagValue is set. Children are
+// written in sorted order so that the same trie always results in the same
+// output. Since version 2 the nodes are wrapped in checksummed sections, see
+// section.go.
+const (
+	binaryMagic   = "TRIE"
+	binaryVersion = 2
+)
+
+// Flags stored in the first byte of each node.
+const (
+	flagValue byte = 1 << iota
+)
+
+// maxBinaryLength limits the size of a single value or segment to avoid huge
+// allocations when reading corrupted input.
+const maxBinaryLength = 1 << 30
+
+// ErrInvalidFormat is returned by ReadFrom if the input is not a trie in the
+// binary format.
+var ErrInvalidFormat = errors.New("trie: invalid binary format")
+
+// binaryWriter keeps track of the number of bytes written to satisfy the
+// io.WriterTo interface.
+type binaryWriter struct {
+	w   *bufio.Writer
+	n   int64
+	buf [binary.MaxVarintLen64]byte
+
+	values   int64
+	progress Progress
+}
+
+func (w *binaryWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func (w *binaryWriter) writeUvarint(x uint64) error {
+	_, err := w.Write(w.buf[:binary.PutUvarint(w.buf[:], x)])
+	return err
+}
+
+func (w *binaryWriter) writeBytes(b []byte) error {
+	err := w.writeUvarint(uint64(len(b)))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// binaryReader keeps track of the number of bytes read to satisfy the
+// io.ReaderFrom interface.
+type binaryReader struct {
+	r *bufio.Reader
+	n int64
+
+	values   int64
+	progress Progress
+}
+
+func (r *binaryReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
+
+func (r *binaryReader) readUvarint() (uint64, error) {
+	x, err := binary.ReadUvarint(r)
+	return x, unexpectedEOF(err)
+}
+
+func (r *binaryReader) readBytes() ([]byte, error) {
+	l, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if l > maxBinaryLength {
+		return nil, fmt.Errorf("%w: length %d exceeds limit", ErrInvalidFormat, l)
+	}
+	b := make([]byte, l)
+	n, err := io.ReadFull(r.r, b)
+	r.n += int64(n)
+	return b, unexpectedEOF(err)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Progress is called by Encode and Decode after each value that has been
+// processed with the number of values and bytes processed so far.
+type Progress func(values, bytes int64)
+
+// Encode writes t in the binary format to w just like WriteTo but calls
+// progress after each value that has been written. The trie is encoded while
+// it is being walked, no intermediate copy of the entries is created.
+func Encode[V any](w io.Writer, t String[V], progress Progress) (int64, error) {
+	st, ok := t.(*stringTrie[V])
+	if !ok {
+		return 0, fmt.Errorf("trie: cannot encode %T", t)
+	}
+	return st.encode(w, progress)
+}
+
+// Decode reads a trie in the binary format from r and merges it into t just
+// like ReadFrom but calls progress after each value that has been read. Each
+// value is inserted as soon as it has been read, so the input is never held in
+// memory as a whole.
+func Decode[V any](r io.Reader, t String[V], progress Progress) (int64, error) {
+	st, ok := t.(*stringTrie[V])
+	if !ok {
+		return 0, fmt.Errorf("trie: cannot decode into %T", t)
+	}
+	return st.decode(r, progress)
+}
+
+// WriteTo writes the trie in the binary format to w.
+func (t *stringTrie[V]) WriteTo(w io.Writer) (int64, error) {
+	return t.encode(w, nil)
+}
+
+// encode writes the trie in the binary format to w. The locks of the
+// individual nodes are only held while their value and children are being
+// collected, concurrent writes may or may not be part of the output.
+func (t *stringTrie[V]) encode(w io.Writer, progress Progress) (int64, error) {
+	defer t.unpin(t.pin())
+	span := t.startSpan("trie.Encode")
+	defer span.End()
+
+	bw := &binaryWriter{w: bufio.NewWriter(w)}
+
+	_, err := bw.Write([]byte(binaryMagic))
+	if err == nil {
+		err = bw.writeUvarint(binaryVersion)
+	}
+	if err == nil {
+		err = bw.writeBytes([]byte(t.delimiter))
+	}
+
+	sw := newSectionWriter(bw)
+	nw := &binaryWriter{w: bufio.NewWriter(sw), progress: progress}
+	if err == nil {
+		err = writeNode(nw, t.Codec(), view[string, V]{n: t.root})
+	}
+	if err == nil {
+		err = nw.w.Flush()
+	}
+	if err == nil {
+		err = sw.close(nw.values)
+	}
+	if err == nil {
+		err = bw.w.Flush()
+	}
+
+	span.SetAttribute("trie.values", int(nw.values))
+	span.SetAttribute("trie.bytes", int(bw.n))
+	return bw.n, err
+}
+
+func writeNode[V any](w *binaryWriter, codec ValueCodec[V], v view[string, V]) error {
+	value, hasValue, keys, children := sortedSnapshot(v)
+
+	var flags byte
+	if hasValue {
+		flags |= flagValue
+	}
+	_, err := w.Write([]byte{flags})
+	if err != nil {
+		return err
+	}
+
+	if hasValue {
+		b, err := codec.Encode(value)
+		if err != nil {
+			return fmt.Errorf("trie: encode value: %w", err)
+		}
+		err = w.writeBytes(b)
+		if err != nil {
+			return err
+		}
+		w.values++
+		if w.progress != nil {
+			w.progress(w.values, w.n)
+		}
+	}
+
+	err = w.writeUvarint(uint64(len(keys)))
+	if err != nil {
+		return err
+	}
+
+	for i, k := range keys {
+		err = w.writeBytes([]byte(k))
+		if err != nil {
+			return err
+		}
+		err = writeNode(w, codec, children[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadFrom reads a trie in the binary format from r and merges it into t.
+// Values present in both tries are overwritten by the ones read from r. The
+// delimiter of the encoded trie must match the delimiter of t.
+// Corrupted input is reported as a *CorruptError, values that have been read
+// before the corruption was detected remain in t.
+func (t *stringTrie[V]) ReadFrom(r io.Reader) (int64, error) {
+	return t.decode(r, nil)
+}
+
+func (t *stringTrie[V]) decode(r io.Reader, progress Progress) (int64, error) {
+	span := t.startSpan("trie.Decode")
+	defer span.End()
+
+	br := &binaryReader{r: bufio.NewReader(r), progress: progress}
+
+	delimiter, version, err := br.readHeader()
+	if err != nil {
+		return br.n, err
+	}
+	if delimiter != t.delimiter {
+		return br.n, fmt.Errorf("trie: delimiter mismatch: expected '%s' but got '%s'", t.delimiter, delimiter)
+	}
+
+	values, err := t.readBody(br, version)
+	span.SetAttribute("trie.values", int(values))
+	span.SetAttribute("trie.bytes", int(br.n))
+	return br.n, err
+}
+
+// readHeader reads the header of the binary format and returns the delimiter
+// and the version.
+func (r *binaryReader) readHeader() (string, uint64, error) {
+	magic := make([]byte, len(binaryMagic))
+	n, err := io.ReadFull(r.r, magic)
+	r.n += int64(n)
+	if err != nil {
+		return "", 0, unexpectedEOF(err)
+	}
+	if string(magic) != binaryMagic {
+		return "", 0, ErrInvalidFormat
+	}
+
+	version, err := r.readUvarint()
+	if err != nil {
+		return "", 0, err
+	}
+	if version < 1 || version > binaryVersion {
+		return "", 0, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, version)
+	}
+
+	delimiter, err := r.readBytes()
+	if err != nil {
+		return "", 0, err
+	}
+
+	return string(delimiter), version, nil
+}
+
+// readBody reads the nodes following the header and returns the number of
+// values that have been read. Version 1 did not contain any checksums.
+func (t *stringTrie[V]) readBody(r *binaryReader, version uint64) (int64, error) {
+	if version == 1 {
+		err := t.readNode(r, t.Codec(), nil)
+		return r.values, err
+	}
+
+	sr := newSectionReader(r)
+	nr := &binaryReader{r: bufio.NewReader(sr), progress: r.progress}
+	err := t.readNode(nr, t.Codec(), nil)
+	if err != nil {
+		return nr.values, err
+	}
+	return nr.values, sr.finish(nr.r, nr.values)
+}
+
+// readNode reads the node at the given segments and all of its children. The
+// children are read depth first using an explicit stack, so deeply nested
+// input cannot exhaust the goroutine stack.
+func (t *stringTrie[V]) readNode(r *binaryReader, codec ValueCodec[V], segments []string) error {
+	// pending[d] is the number of children of the node at
+	// segments[:base+d] that are left to be read.
+	var pending []uint64
+	base := len(segments)
+	segments = segments[:base:base]
+	for {
+		count, err := t.readValue(r, codec, segments)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, count)
+
+		for len(pending) > 0 && pending[len(pending)-1] == 0 {
+			pending = pending[:len(pending)-1]
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		pending[len(pending)-1]--
+		segments = segments[:base+len(pending)-1]
+
+		key, err := r.readBytes()
+		if err != nil {
+			return err
+		}
+		err = t.checkSegment(string(key))
+		if err == nil {
+			err = t.checkDepth(len(segments) + 1)
+		}
+		if err != nil {
+			return err
+		}
+		segments = append(segments, string(key))
+	}
+}
+
+// readValue reads the flags and the value of the node at segments and returns
+// the number of its children.
+func (t *stringTrie[V]) readValue(r *binaryReader, codec ValueCodec[V], segments []string) (uint64, error) {
+	flags, err := r.ReadByte()
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if flags&^flagValue != 0 {
+		return 0, fmt.Errorf("%w: unknown flags %x", ErrInvalidFormat, flags)
+	}
+
+	if flags&flagValue != 0 {
+		b, err := r.readBytes()
+		if err != nil {
+			return 0, err
+		}
+		v, err := codec.Decode(b)
+		if err != nil {
+			return 0, fmt.Errorf("trie: decode value: %w", err)
+		}
+		t.insert(segments, v, true)
+
+		r.values++
+		if r.progress != nil {
+			r.progress(r.values, r.n)
+		}
+	}
+
+	count, err := r.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 0 && flags&flagValue == 0 {
+		// Nodes with children are created implicitly, only leaves without
+		// a value have to be created explicitly.
+		var zero V
+		t.insert(segments, zero, false)
+	}
+
+	return count, nil
+}
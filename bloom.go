@@ -0,0 +1,71 @@
+package trie
+
+import (
+	"hash/maphash"
+	"math"
+	"sync/atomic"
+)
+
+// bloomFilter is a Bloom filter over the hashes of paths. It is safe for
+// concurrent use, bits are only ever set and never cleared except by reset.
+type bloomFilter struct {
+	// seed is used to hash the paths, see stringTrie.mayContain.
+	seed maphash.Seed
+	bits []atomic.Uint64
+	// k is the number of bits set per hash.
+	k uint64
+}
+
+// newBloomFilter creates a filter sized for n entries with the given false
+// positive rate.
+func newBloomFilter(n int, falsePositiveRate float64) *bloomFilter {
+	n = max(n, 1)
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+
+	m := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+
+	return &bloomFilter{
+		seed: maphash.MakeSeed(),
+		bits: make([]atomic.Uint64, (uint64(m)+63)/64),
+		k:    uint64(max(k, 1)),
+	}
+}
+
+// add sets the bits of hash h.
+func (b *bloomFilter) add(h uint64) {
+	m := uint64(len(b.bits)) * 64
+	h1, h2 := h&math.MaxUint32, h>>32|1
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % m
+		word, mask := &b.bits[bit/64], uint64(1)<<(bit%64)
+		for {
+			old := word.Load()
+			if old&mask != 0 || word.CompareAndSwap(old, old|mask) {
+				break
+			}
+		}
+	}
+}
+
+// mayContain returns false if h has definitely not been added.
+func (b *bloomFilter) mayContain(h uint64) bool {
+	m := uint64(len(b.bits)) * 64
+	h1, h2 := h&math.MaxUint32, h>>32|1
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % m
+		if b.bits[bit/64].Load()&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// reset clears all bits.
+func (b *bloomFilter) reset() {
+	for i := range b.bits {
+		b.bits[i].Store(0)
+	}
+}
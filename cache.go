@@ -0,0 +1,123 @@
+package trie
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// lookupCache is a bounded LRU cache of the results of Get. Entries are keyed
+// by the segments of their path joined by the delimiter, see
+// stringTrie.cacheKey.
+type lookupCache[V any] struct {
+	lock    sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List
+	// gen is incremented on every invalidation, results of lookups that have
+	// been started before an invalidation are not stored.
+	gen uint64
+}
+
+type cacheEntry[V any] struct {
+	path  string
+	value V
+	found bool
+}
+
+func newLookupCache[V any](size int) *lookupCache[V] {
+	return &lookupCache[V]{
+		size:    max(size, 1),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns the cached result for path. If there is none, ok is false and
+// gen has to be passed to store.
+func (c *lookupCache[V]) get(path string) (value V, found, ok bool, gen uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[path]
+	if !ok {
+		return value, false, false, c.gen
+	}
+	c.lru.MoveToFront(e)
+	entry := e.Value.(*cacheEntry[V])
+	return entry.value, entry.found, true, c.gen
+}
+
+// store adds the result of a lookup unless the cache has been invalidated
+// since gen has been returned by get.
+func (c *lookupCache[V]) store(path string, value V, found bool, gen uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	if _, ok := c.entries[path]; ok {
+		return
+	}
+
+	c.entries[path] = c.lru.PushFront(&cacheEntry[V]{path: path, value: value, found: found})
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry[V]).path)
+	}
+}
+
+// invalidate removes the entries of all prefixes of the node at segments,
+// including the node itself.
+func (c *lookupCache[V]) invalidate(segments []string, delimiter string) {
+	path := strings.Join(segments, delimiter)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	end := 0
+	for i, key := range segments {
+		if i > 0 {
+			end += len(delimiter)
+		}
+		end += len(key)
+		c.remove(path[:end])
+	}
+}
+
+// invalidateTree removes the entries of the node at segments and of all
+// nodes below it.
+func (c *lookupCache[V]) invalidateTree(segments []string, delimiter string) {
+	path := strings.Join(segments, delimiter)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	prefix := path + delimiter
+	for p := range c.entries {
+		if p == path || strings.HasPrefix(p, prefix) {
+			c.remove(p)
+		}
+	}
+}
+
+// purge removes all entries.
+func (c *lookupCache[V]) purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	clear(c.entries)
+	c.lru.Init()
+}
+
+func (c *lookupCache[V]) remove(path string) {
+	if e, ok := c.entries[path]; ok {
+		c.lru.Remove(e)
+		delete(c.entries, path)
+	}
+}
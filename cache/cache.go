@@ -0,0 +1,273 @@
+// Package cache implements a size-limited cache whose keys are stored in a
+// String trie. Besides removing single keys, all keys below a prefix can be
+// dropped at once with InvalidatePrefix, which only touches the entries that
+// are removed instead of scanning all keys like a cache based on a map.
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"strings"
+	"sync"
+
+	"moehl.dev/trie"
+)
+
+// Policy selects the entry that is evicted when the cache is full.
+type Policy uint8
+
+const (
+	// LRU evicts the entry that has not been accessed for the longest time.
+	LRU Policy = iota
+	// LFU evicts the entry that has been accessed the least number of
+	// times, ties are broken by evicting the least recently used one.
+	LFU
+)
+
+// Cache maps keys, which are paths delimited by the delimiter of the cache, to
+// values. A Cache is safe for concurrent use.
+type Cache[V any] struct {
+	// lock is held for reads as well, since they update the policy.
+	lock     sync.Mutex
+	entries  trie.String[*entry[V]]
+	policy   policy[V]
+	capacity int
+	len      int
+	// onEvict is called for entries evicted to make room for new ones.
+	onEvict func(key string, value V)
+}
+
+type entry[V any] struct {
+	key   string
+	value V
+
+	// elem is the element of the entry in the list of the LRU policy.
+	elem *list.Element
+	// index, uses and tick are used by the LFU policy.
+	index int
+	uses  uint64
+	tick  uint64
+}
+
+// New creates a cache for up to capacity entries, at least one.
+func New[V any](delimiter string, capacity int, policy Policy) *Cache[V] {
+	c := &Cache[V]{capacity: max(capacity, 1)}
+	switch policy {
+	case LFU:
+		c.policy = &lfu[V]{}
+	default:
+		c.policy = &lru[V]{list: list.New()}
+	}
+	// Entries are removed from the policy whenever they are removed from
+	// the trie, including all entries of a deleted subtree.
+	c.entries = trie.New[*entry[V]](delimiter, trie.WithEvict(func(_ string, e *entry[V]) {
+		if e != nil {
+			c.policy.remove(e)
+			c.len--
+		}
+	}))
+	return c
+}
+
+// OnEvict sets a function that is called for every entry that is evicted to
+// make room for a new one. It is called while the cache is locked and must
+// not access the cache.
+func (c *Cache[V]) OnEvict(fn func(key string, value V)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onEvict = fn
+}
+
+// Set the value of key, evicting an entry if the cache is full.
+func (c *Cache[V]) Set(key string, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, _ := c.entries.Get(key); e != nil {
+		e.value = value
+		c.policy.touch(e)
+		return
+	}
+
+	if c.len >= c.capacity {
+		victim := c.policy.victim()
+		c.remove(victim.key)
+		if c.onEvict != nil {
+			c.onEvict(victim.key, victim.value)
+		}
+	}
+
+	e := &entry[V]{key: key, value: value}
+	c.entries.Put(key, e)
+	c.policy.add(e)
+	c.len++
+}
+
+// Get the value of key.
+func (c *Cache[V]) Get(key string) (value V, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, _ := c.entries.Get(key)
+	if e == nil {
+		return value, false
+	}
+	c.policy.touch(e)
+	return e.value, true
+}
+
+// Delete removes key, keys below it are kept.
+func (c *Cache[V]) Delete(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.remove(key)
+}
+
+// remove removes the entry of key, the node is only deleted if it has no
+// children since Delete of the trie removes the whole subtree.
+func (c *Cache[V]) remove(key string) {
+	if children, _ := c.entries.Children(key); len(children) > 0 {
+		if e, _ := c.entries.Get(key); e != nil {
+			c.entries.Put(key, nil)
+		}
+		return
+	}
+	c.entries.Delete(key)
+	c.prune(key)
+}
+
+// prune deletes the parents of key that have neither an entry nor children
+// left, like domain.Tree.Delete does, so that the trie does not keep a node
+// for every parent that has ever had an entry.
+func (c *Cache[V]) prune(key string) {
+	delimiter := c.entries.Delimiter()
+	for {
+		i := strings.LastIndex(key, delimiter)
+		if i < 0 {
+			return
+		}
+		key = key[:i]
+		if e, _ := c.entries.Get(key); e != nil {
+			return
+		}
+		if children, _ := c.entries.Children(key); len(children) > 0 {
+			return
+		}
+		c.entries.Delete(key)
+	}
+}
+
+// InvalidatePrefix removes key prefix and all keys below it and returns the
+// number of entries that have been removed. The empty prefix removes all
+// entries.
+func (c *Cache[V]) InvalidatePrefix(prefix string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	before := c.len
+	if prefix == "" {
+		c.entries.Clear()
+	} else {
+		c.entries.Delete(prefix)
+		c.prune(prefix)
+	}
+	return before - c.len
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache[V]) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.len
+}
+
+// policy keeps track of the accesses to the entries of a cache.
+type policy[V any] interface {
+	add(e *entry[V])
+	touch(e *entry[V])
+	remove(e *entry[V])
+	// victim returns the entry to evict, the cache is never empty when it
+	// is called.
+	victim() *entry[V]
+}
+
+// lru keeps the entries in the order of their last access, the most recently
+// used one first.
+type lru[V any] struct {
+	list *list.List
+}
+
+func (p *lru[V]) add(e *entry[V]) {
+	e.elem = p.list.PushFront(e)
+}
+
+func (p *lru[V]) touch(e *entry[V]) {
+	p.list.MoveToFront(e.elem)
+}
+
+func (p *lru[V]) remove(e *entry[V]) {
+	p.list.Remove(e.elem)
+}
+
+func (p *lru[V]) victim() *entry[V] {
+	return p.list.Back().Value.(*entry[V])
+}
+
+// lfu keeps the entries in a heap ordered by the number of accesses and the
+// time of the last access.
+type lfu[V any] struct {
+	entries []*entry[V]
+	tick    uint64
+}
+
+func (p *lfu[V]) add(e *entry[V]) {
+	p.tick++
+	e.uses, e.tick = 1, p.tick
+	heap.Push(p, e)
+}
+
+func (p *lfu[V]) touch(e *entry[V]) {
+	p.tick++
+	e.uses++
+	e.tick = p.tick
+	heap.Fix(p, e.index)
+}
+
+func (p *lfu[V]) remove(e *entry[V]) {
+	heap.Remove(p, e.index)
+}
+
+func (p *lfu[V]) victim() *entry[V] {
+	return p.entries[0]
+}
+
+func (p *lfu[V]) Len() int {
+	return len(p.entries)
+}
+
+func (p *lfu[V]) Less(i, j int) bool {
+	a, b := p.entries[i], p.entries[j]
+	if a.uses != b.uses {
+		return a.uses < b.uses
+	}
+	return a.tick < b.tick
+}
+
+func (p *lfu[V]) Swap(i, j int) {
+	p.entries[i], p.entries[j] = p.entries[j], p.entries[i]
+	p.entries[i].index = i
+	p.entries[j].index = j
+}
+
+func (p *lfu[V]) Push(x any) {
+	e := x.(*entry[V])
+	e.index = len(p.entries)
+	p.entries = append(p.entries, e)
+}
+
+func (p *lfu[V]) Pop() any {
+	e := p.entries[len(p.entries)-1]
+	p.entries[len(p.entries)-1] = nil
+	p.entries = p.entries[:len(p.entries)-1]
+	return e
+}
@@ -0,0 +1,76 @@
+package cache_test
+
+import (
+	"testing"
+
+	"moehl.dev/trie/cache"
+)
+
+func TestCacheInvalidatePrefix(t *testing.T) {
+	c := cache.New[int]("/", 10, cache.LRU)
+	c.Set("users/1", 1)
+	c.Set("users/1/posts", 2)
+	c.Set("users/2", 3)
+	c.Set("groups/1", 4)
+
+	c.Delete("users/1")
+	if _, ok := c.Get("users/1"); ok {
+		t.Errorf("expected users/1 to be deleted")
+	}
+	if v, ok := c.Get("users/1/posts"); !ok || v != 2 {
+		t.Errorf("expected users/1/posts to be kept but got %v, %v", v, ok)
+	}
+
+	if n := c.InvalidatePrefix("users"); n != 2 {
+		t.Errorf("expected 2 entries to be invalidated but got %d", n)
+	}
+	if c.Len() != 1 {
+		t.Errorf("expected 1 entry but got %d", c.Len())
+	}
+	if _, ok := c.Get("groups/1"); !ok {
+		t.Errorf("expected groups/1 to be kept")
+	}
+	if n := c.InvalidatePrefix(""); n != 1 || c.Len() != 0 {
+		t.Errorf("expected all entries to be invalidated but got %d, %d left", n, c.Len())
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	for _, tc := range []struct {
+		policy  cache.Policy
+		evicted string
+	}{
+		// b is the least recently used, c the least frequently used.
+		{cache.LRU, "b"},
+		{cache.LFU, "c"},
+	} {
+		c := cache.New[int]("/", 3, tc.policy)
+		var evicted []string
+		c.OnEvict(func(key string, _ int) {
+			evicted = append(evicted, key)
+		})
+
+		c.Set("a", 1)
+		c.Set("b", 2)
+		c.Set("c", 3)
+		c.Get("b")
+		c.Get("b")
+		c.Get("a")
+		c.Get("c")
+		c.Get("a")
+		c.Set("d", 4)
+
+		if len(evicted) != 1 || evicted[0] != tc.evicted {
+			t.Errorf("%v: expected %s to be evicted but got %v", tc.policy, tc.evicted, evicted)
+		}
+		if c.Len() != 3 {
+			t.Errorf("%v: expected 3 entries but got %d", tc.policy, c.Len())
+		}
+		if _, ok := c.Get(tc.evicted); ok {
+			t.Errorf("%v: expected %s to be gone", tc.policy, tc.evicted)
+		}
+		if v, ok := c.Get("d"); !ok || v != 4 {
+			t.Errorf("%v: expected d to be cached but got %v, %v", tc.policy, v, ok)
+		}
+	}
+}
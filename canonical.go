@@ -0,0 +1,52 @@
+package trie
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// canonicalHeader is the first line of the output of DumpCanonical. The
+// format must never change, a different format requires a new version.
+const canonicalHeader = "trie canonical v1"
+
+// DumpCanonical returns a textual rendering of the trie in a format that is
+// guaranteed to stay the same across versions of this package, e.g. for
+// golden files in tests. After a header line containing the version of the
+// format and the quoted delimiter, it contains one line per node, including
+// nodes without a value, in depth-first order with the children of each node
+// sorted by the bytes of their segment. Each line contains the full path of
+// the node quoted by strconv.Quote, with segments escaped if escaping is
+// enabled, followed by " = " and the value formatted with %v if the node has
+// a value. The root is only listed if it has a value:
+//
+//	trie canonical v1 "/"
+//	"" = 0
+//	"a"
+//	"a/b" = 1
+func (t *stringTrie[V]) DumpCanonical() string {
+	defer t.unpin(t.pin())
+	return t.dumpCanonical(view[string, V]{n: t.root})
+}
+
+// dumpCanonical renders the subtree of v as if v was the root.
+func (t *stringTrie[V]) dumpCanonical(v view[string, V]) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", canonicalHeader, strconv.Quote(t.delimiter))
+	t.canonicalNode(&b, v, nil)
+	return b.String()
+}
+
+func (t *stringTrie[V]) canonicalNode(b *strings.Builder, v view[string, V], segments []string) {
+	value, hasValue, keys, children := sortedSnapshot(v)
+	if len(segments) > 0 || hasValue {
+		b.WriteString(strconv.Quote(t.joinSegments(segments)))
+		if hasValue {
+			fmt.Fprintf(b, " = %v", value)
+		}
+		b.WriteByte('\n')
+	}
+	for i, child := range children {
+		t.canonicalNode(b, child, append(segments[:len(segments):len(segments)], keys[i]))
+	}
+}
@@ -0,0 +1,96 @@
+package trie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// CBOR major types.
+const (
+	cborUint   byte = 0 << 5
+	cborNegInt byte = 1 << 5
+	cborBytes  byte = 2 << 5
+	cborString byte = 3 << 5
+	cborArray  byte = 4 << 5
+	cborMap    byte = 5 << 5
+)
+
+type cborWriter struct {
+	buf *bytes.Buffer
+}
+
+func newCBORWriter(buf *bytes.Buffer) wireWriter {
+	return cborWriter{buf}
+}
+
+// head writes the initial byte of a data item with its argument n.
+func (w cborWriter) head(major byte, n uint64) {
+	switch {
+	case n < 24:
+		w.buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		w.buf.Write([]byte{major | 24, byte(n)})
+	case n <= math.MaxUint16:
+		w.buf.WriteByte(major | 25)
+		w.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		w.buf.WriteByte(major | 26)
+		w.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		w.buf.WriteByte(major | 27)
+		w.buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func (w cborWriter) writeNil() {
+	w.buf.WriteByte(0xf6)
+}
+
+func (w cborWriter) writeBool(b bool) {
+	if b {
+		w.buf.WriteByte(0xf5)
+	} else {
+		w.buf.WriteByte(0xf4)
+	}
+}
+
+func (w cborWriter) writeInt(i int64) {
+	if i < 0 {
+		w.head(cborNegInt, uint64(^i))
+		return
+	}
+	w.head(cborUint, uint64(i))
+}
+
+func (w cborWriter) writeUint(u uint64) {
+	w.head(cborUint, u)
+}
+
+func (w cborWriter) writeFloat32(f float32) {
+	w.buf.WriteByte(0xfa)
+	w.buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(f)))
+}
+
+func (w cborWriter) writeFloat64(f float64) {
+	w.buf.WriteByte(0xfb)
+	w.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func (w cborWriter) writeString(s string) {
+	w.head(cborString, uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w cborWriter) writeBytes(b []byte) {
+	w.head(cborBytes, uint64(len(b)))
+	w.buf.Write(b)
+}
+
+func (w cborWriter) writeArrayHeader(n int) {
+	w.head(cborArray, uint64(n))
+}
+
+func (w cborWriter) writeMapHeader(n int) {
+	w.head(cborMap, uint64(n))
+}
@@ -0,0 +1,124 @@
+package trie
+
+// maxSmallFanout is the number of children up to which the children of a node
+// are stored in a slice. Most nodes only have a handful of children, for those
+// a linear scan is faster and uses less memory than a map.
+const maxSmallFanout = 8
+
+// nodeChildren maps keys to the child nodes of a node. Up to maxSmallFanout
+// children are stored unordered in keys and nodes, above that all
+// children are moved to m. The zero value is an empty set of children which
+// does not allocate any memory until the first child is inserted, so leaves
+// only carry the size of the struct itself.
+type nodeChildren[K comparable, V any] struct {
+	keys  []K
+	nodes []*node[K, V]
+	m     map[K]*node[K, V]
+}
+
+func (c *nodeChildren[K, V]) get(key K) (*node[K, V], bool) {
+	if c.m != nil {
+		n, ok := c.m[key]
+		return n, ok
+	}
+
+	for i, k := range c.keys {
+		if k == key {
+			return c.nodes[i], true
+		}
+	}
+	return nil, false
+}
+
+func (c *nodeChildren[K, V]) set(key K, n *node[K, V]) {
+	if c.m != nil {
+		c.m[key] = n
+		return
+	}
+
+	for i, k := range c.keys {
+		if k == key {
+			c.nodes[i] = n
+			return
+		}
+	}
+
+	if len(c.keys) < maxSmallFanout {
+		c.keys = append(c.keys, key)
+		c.nodes = append(c.nodes, n)
+		return
+	}
+
+	c.m = make(map[K]*node[K, V], len(c.keys)+1)
+	for i, k := range c.keys {
+		c.m[k] = c.nodes[i]
+	}
+	c.m[key] = n
+	c.keys, c.nodes = nil, nil
+}
+
+func (c *nodeChildren[K, V]) delete(key K) {
+	if c.m != nil {
+		delete(c.m, key)
+		return
+	}
+
+	for i, k := range c.keys {
+		if k == key {
+			last := len(c.keys) - 1
+			c.keys[i], c.nodes[i] = c.keys[last], c.nodes[last]
+			c.nodes[last] = nil
+			c.keys, c.nodes = c.keys[:last], c.nodes[:last]
+			return
+		}
+	}
+}
+
+func (c *nodeChildren[K, V]) len() int {
+	if c.m != nil {
+		return len(c.m)
+	}
+	return len(c.keys)
+}
+
+// each calls fn for all children in no particular order.
+func (c *nodeChildren[K, V]) each(fn func(key K, n *node[K, V])) {
+	if c.m != nil {
+		for k, n := range c.m {
+			fn(k, n)
+		}
+		return
+	}
+
+	for i, k := range c.keys {
+		fn(k, c.nodes[i])
+	}
+}
+
+// shrink releases unused capacity. If enough children have been deleted, they
+// are moved back from the map to the slices.
+func (c *nodeChildren[K, V]) shrink() {
+	if c.m == nil {
+		c.keys = append([]K(nil), c.keys...)
+		c.nodes = append([]*node[K, V](nil), c.nodes...)
+		return
+	}
+
+	if len(c.m) <= maxSmallFanout {
+		m := c.m
+		c.m = nil
+		for k, n := range m {
+			c.keys = append(c.keys, k)
+			c.nodes = append(c.nodes, n)
+		}
+		c.shrink()
+		return
+	}
+
+	// Maps never shrink, rehash the children into a new one.
+	m := make(map[K]*node[K, V], len(c.m))
+	for k, n := range c.m {
+		m[k] = n
+	}
+	c.m = m
+}
@@ -0,0 +1,249 @@
+// Command trie builds tries from word lists, converts them to snapshots in the
+// binary format and answers queries on them.
+//
+// Usage:
+//
+//	trie build [-d delimiter] -o snapshot [file...]
+//	trie get [-d delimiter] [-f file] path...
+//	trie prefix [-d delimiter] [-f file] prefix
+//	trie lpm [-d delimiter] [-f file] path
+//	trie suggest [-d delimiter] [-f file] [-n limit] input
+//	trie verify -f snapshot
+//
+// Input files are either snapshots written by build or word lists with one
+// path per line, optionally followed by a tab and a value. Word lists are
+// read from stdin if no file is given or the file is "-".
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"moehl.dev/trie"
+)
+
+// snapshotMagic are the first bytes of the binary format.
+const snapshotMagic = "TRIE"
+
+// errNotFound is returned by get and lpm if a path has no value, it makes
+// the command exit with status 1 without printing an error.
+var errNotFound = errors.New("not found")
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	switch {
+	case errors.Is(err, errNotFound):
+		os.Exit(1)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "trie:", err)
+		os.Exit(2)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("missing command, expected one of build, get, prefix, lpm, suggest or verify")
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	delimiter := fs.String("d", "/", "delimiter of the paths in word lists")
+	file := fs.String("f", "-", "snapshot or word list to query")
+	out := fs.String("o", "", "file to write the snapshot to")
+	limit := fs.Int("n", 10, "maximum number of suggestions")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "build" {
+		if *out == "" {
+			return errors.New("build: missing -o")
+		}
+		t, err := build(fs.Args(), *delimiter, stdin)
+		if err != nil {
+			return err
+		}
+		return trie.SaveSnapshot(t, *out)
+	}
+
+	t, err := load(*file, *delimiter, stdin)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+
+	switch args[0] {
+	case "get":
+		return get(w, t, fs.Args())
+	case "prefix":
+		if fs.NArg() != 1 {
+			return errors.New("prefix: expected a single prefix")
+		}
+		trie.Namespace(t, fs.Arg(0)).Walk(func(path string, value string) bool {
+			printEntry(w, join(t, fs.Arg(0), path), value)
+			return true
+		})
+		return nil
+	case "lpm":
+		if fs.NArg() != 1 {
+			return errors.New("lpm: expected a single path")
+		}
+		path, value, ok := longestPrefix(t, fs.Arg(0))
+		if !ok {
+			return errNotFound
+		}
+		printEntry(w, path, value)
+		return nil
+	case "suggest":
+		if fs.NArg() != 1 {
+			return errors.New("suggest: expected a single input")
+		}
+		for _, path := range suggest(t, fs.Arg(0), *limit) {
+			fmt.Fprintln(w, path)
+		}
+		return nil
+	case "verify":
+		s := trie.StatsOf(t)
+		fmt.Fprintf(w, "ok: %d values, %d nodes, depth %d\n", s.Values, s.Nodes, s.Depth)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// build reads all word lists in files, or stdin if there are none, into a
+// single trie.
+func build(files []string, delimiter string, stdin io.Reader) (trie.String[string], error) {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	t := trie.New[string](delimiter)
+	for _, name := range files {
+		words, err := load(name, delimiter, stdin)
+		if err != nil {
+			return nil, err
+		}
+		words.Walk(func(path string, value string) bool {
+			t.Put(path, value)
+			return true
+		})
+	}
+	return t, nil
+}
+
+// load reads the snapshot or word list in the file with the given name.
+func load(name, delimiter string, stdin io.Reader) (trie.String[string], error) {
+	if name == "-" {
+		return loadWords(stdin, delimiter)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic, _ := r.Peek(len(snapshotMagic))
+	if string(magic) == snapshotMagic {
+		return trie.LoadSnapshot[string](name)
+	}
+	return loadWords(r, delimiter)
+}
+
+func loadWords(r io.Reader, delimiter string) (trie.String[string], error) {
+	return trie.LoadLinesFunc(r, delimiter, func(line string) (string, string, error) {
+		path, value, _ := strings.Cut(line, "\t")
+		return path, value, nil
+	})
+}
+
+func get(w io.Writer, t trie.String[string], paths []string) error {
+	var err error
+	for _, path := range paths {
+		value, state := t.Lookup(path)
+		if state != trie.Exists {
+			err = errNotFound
+			continue
+		}
+		printEntry(w, path, value)
+	}
+	return err
+}
+
+// longestPrefix returns the longest prefix of path, in segments, that has a
+// value.
+func longestPrefix(t trie.String[string], path string) (prefix, value string, ok bool) {
+	n := t.Root()
+	if v, hasValue := n.Value(); hasValue {
+		prefix, value, ok = "", v, true
+	}
+	for _, segment := range strings.Split(path, t.Delimiter()) {
+		child, found := n.Child(segment)
+		if !found {
+			break
+		}
+		n = child
+		if v, hasValue := n.Value(); hasValue {
+			prefix, value, ok = n.Path(), v, true
+		}
+	}
+	return prefix, value, ok
+}
+
+// suggest returns up to limit paths with a value that start with input, which
+// may end within a segment, in sorted order.
+func suggest(t trie.String[string], input string, limit int) []string {
+	var parent, partial string
+	if i := strings.LastIndex(input, t.Delimiter()); i >= 0 {
+		parent, partial = input[:i], input[i+len(t.Delimiter()):]
+	} else {
+		partial = input
+	}
+
+	children, _ := t.Children(parent)
+	var paths []string
+	for _, child := range children {
+		if !strings.HasPrefix(child, partial) {
+			continue
+		}
+		prefix := join(t, parent, child)
+		trie.Namespace(t, prefix).Walk(func(path string, _ string) bool {
+			if len(paths) == limit {
+				return false
+			}
+			paths = append(paths, join(t, prefix, path))
+			return true
+		})
+		if len(paths) == limit {
+			break
+		}
+	}
+	return paths
+}
+
+// join returns path appended to prefix.
+func join(t trie.String[string], prefix, path string) string {
+	switch {
+	case prefix == "":
+		return path
+	case path == "":
+		return prefix
+	}
+	return prefix + t.Delimiter() + path
+}
+
+func printEntry(w io.Writer, path, value string) {
+	if value == "" {
+		fmt.Fprintln(w, path)
+		return
+	}
+	fmt.Fprintf(w, "%s\t%s\n", path, value)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	words := "a/b\t1\na/b/c\t2\na/bc\t3\nx/y\n"
+	snapshot := filepath.Join(t.TempDir(), "words.trie")
+
+	err := run([]string{"build", "-o", snapshot}, strings.NewReader(words), nil)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		out  string
+		err  error
+	}{
+		{[]string{"get", "a/b", "a/bc"}, "a/b\t1\na/bc\t3\n", nil},
+		{[]string{"get", "a"}, "", errNotFound},
+		{[]string{"prefix", "a"}, "a/b\t1\na/b/c\t2\na/bc\t3\n", nil},
+		{[]string{"lpm", "a/b/d/e"}, "a/b\t1\n", nil},
+		{[]string{"lpm", "b"}, "", errNotFound},
+		{[]string{"suggest", "a/b"}, "a/b\na/b/c\na/bc\n", nil},
+		{[]string{"suggest", "-n", "1", "a/bc"}, "a/bc\n", nil},
+		{[]string{"verify"}, "ok: 4 values, 6 nodes, depth 3\n", nil},
+	} {
+		// Query the snapshot as well as the word list on stdin.
+		for _, file := range []string{snapshot, "-"} {
+			args := append([]string{tc.args[0], "-f", file}, tc.args[1:]...)
+			var out bytes.Buffer
+			err := run(args, strings.NewReader(words), &out)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%v: expected error %v but got %v", args, tc.err, err)
+			}
+			if out.String() != tc.out {
+				t.Errorf("%v: expected %q but got %q", args, tc.out, out.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package trie
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Encoder encodes Go values in a wire format. It is used by Export to write
+// the contents of a trie.
+type Encoder interface {
+	Encode(w io.Writer, v any) error
+}
+
+// Export writes all paths of t that have a value set as a map from path to
+// value using enc.
+func Export[V any](w io.Writer, t String[V], enc Encoder) error {
+	return enc.Encode(w, t.ToMap())
+}
+
+// Built-in encoders. Both support booleans, numbers, strings, byte slices,
+// slices, arrays, maps and structs. Struct fields are named according to their
+// json tag if present. Types implementing encoding.TextMarshaler are encoded
+// as strings. Map entries are sorted by their encoded key, so the output is
+// deterministic.
+var (
+	// CBOR encodes values as defined by RFC 8949.
+	CBOR Encoder = wireEncoder{newCBORWriter}
+	// MessagePack encodes values as defined by the MessagePack specification.
+	MessagePack Encoder = wireEncoder{newMsgpackWriter}
+)
+
+// wireWriter writes the primitives of a self-describing binary wire format.
+type wireWriter interface {
+	writeNil()
+	writeBool(b bool)
+	writeInt(i int64)
+	writeUint(u uint64)
+	writeFloat32(f float32)
+	writeFloat64(f float64)
+	writeString(s string)
+	writeBytes(b []byte)
+	writeArrayHeader(n int)
+	writeMapHeader(n int)
+}
+
+type wireEncoder struct {
+	newWriter func(*bytes.Buffer) wireWriter
+}
+
+func (e wireEncoder) Encode(w io.Writer, v any) error {
+	var buf bytes.Buffer
+	err := e.encode(&buf, reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func (e wireEncoder) encode(buf *bytes.Buffer, v reflect.Value) error {
+	w := e.newWriter(buf)
+
+	if !v.IsValid() {
+		w.writeNil()
+		return nil
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		w.writeString(string(b))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		return e.encode(buf, v.Elem())
+	case reflect.Bool:
+		w.writeBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.writeUint(v.Uint())
+	case reflect.Float32:
+		w.writeFloat32(float32(v.Float()))
+	case reflect.Float64:
+		w.writeFloat64(v.Float())
+	case reflect.String:
+		w.writeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			w.writeBytes(v.Bytes())
+			return nil
+		}
+		return e.encodeArray(buf, w, v)
+	case reflect.Array:
+		return e.encodeArray(buf, w, v)
+	case reflect.Map:
+		if v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		return e.encodeMap(buf, w, v)
+	case reflect.Struct:
+		return e.encodeStruct(buf, w, v)
+	default:
+		return fmt.Errorf("trie: cannot encode value of type %s", v.Type())
+	}
+
+	return nil
+}
+
+func (e wireEncoder) encodeArray(buf *bytes.Buffer, w wireWriter, v reflect.Value) error {
+	w.writeArrayHeader(v.Len())
+	for i := 0; i < v.Len(); i++ {
+		err := e.encode(buf, v.Index(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e wireEncoder) encodeMap(buf *bytes.Buffer, w wireWriter, v reflect.Value) error {
+	type entry struct {
+		key, value []byte
+	}
+
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		var key, value bytes.Buffer
+		err := e.encode(&key, iter.Key())
+		if err != nil {
+			return err
+		}
+		err = e.encode(&value, iter.Value())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key.Bytes(), value.Bytes()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	w.writeMapHeader(len(entries))
+	for _, entry := range entries {
+		buf.Write(entry.key)
+		buf.Write(entry.value)
+	}
+	return nil
+}
+
+func (e wireEncoder) encodeStruct(buf *bytes.Buffer, w wireWriter, v reflect.Value) error {
+	type field struct {
+		name  string
+		index int
+	}
+
+	var fields []field
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, field{name, i})
+	}
+
+	w.writeMapHeader(len(fields))
+	for _, f := range fields {
+		w.writeString(f.name)
+		err := e.encode(buf, v.Field(f.index))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
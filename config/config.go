@@ -0,0 +1,119 @@
+// Package config resolves hierarchical configuration, e.g. per directory or
+// per namespace overrides. Values are stored at paths in a String trie and
+// the configuration of a path is the result of merging the values of all of
+// its ancestors, from the root down to the path itself.
+package config
+
+import (
+	"reflect"
+
+	"moehl.dev/trie"
+)
+
+// MergeFunc merges the value of a node into the configuration resolved for
+// its parent and returns the result. It must not modify parent or child.
+type MergeFunc[V any] func(parent, child V) V
+
+// Tree stores configuration values at paths. It is safe for concurrent use.
+type Tree[V any] struct {
+	values trie.String[V]
+	merge  MergeFunc[V]
+}
+
+// New creates an empty Tree with paths delimited by delimiter. If merge is
+// nil, MergeFields is used.
+func New[V any](delimiter string, merge MergeFunc[V], opts ...trie.Option) *Tree[V] {
+	if merge == nil {
+		merge = MergeFields[V]
+	}
+	return &Tree[V]{
+		values: trie.New[V](delimiter, opts...),
+		merge:  merge,
+	}
+}
+
+// Set the value at path, the empty path refers to the root.
+func (t *Tree[V]) Set(path string, value V) {
+	t.values.Put(path, value)
+}
+
+// Get returns the value that has been set at path without merging it.
+func (t *Tree[V]) Get(path string) (value V, found bool) {
+	value, state := t.values.Lookup(path)
+	return value, state == trie.Exists
+}
+
+// Delete the value at path and all values below it.
+func (t *Tree[V]) Delete(path string) {
+	t.values.Delete(path)
+}
+
+// Resolve merges the values of all nodes along path, starting at the root,
+// so the value nearest to path takes precedence. found is false if neither
+// path nor any of its ancestors has a value.
+func (t *Tree[V]) Resolve(path string) (value V, found bool) {
+	t.values.WalkPath(path, func(_ string, v V) bool {
+		if !found {
+			value, found = v, true
+		} else {
+			value = t.merge(value, v)
+		}
+		return true
+	})
+	return value, found
+}
+
+// Trie returns the trie storing the values, e.g. to walk or serialize them.
+func (t *Tree[V]) Trie() trie.String[V] {
+	return t.values
+}
+
+// MergeFields merges child into parent field by field: fields of structs are
+// merged recursively, maps are merged key by key with the entries of child
+// taking precedence, all other values of child replace the ones of parent
+// unless they are the zero value. Unexported fields are taken from parent.
+// Consequently a child cannot reset a field to its zero value, use pointers
+// for fields where that is needed.
+func MergeFields[V any](parent, child V) V {
+	p := reflect.ValueOf(&parent).Elem()
+	mergeValue(p, reflect.ValueOf(&child).Elem())
+	return parent
+}
+
+// mergeValue merges child into dst, which must be settable.
+func mergeValue(dst, child reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			if f := dst.Field(i); f.CanSet() {
+				mergeValue(f, child.Field(i))
+			}
+		}
+	case reflect.Map:
+		if child.Len() == 0 {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(child)
+			return
+		}
+		// The map of the parent is shared with other configurations, so
+		// the entries are copied into a new one.
+		m := reflect.MakeMapWithSize(dst.Type(), dst.Len()+child.Len())
+		for it := dst.MapRange(); it.Next(); {
+			m.SetMapIndex(it.Key(), it.Value())
+		}
+		for it := child.MapRange(); it.Next(); {
+			m.SetMapIndex(it.Key(), it.Value())
+		}
+		dst.Set(m)
+	case reflect.Interface:
+		if !child.IsNil() {
+			dst.Set(child)
+		}
+	default:
+		if !child.IsZero() {
+			dst.Set(child)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package config_test
+
+import (
+	"reflect"
+	"testing"
+
+	"moehl.dev/trie/config"
+)
+
+type settings struct {
+	Owner   string
+	Mode    int
+	Public  *bool
+	Labels  map[string]string
+	Limits  limits
+	private string
+}
+
+type limits struct {
+	Size  int
+	Files int
+}
+
+func TestTreeResolve(t *testing.T) {
+	no := false
+	c := config.New[settings]("/", nil)
+	c.Set("", settings{Owner: "root", Mode: 0o644, Labels: map[string]string{"a": "1"}, Limits: limits{Size: 10, Files: 5}})
+	c.Set("home", settings{Mode: 0o700, Labels: map[string]string{"b": "2"}})
+	c.Set("home/alice", settings{Owner: "alice", Public: &no, Limits: limits{Files: 50}, private: "x"})
+
+	got, found := c.Resolve("home/alice/docs")
+	expected := settings{
+		Owner:  "alice",
+		Mode:   0o700,
+		Public: &no,
+		Labels: map[string]string{"a": "1", "b": "2"},
+		Limits: limits{Size: 10, Files: 50},
+	}
+	if !found || !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v but got %+v", expected, got)
+	}
+
+	if got, _ := c.Resolve("tmp"); got.Owner != "root" || got.Mode != 0o644 {
+		t.Errorf("expected the root configuration but got %+v", got)
+	}
+	if root, _ := c.Get(""); len(root.Labels) != 1 {
+		t.Errorf("expected the labels of the root to be unchanged but got %v", root.Labels)
+	}
+	if _, found := c.Get("home/alice/docs"); found {
+		t.Errorf("expected no value to be set at home/alice/docs")
+	}
+
+	c.Delete("home")
+	if got, _ := c.Resolve("home/alice"); got.Owner != "root" {
+		t.Errorf("expected the root configuration after the delete but got %+v", got)
+	}
+}
+
+func TestTreeMergeFunc(t *testing.T) {
+	c := config.New[[]string]("/", func(parent, child []string) []string {
+		return append(parent[:len(parent):len(parent)], child...)
+	})
+	if _, found := c.Resolve("a"); found {
+		t.Errorf("expected nothing to be found in an empty tree")
+	}
+
+	c.Set("a", []string{"a"})
+	c.Set("a/b/c", []string{"c"})
+	got, _ := c.Resolve("a/b/c/d")
+	if !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("expected [a c] but got %v", got)
+	}
+}
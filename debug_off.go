@@ -0,0 +1,18 @@
+//go:build !triedebug
+
+package trie
+
+// debugEnabled is set by the triedebug build tag, see debug_on.go.
+const debugEnabled = false
+
+type nodeDebug struct{}
+
+func (d *nodeDebug) markReleased() {}
+
+func assertNotReleased[K comparable, V any](n *node[K, V]) {}
+
+func assertLocked[K comparable, V any](n *node[K, V]) {}
+
+func assertChildren[K comparable, V any](n *node[K, V]) {}
+
+func assertCounts[K comparable, V any](t *tree[K, V], segments []K) {}
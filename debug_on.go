@@ -0,0 +1,97 @@
+//go:build triedebug
+
+package trie
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+)
+
+// Building with the triedebug tag enables internal assertions which panic as
+// soon as the structure of a trie is found to be inconsistent: the locks that
+// callers must hold are checked, the storage of the children of every node is
+// checked after each modification, the subtree counts of the nodes along the
+// modified path are compared with the values they contain, and released nodes are kept out of the
+// pool so that every later access through a stale view or handle is detected.
+// The assertions slow down all operations considerably.
+const debugEnabled = true
+
+// nodeDebug contains the state of a node used by the assertions.
+type nodeDebug struct {
+	released atomic.Bool
+}
+
+func (d *nodeDebug) markReleased() {
+	d.released.Store(true)
+}
+
+// assertNotReleased panics if n has been released to the pool.
+func assertNotReleased[K comparable, V any](n *node[K, V]) {
+	if n.debug.released.Load() {
+		panic("trie: access to a node that has been released")
+	}
+}
+
+// assertLocked panics if the lock of n is not held by anyone. A lock held by
+// another goroutine is not detected.
+func assertLocked[K comparable, V any](n *node[K, V]) {
+	if n.lock.TryLock() {
+		n.lock.Unlock()
+		panic("trie: lock of node is not held")
+	}
+}
+
+// assertChildren panics if the storage of the children of n is inconsistent.
+// The lock of n must be held by the caller.
+func assertChildren[K comparable, V any](n *node[K, V]) {
+	if reason := n.children.check(); reason != "" {
+		panic(fmt.Sprintf("trie: inconsistent children: %s", reason))
+	}
+}
+
+// assertCounts panics if the subtree count of the root or of a node along
+// segments differs from the number of values in its subtree. It does nothing
+// unless WithSubtreeCounts is used. The counts lock must be held by the caller.
+func assertCounts[K comparable, V any](t *tree[K, V], segments []K) {
+	if t.counts == nil {
+		return
+	}
+	n := t.root
+	for {
+		if count := countValues(n); n.count != count {
+			panic(fmt.Sprintf("trie: subtree count is %d but contains %d values", n.count, count))
+		}
+		if len(segments) == 0 {
+			return
+		}
+		n.lock.RLock()
+		child, ok := n.children.get(segments[0])
+		n.lock.RUnlock()
+		if !ok || len(child.segments) > len(segments) || !slices.Equal(child.segments, segments[:len(child.segments)]) {
+			return
+		}
+		n = child
+		segments = segments[len(child.segments):]
+	}
+}
+
+// countValues returns the number of values in the subtree of n, unlike
+// recount it does not update the counts.
+func countValues[K comparable, V any](n *node[K, V]) int {
+	n.lock.RLock()
+	count := 0
+	if n.hasValue {
+		count++
+	}
+	var children []*node[K, V]
+	n.children.each(func(_ K, child *node[K, V]) {
+		children = append(children, child)
+	})
+	n.lock.RUnlock()
+
+	for _, child := range children {
+		count += countValues(child)
+	}
+	return count
+}
@@ -0,0 +1,57 @@
+//go:build triedebug
+
+package trie_test
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"moehl.dev/trie"
+)
+
+func TestDebugReleasedNode(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithPooling())
+	tr.Put("a/b", 1)
+	tr.Put("a/c", 2)
+	n, _ := tr.Root().Child("a")
+	n, _ = n.Child("b")
+	tr.Delete("a")
+
+	// The handle must not touch the released node, which would panic.
+	if _, ok := n.Value(); ok {
+		t.Errorf("expected deleted node to have no value")
+	}
+
+	tr.Put("a/b", 3)
+	if value, _ := n.Value(); value != 3 {
+		t.Errorf("expected value 3 but got %d", value)
+	}
+}
+
+func TestDebugSubtreeCounts(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithSubtreeCounts(), trie.WithPooling())
+	segments := []string{"a", "b", "ab"}
+	r := rand.New(rand.NewSource(1))
+
+	// Every mutation panics if it leaves a count along its path wrong.
+	for i := 0; i < 2000; i++ {
+		path := make([]string, r.Intn(4))
+		for j := range path {
+			path[j] = segments[r.Intn(len(segments))]
+		}
+		switch x := r.Intn(100); {
+		case x < 60:
+			tr.Put(strings.Join(path, "/"), i)
+		case x < 95:
+			tr.Delete(strings.Join(path, "/"))
+		case x < 98:
+			tr.Compact()
+		default:
+			tr.Clear()
+		}
+	}
+	if err := tr.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package trie
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// The delta format contains all changes between two revisions of a Journal:
+//
+//	header:  magic "TRID" | uvarint version | string delimiter | uvarint from | uvarint to
+//	records: write-ahead log records (see wal.go) terminated by EOF
+//
+// A delta starts with a clear record if the trie has been cleared after the
+// revision it has been exported since.
+const (
+	deltaMagic   = "TRID"
+	deltaVersion = 1
+)
+
+// journalEntry records the last changes of a path. deleted is the revision of
+// the last Delete of the path and its subtree, put the revision of the last Put
+// after it, zero if there has been none.
+type journalEntry struct {
+	deleted uint64
+	put     uint64
+}
+
+// Journal wraps a String trie and records the revision of the last change of
+// every path, so that ExportSince can export only the changes since a
+// previous revision. Deleting a path discards the journal entries of all paths
+// below it, the journal therefore never grows beyond the number of paths that
+// have been written or deleted.
+type Journal[V any] struct {
+	String[V]
+
+	lock *sync.RWMutex
+	rev  uint64
+	// cleared is the revision of the last Clear, all entries of journal
+	// are newer.
+	cleared uint64
+	// journal contains the entries at the canonical paths of st, the trie
+	// at the bottom of String.
+	st      *stringTrie[V]
+	journal *stringTrie[*journalEntry]
+}
+
+// NewJournal wraps t and starts recording changes at revision zero.
+func NewJournal[V any](t String[V]) *Journal[V] {
+	st := t.Root().t
+	journal := newStringTrie[*journalEntry](st.delimiter)
+	journal.escape = st.escape
+	return &Journal[V]{
+		String:  t,
+		lock:    new(sync.RWMutex),
+		st:      st,
+		journal: journal,
+	}
+}
+
+// Revision returns the revision of the last change. A snapshot written while
+// no changes are made contains all changes up to and including this revision.
+func (j *Journal[V]) Revision() uint64 {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	return j.rev
+}
+
+func (j *Journal[V]) Put(path string, value V) {
+	if j.CheckPath(path) != nil {
+		return
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.rev++
+	canonical := j.st.canonical(path)
+	// A Put does not undo the Delete of the subtree, the entry keeps both so
+	// that a delta still deletes the paths below.
+	if e, _ := j.journal.Get(canonical); e != nil {
+		e.put = j.rev
+	} else {
+		j.journal.Put(canonical, &journalEntry{put: j.rev})
+	}
+	j.String.Put(path, value)
+}
+
+// Rekey is applied as a sequence of journaled Delete and Put operations, unlike
+// the Rekey of the String trie it is not atomic.
+func (j *Journal[V]) Rekey(fn func(oldPath string) (newPath string, keep bool)) error {
+	return rekeyEach[V](j, fn)
+}
+
+func (j *Journal[V]) PutSegments(value V, segments ...string) {
+	j.Put(j.st.joinSegments(segments), value)
+}
+
+// PutE is like Put but returns the error of the key validator if the path is
+// rejected.
+func (j *Journal[V]) PutE(path string, value V) error {
+	err := j.CheckPath(path)
+	if err != nil {
+		return err
+	}
+	j.Put(path, value)
+	return nil
+}
+
+func (j *Journal[V]) Delete(path string) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.rev++
+	canonical := j.st.canonical(path)
+	j.journal.Delete(canonical)
+	j.journal.Put(canonical, &journalEntry{deleted: j.rev})
+	j.String.Delete(path)
+}
+
+// Root returns a handle to the root of the trie, its Set is journaled just
+// like Put.
+func (j *Journal[V]) Root() Node[V] {
+	return rootWithPut(j.String, j.Put)
+}
+
+// Clear discards the whole journal, deltas exported since an earlier revision
+// clear the trie they are applied to before applying later changes.
+func (j *Journal[V]) Clear() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.rev++
+	j.cleared = j.rev
+	j.journal.Clear()
+	j.String.Clear()
+}
+
+// DeleteE is like Delete but returns an error wrapping ErrNotFound if the
+// node does not exist.
+func (j *Journal[V]) DeleteE(path string) error {
+	_, err := j.GetE(path)
+	if err != nil {
+		return err
+	}
+	j.Delete(path)
+	return nil
+}
+
+// ReadFrom reads a trie in the binary format and puts all of its values into
+// the journal one by one.
+func (j *Journal[V]) ReadFrom(r io.Reader) (int64, error) {
+	t := newStringTrie[V](j.Delimiter())
+	t.codec = j.Codec()
+	n, err := t.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+
+	t.Walk(func(path string, value V) bool {
+		j.Put(path, value)
+		return true
+	})
+	return n, nil
+}
+
+// ExportSince writes all changes after revision rev to w in the delta format.
+// Puts of the same path are collapsed into the last one and values are taken
+// from the current state of the trie. A path that has been deleted and written
+// again is exported as a delete followed by a put.
+func (j *Journal[V]) ExportSince(w io.Writer, rev uint64) error {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
+	type change struct {
+		path    string
+		rev     uint64
+		deleted bool
+	}
+
+	var changes []change
+	j.journal.Walk(func(path string, e *journalEntry) bool {
+		if e.deleted > rev {
+			changes = append(changes, change{path, e.deleted, true})
+		}
+		if e.put > rev {
+			changes = append(changes, change{path, e.put, false})
+		}
+		return true
+	})
+	sort.Slice(changes, func(a, b int) bool {
+		return changes[a].rev < changes[b].rev
+	})
+
+	bw := &binaryWriter{w: bufio.NewWriter(w)}
+	_, err := bw.Write([]byte(deltaMagic))
+	if err == nil {
+		err = bw.writeUvarint(deltaVersion)
+	}
+	if err == nil {
+		err = bw.writeBytes([]byte(j.Delimiter()))
+	}
+	if err == nil {
+		err = bw.writeUvarint(rev)
+	}
+	if err == nil {
+		err = bw.writeUvarint(j.rev)
+	}
+	if err == nil && j.cleared > rev {
+		_, err = bw.Write([]byte{walClear})
+	}
+
+	for _, c := range changes {
+		if err != nil {
+			break
+		}
+
+		if c.deleted {
+			_, err = bw.Write([]byte{walDelete})
+			if err == nil {
+				err = bw.writeBytes([]byte(c.path))
+			}
+			continue
+		}
+
+		value, ok := j.String.Get(c.path)
+		if !ok {
+			// The path has been deleted by a later deletion of one of its
+			// parents which is part of the delta as well.
+			continue
+		}
+		b, encErr := j.Codec().Encode(value)
+		if encErr != nil {
+			return fmt.Errorf("trie: encode value: %w", encErr)
+		}
+
+		_, err = bw.Write([]byte{walPut})
+		if err == nil {
+			err = bw.writeBytes([]byte(c.path))
+		}
+		if err == nil {
+			err = bw.writeBytes(b)
+		}
+	}
+
+	if err == nil {
+		err = bw.w.Flush()
+	}
+	return err
+}
+
+// ApplyDelta applies a delta written by ExportSince to t and returns the
+// revision the delta has been exported at. The caller is responsible for
+// applying deltas in order, i.e. the next delta has to be exported since the
+// returned revision.
+func ApplyDelta[V any](t String[V], r io.Reader) (uint64, error) {
+	br := &binaryReader{r: bufio.NewReader(r)}
+
+	magic := make([]byte, len(deltaMagic))
+	_, err := io.ReadFull(br.r, magic)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if string(magic) != deltaMagic {
+		return 0, ErrInvalidFormat
+	}
+	version, err := br.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if version != deltaVersion {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, version)
+	}
+	delimiter, err := br.readBytes()
+	if err != nil {
+		return 0, err
+	}
+	if string(delimiter) != t.Delimiter() {
+		return 0, fmt.Errorf("trie: delimiter mismatch: expected '%s' but got '%s'", t.Delimiter(), delimiter)
+	}
+	_, err = br.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	rev, err := br.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	return rev, NewWAL(t, nil).Replay(br.r)
+}
@@ -0,0 +1,131 @@
+package trie
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// ShortestKeys visits the nodes level by level and stops as soon as k paths
+// have been found.
+func (t *stringTrie[V]) ShortestKeys(k int) []string {
+	defer t.unpin(t.pin())
+	return t.shortestKeys(view[string, V]{n: t.root}, k)
+}
+
+// LongestKeys has to visit the whole trie, only the k deepest paths are kept
+// in memory.
+func (t *stringTrie[V]) LongestKeys(k int) []string {
+	defer t.unpin(t.pin())
+	return t.longestKeys(view[string, V]{n: t.root}, k)
+}
+
+// childPath returns the path of the child with the given key of the node at
+// path. The root is denoted by the empty path.
+func (t *stringTrie[V]) childPath(path, key string, root bool) string {
+	if t.escape != 0 {
+		key = EscapeSegment(key, t.delimiter, t.escape)
+	}
+	if root {
+		return key
+	}
+	return path + t.delimiter + key
+}
+
+// shortestKeys returns up to k paths of the values at and below v with the
+// fewest segments, paths are relative to v.
+func (t *stringTrie[V]) shortestKeys(v view[string, V], k int) []string {
+	type entry struct {
+		path string
+		v    view[string, V]
+	}
+
+	var keys []string
+	level := []entry{{v: v}}
+	for root := true; len(level) > 0 && len(keys) < k; root = false {
+		var next []entry
+		for _, e := range level {
+			_, hasValue, segments, children := t.sortedSnapshot(e.v)
+			if hasValue {
+				keys = append(keys, e.path)
+				if len(keys) == k {
+					break
+				}
+			}
+			for i, child := range children {
+				next = append(next, entry{path: t.childPath(e.path, segments[i], root), v: child})
+			}
+		}
+		level = next
+	}
+	return keys
+}
+
+// longestKeys returns up to k paths of the values at and below v with the
+// most segments, paths are relative to v.
+func (t *stringTrie[V]) longestKeys(v view[string, V], k int) []string {
+	if k <= 0 {
+		return nil
+	}
+
+	var (
+		h     depthHeap
+		seq   int
+		visit func(v view[string, V], path string, depth int)
+	)
+	visit = func(v view[string, V], path string, depth int) {
+		_, hasValue, segments, children := t.sortedSnapshot(v)
+		if hasValue {
+			e := depthEntry{path: path, depth: depth, seq: seq}
+			seq++
+			if len(h) < k {
+				heap.Push(&h, e)
+			} else if h.less(h[0], e) {
+				h[0] = e
+				heap.Fix(&h, 0)
+			}
+		}
+		for i, child := range children {
+			visit(child, t.childPath(path, segments[i], depth == 0), depth+1)
+		}
+	}
+	visit(v, "", 0)
+
+	sort.Slice(h, func(i, j int) bool { return h.less(h[j], h[i]) })
+	keys := make([]string, len(h))
+	for i, e := range h {
+		keys[i] = e.path
+	}
+	return keys
+}
+
+type depthEntry struct {
+	path  string
+	depth int
+	// seq is the position of the path in the walk order, it breaks ties
+	// between paths of the same depth in favour of the earlier one.
+	seq int
+}
+
+// depthHeap keeps the shallowest entry at the top so it can be replaced by
+// deeper ones.
+type depthHeap []depthEntry
+
+func (h depthHeap) less(a, b depthEntry) bool {
+	if a.depth == b.depth {
+		return a.seq > b.seq
+	}
+	return a.depth < b.depth
+}
+
+func (h depthHeap) Len() int           { return len(h) }
+func (h depthHeap) Less(i, j int) bool { return h.less(h[i], h[j]) }
+func (h depthHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *depthHeap) Push(x any) { *h = append(*h, x.(depthEntry)) }
+
+func (h *depthHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,156 @@
+// Package domain stores values for domain names. The names are kept in a
+// String trie keyed by their labels in reverse order, so that all names
+// within a zone share the node of the zone, and lookups implement the
+// wildcard semantics of DNS.
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"moehl.dev/trie"
+)
+
+// Wildcard is the label of wildcard names like *.example.com.
+const Wildcard = "*"
+
+// ErrInvalidName is returned if a domain name cannot be stored.
+var ErrInvalidName = errors.New("domain: invalid name")
+
+// Tree maps domain names to values. Names are compared case-insensitively and
+// a trailing dot is ignored, so example.com and EXAMPLE.COM. are the same
+// name. The empty name and "." refer to the root. A Tree is safe for
+// concurrent use.
+type Tree[V any] struct {
+	// lock serializes writes, since Delete removes the ancestors of a name
+	// that are no longer needed.
+	lock  sync.Mutex
+	names trie.String[V]
+}
+
+// New creates an empty Tree.
+func New[V any]() *Tree[V] {
+	return &Tree[V]{names: trie.New[V](".")}
+}
+
+// Labels returns the labels of name in reverse order, i.e. starting at the
+// top-level domain, in lower case.
+func Labels(name string) []string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == "" {
+		return nil
+	}
+	labels := strings.Split(name, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return labels
+}
+
+// Key returns the path under which name is stored in the trie, its labels in
+// reverse order joined by dots.
+func Key(name string) string {
+	return strings.Join(Labels(name), ".")
+}
+
+// Put stores the value for name. A * label is only a wildcard if it is the
+// leftmost label, elsewhere it is matched literally like any other label.
+func (t *Tree[V]) Put(name string, value V) error {
+	labels := Labels(name)
+	for _, label := range labels {
+		if label == "" {
+			return fmt.Errorf("%w: %q: empty label", ErrInvalidName, name)
+		}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.names.Put(strings.Join(labels, "."), value)
+	return nil
+}
+
+// Get returns the value stored for name without applying wildcards.
+func (t *Tree[V]) Get(name string) (value V, found bool) {
+	value, state := t.names.Lookup(Key(name))
+	return value, state == trie.Exists
+}
+
+// Delete the value of name and all names below it. Ancestors of name that
+// neither have a value nor other descendants are removed as well, so they no
+// longer hide wildcards from Match.
+func (t *Tree[V]) Delete(name string) {
+	labels := Labels(name)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.names.Delete(strings.Join(labels, "."))
+	for i := len(labels) - 1; i > 0; i-- {
+		parent := strings.Join(labels[:i], ".")
+		_, state := t.names.Lookup(parent)
+		children, _ := t.names.Children(parent)
+		if state != trie.ImplicitNode || len(children) > 0 {
+			break
+		}
+		t.names.Delete(parent)
+	}
+}
+
+// Match returns the value for name following the rules of RFC 1034 section
+// 4.3.3 as clarified by RFC 4592: if name has a value it is returned. If
+// name does not exist, the wildcard child of its closest encloser, the
+// longest existing ancestor, is used. A wildcard therefore matches one or
+// more labels, but not if the name or one of the labels it would replace
+// exists, even without a value. owner is the name the value is stored at, in
+// its original order.
+func (t *Tree[V]) Match(name string) (owner string, value V, ok bool) {
+	labels := Labels(name)
+
+	n := t.names.Root()
+	depth := 0
+	for ; depth < len(labels); depth++ {
+		child, found := n.Child(labels[depth])
+		if !found {
+			break
+		}
+		n = child
+	}
+
+	if depth == len(labels) {
+		// The name exists, wildcards never apply to it.
+		value, ok = n.Value()
+		if !ok {
+			return "", value, false
+		}
+		return fromLabels(labels), value, true
+	}
+
+	wildcard, found := n.Child(Wildcard)
+	if !found {
+		return "", value, false
+	}
+	value, ok = wildcard.Value()
+	if !ok {
+		return "", value, false
+	}
+	return fromLabels(append(labels[:depth:depth], Wildcard)), value, true
+}
+
+// Walk calls fn for every name that has a value, in sorted order of their
+// reversed labels, i.e. grouped by zone. Walking stops if fn returns false.
+func (t *Tree[V]) Walk(fn func(name string, value V) bool) {
+	t.names.Walk(func(key string, value V) bool {
+		return fn(fromLabels(strings.Split(key, ".")), value)
+	})
+}
+
+// fromLabels returns the name of the reversed labels.
+func fromLabels(labels []string) string {
+	name := make([]string, len(labels))
+	for i, label := range labels {
+		name[len(labels)-1-i] = label
+	}
+	return strings.Join(name, ".")
+}
@@ -0,0 +1,76 @@
+package domain_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"moehl.dev/trie/domain"
+)
+
+func TestTreeMatch(t *testing.T) {
+	// The example zone of RFC 4592 section 2.2.1.
+	d := domain.New[string]()
+	for _, name := range []string{
+		"example.",
+		"*.example.",
+		"host1.example.",
+		"sub.*.example.",
+		"_ssh._tcp.host1.example.",
+		"_ssh._tcp.host2.example.",
+		"subdel.example.",
+	} {
+		if err := d.Put(name, name); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		owner string
+		ok    bool
+	}{
+		{"host3.example.", "*.example", true},
+		{"foo.bar.example.", "*.example", true},
+		{"Host1.Example", "host1.example", true},
+		{"example", "example", true},
+		// Existing names without a value are not matched by wildcards.
+		{"_tcp.host1.example.", "", false},
+		{"host2.example.", "", false},
+		{"_telnet._tcp.host1.example.", "", false},
+		{"ghost.*.example.", "", false},
+		{"sub.*.example.", "sub.*.example", true},
+		{"host1.subdel.example.", "", false},
+		{"other.", "", false},
+	} {
+		owner, _, ok := d.Match(tc.name)
+		if owner != tc.owner || ok != tc.ok {
+			t.Errorf("%s: expected (%q, %v) but got (%q, %v)", tc.name, tc.owner, tc.ok, owner, ok)
+		}
+	}
+
+	// Removing the last name below host2 makes the wildcard apply again.
+	d.Delete("_ssh._tcp.host2.example")
+	if owner, _, _ := d.Match("host2.example"); owner != "*.example" {
+		t.Errorf("expected *.example but got %q", owner)
+	}
+	if _, found := d.Get("host3.example"); found {
+		t.Errorf("expected Get to not apply wildcards")
+	}
+
+	var names []string
+	d.Walk(func(name, _ string) bool {
+		names = append(names, name)
+		return true
+	})
+	expected := []string{"example", "*.example", "sub.*.example", "host1.example", "_ssh._tcp.host1.example", "subdel.example"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v but got %v", expected, names)
+	}
+
+	for _, name := range []string{"a..b", ".example"} {
+		if err := d.Put(name, name); !errors.Is(err, domain.ErrInvalidName) {
+			t.Errorf("%s: expected ErrInvalidName but got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"moehl.dev/trie"
+)
+
+// PublicSuffixList contains the rules of a public suffix list in the format
+// of https://publicsuffix.org/list/. The rules are stored in a Tree, so they
+// can be updated without rebuilding a table. A PublicSuffixList is safe for
+// concurrent use.
+type PublicSuffixList struct {
+	rules *Tree[suffixRule]
+}
+
+type suffixRule uint8
+
+const (
+	// ruleNormal is used for plain and wildcard rules, the wildcard is
+	// stored as a label of its own.
+	ruleNormal suffixRule = iota + 1
+	// ruleException is used for rules starting with !.
+	ruleException
+)
+
+// LoadPublicSuffixList reads the rules of a public suffix list from r. Rules
+// from the ICANN and the private section are treated the same. Both the rules
+// and the hosts passed to the methods of the list are compared as they are
+// after converting them to lower case, hosts in punycode have to be converted
+// to Unicode first to match rules in Unicode and vice versa.
+func LoadPublicSuffixList(r io.Reader) (*PublicSuffixList, error) {
+	l := &PublicSuffixList{rules: New[suffixRule]()}
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		// Only the text up to the first whitespace is part of the rule.
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "//") {
+			continue
+		}
+
+		rule, kind := fields[0], ruleNormal
+		if strings.HasPrefix(rule, "!") {
+			rule, kind = rule[1:], ruleException
+		}
+		err := l.rules.Put(rule, kind)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// PublicSuffix returns the public suffix of host. If no rule matches, the
+// last label is the public suffix as required by the default rule *.
+func (l *PublicSuffixList) PublicSuffix(host string) string {
+	labels := Labels(host)
+	return fromLabels(labels[:l.suffixLen(labels)])
+}
+
+// IsPublicSuffix reports whether host is a public suffix, i.e. whether it is
+// not possible to register a domain at host.
+func (l *PublicSuffixList) IsPublicSuffix(host string) bool {
+	labels := Labels(host)
+	return len(labels) > 0 && l.suffixLen(labels) == len(labels)
+}
+
+// EffectiveTLDPlusOne returns the public suffix of host plus the label
+// preceding it, e.g. the registered domain example.co.uk of
+// www.example.co.uk. An error is returned if host is a public suffix or
+// contains empty labels.
+func (l *PublicSuffixList) EffectiveTLDPlusOne(host string) (string, error) {
+	labels := Labels(host)
+	for _, label := range labels {
+		if label == "" {
+			return "", fmt.Errorf("%w: %q: empty label", ErrInvalidName, host)
+		}
+	}
+
+	n := l.suffixLen(labels)
+	if n >= len(labels) {
+		return "", fmt.Errorf("domain: %q is a public suffix", host)
+	}
+	return fromLabels(labels[:n+1]), nil
+}
+
+// suffixLen returns the number of labels of the public suffix of labels,
+// which are in reverse order. The rule with the most labels determines the
+// public suffix unless an exception rule matches, in which case the public
+// suffix is the exception without its leftmost label.
+func (l *PublicSuffixList) suffixLen(labels []string) int {
+	longest, exception := 0, 0
+	var match func(n trie.Node[suffixRule], depth int)
+	match = func(n trie.Node[suffixRule], depth int) {
+		if kind, ok := n.Value(); ok {
+			switch kind {
+			case ruleException:
+				exception = max(exception, depth)
+			case ruleNormal:
+				longest = max(longest, depth)
+			}
+		}
+		if depth == len(labels) {
+			return
+		}
+		if child, ok := n.Child(labels[depth]); ok {
+			match(child, depth+1)
+		}
+		if labels[depth] != Wildcard {
+			if child, ok := n.Child(Wildcard); ok {
+				match(child, depth+1)
+			}
+		}
+	}
+	match(l.rules.names.Root(), 0)
+
+	switch {
+	case exception > 0:
+		return exception - 1
+	case longest > 0:
+		return longest
+	}
+	return min(1, len(labels))
+}
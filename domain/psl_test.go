@@ -0,0 +1,81 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+
+	"moehl.dev/trie/domain"
+)
+
+const testList = `// ===BEGIN ICANN DOMAINS===
+com
+uk
+co.uk
+ck
+*.ck
+!www.ck
+jp
+ac.jp
+*.kobe.jp
+!city.kobe.jp
+cn
+公司.cn // with a comment
+// ===BEGIN PRIVATE DOMAINS===
+blogspot.com
+`
+
+func TestPublicSuffixList(t *testing.T) {
+	l, err := domain.LoadPublicSuffixList(strings.NewReader(testList))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Test cases taken from the tests of the public suffix list.
+	for _, tc := range []struct {
+		host, etldPlusOne string
+	}{
+		{"com", ""},
+		{"example.com", "example.com"},
+		{"WWW.Example.COM.", "example.com"},
+		{"blogspot.com", ""},
+		{"foo.blogspot.com", "foo.blogspot.com"},
+		{"example", ""},
+		{"b.example.example", "example.example"},
+		{"www.example.co.uk", "example.co.uk"},
+		{"ck", ""},
+		{"test.ck", ""},
+		{"b.test.ck", "b.test.ck"},
+		{"www.ck", "www.ck"},
+		{"www.www.ck", "www.ck"},
+		{"c.kobe.jp", ""},
+		{"b.c.kobe.jp", "b.c.kobe.jp"},
+		{"city.kobe.jp", "city.kobe.jp"},
+		{"www.city.kobe.jp", "city.kobe.jp"},
+		{"食狮.公司.cn", "食狮.公司.cn"},
+		{"www.食狮.公司.cn", "食狮.公司.cn"},
+		{"a..com", ""},
+	} {
+		got, err := l.EffectiveTLDPlusOne(tc.host)
+		if got != tc.etldPlusOne || (err != nil) != (tc.etldPlusOne == "") {
+			t.Errorf("%s: expected %q but got %q, %v", tc.host, tc.etldPlusOne, got, err)
+		}
+	}
+
+	for host, expected := range map[string]bool{
+		"com":         true,
+		"co.uk":       true,
+		"example.com": false,
+		"test.ck":     true,
+		"www.ck":      false,
+		"unknown":     true,
+		"":            false,
+	} {
+		if got := l.IsPublicSuffix(host); got != expected {
+			t.Errorf("%s: expected %v but got %v", host, expected, got)
+		}
+	}
+
+	if got := l.PublicSuffix("www.city.kobe.jp"); got != "kobe.jp" {
+		t.Errorf("expected kobe.jp but got %q", got)
+	}
+}
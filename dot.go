@@ -0,0 +1,81 @@
+package trie
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DotOption configures the output of WriteDOT.
+type DotOption func(*dotConfig)
+
+type dotConfig struct {
+	values  bool
+	markers bool
+}
+
+// DotValues adds the values of nodes that have a value set to their labels.
+func DotValues() DotOption {
+	return func(c *dotConfig) {
+		c.values = true
+	}
+}
+
+// DotValueMarkers draws nodes that have a value set as a double circle to
+// distinguish them from nodes that were only created as part of a longer path.
+func DotValueMarkers() DotOption {
+	return func(c *dotConfig) {
+		c.markers = true
+	}
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteDOT renders the structure of the trie as a graph in the DOT language.
+// Segments are used as edge labels, the root node is labelled with the
+// delimiter.
+func (t *stringTrie[V]) WriteDOT(w io.Writer, opts ...DotOption) error {
+	defer t.unpin(t.pin())
+	var c dotConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph trie {")
+	fmt.Fprintf(bw, "\tn0 [label=\"%s\"];\n", dotEscaper.Replace(t.delimiter))
+
+	id := 0
+	_, _, keys, children := sortedSnapshot(view[string, V]{n: t.root})
+	for i, child := range children {
+		writeDOT(bw, &c, child, keys[i], 0, &id)
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// writeDOT writes the node v with the given key and all of its children.
+func writeDOT[V any](w io.Writer, c *dotConfig, v view[string, V], key string, parent int, id *int) {
+	value, hasValue, keys, children := sortedSnapshot(v)
+
+	*id++
+	self := *id
+
+	label := key
+	if c.values && hasValue {
+		label = fmt.Sprintf("%s\n%v", label, value)
+	}
+	shape := "circle"
+	if c.markers && hasValue {
+		shape = "doublecircle"
+	}
+
+	fmt.Fprintf(w, "\tn%d [label=\"%s\", shape=%s];\n", self, dotEscaper.Replace(label), shape)
+	fmt.Fprintf(w, "\tn%d -> n%d;\n", parent, self)
+
+	for i, child := range children {
+		writeDOT(w, c, child, keys[i], self, id)
+	}
+}
@@ -0,0 +1,41 @@
+package trie
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Dump writes an indented rendering of the trie in the style of tree(1) to w.
+// Nodes that have a value set are followed by a colon and their value.
+func (t *stringTrie[V]) Dump(w io.Writer) error {
+	defer t.unpin(t.pin())
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, t.delimiter)
+	_, _, keys, children := sortedSnapshot(view[string, V]{n: t.root})
+	for i, child := range children {
+		dump(bw, child, keys[i], "", i == len(children)-1)
+	}
+	return bw.Flush()
+}
+
+// dump writes the node v with the given key and all of its children. last
+// indicates whether v is the last child of its parent.
+func dump[V any](w io.Writer, v view[string, V], key, indent string, last bool) {
+	value, hasValue, keys, children := sortedSnapshot(v)
+
+	branch, next := "├── ", "│   "
+	if last {
+		branch, next = "└── ", "    "
+	}
+
+	if hasValue {
+		fmt.Fprintf(w, "%s%s%s: %v\n", indent, branch, key, value)
+	} else {
+		fmt.Fprintf(w, "%s%s%s\n", indent, branch, key)
+	}
+
+	for i, child := range children {
+		dump(w, child, keys[i], indent+next, i == len(children)-1)
+	}
+}
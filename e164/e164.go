@@ -0,0 +1,81 @@
+// Package e164 routes telephone numbers in the E.164 format by their longest
+// matching dial prefix. The prefixes are stored digit by digit in a Slice
+// trie, so a number is routed in a single descent.
+package e164
+
+import (
+	"errors"
+	"fmt"
+
+	"moehl.dev/trie"
+)
+
+// MaxDigits is the maximum number of digits of an E.164 number.
+const MaxDigits = 15
+
+// ErrInvalidNumber is returned if a prefix or number contains characters
+// other than digits and separators, or too many digits.
+var ErrInvalidNumber = errors.New("e164: invalid number")
+
+// Table maps dial prefixes to values, e.g. carriers. A Table is safe for
+// concurrent use.
+type Table[V any] struct {
+	prefixes trie.Slice[byte, V]
+}
+
+// New creates an empty Table.
+func New[V any]() *Table[V] {
+	return &Table[V]{prefixes: trie.NewSlice[byte, V]()}
+}
+
+// digits returns the digits of number. A leading + as well as spaces, dashes,
+// dots and parentheses are ignored.
+func digits(number string) ([]byte, error) {
+	d := make([]byte, 0, MaxDigits)
+	for i, c := range []byte(number) {
+		switch {
+		case c >= '0' && c <= '9':
+			d = append(d, c)
+		case c == '+' && i == 0, c == ' ', c == '-', c == '.', c == '(', c == ')':
+		default:
+			return nil, fmt.Errorf("%w: %q: unexpected character %q", ErrInvalidNumber, number, c)
+		}
+	}
+	if len(d) > MaxDigits {
+		return nil, fmt.Errorf("%w: %q: more than %d digits", ErrInvalidNumber, number, MaxDigits)
+	}
+	return d, nil
+}
+
+// AddPrefix sets the value of the dial prefix, e.g. 4930 for Berlin. The
+// empty prefix is the default route.
+func (t *Table[V]) AddPrefix(prefix string, value V) error {
+	d, err := digits(prefix)
+	if err != nil {
+		return err
+	}
+	t.prefixes.Put(d, value)
+	return nil
+}
+
+// RemovePrefix removes the dial prefix and all longer prefixes starting with
+// it.
+func (t *Table[V]) RemovePrefix(prefix string) error {
+	d, err := digits(prefix)
+	if err != nil {
+		return err
+	}
+	t.prefixes.Delete(d)
+	return nil
+}
+
+// Route returns the longest dial prefix of number that has a value and its
+// value. ok is false if no prefix matches or the number is invalid.
+func (t *Table[V]) Route(number string) (prefix string, value V, ok bool) {
+	d, err := digits(number)
+	if err != nil {
+		return "", value, false
+	}
+	n, value, ok := t.prefixes.LongestPrefix(d)
+	return string(d[:n]), value, ok
+}
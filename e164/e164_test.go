@@ -0,0 +1,52 @@
+package e164_test
+
+import (
+	"errors"
+	"testing"
+
+	"moehl.dev/trie/e164"
+)
+
+func TestTableRoute(t *testing.T) {
+	tbl := e164.New[string]()
+	for prefix, carrier := range map[string]string{
+		"49":      "de",
+		"4930":    "berlin",
+		"49301":   "berlin-1",
+		"1":       "nanp",
+		"1 (212)": "nyc",
+	} {
+		if err := tbl.AddPrefix(prefix, carrier); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		number, prefix, carrier string
+		ok                      bool
+	}{
+		{"+49301234567", "49301", "berlin-1", true},
+		{"+49 30 2345678", "4930", "berlin", true},
+		{"+49-89-123456", "49", "de", true},
+		{"+1 (212) 555-0100", "1212", "nyc", true},
+		{"+1 415 555 0100", "1", "nanp", true},
+		{"+33 1 23456789", "", "", false},
+		{"+49 30 abc", "", "", false},
+		{"+4930123456789012", "", "", false},
+	} {
+		prefix, carrier, ok := tbl.Route(tc.number)
+		if prefix != tc.prefix || carrier != tc.carrier || ok != tc.ok {
+			t.Errorf("%s: expected (%q, %q, %v) but got (%q, %q, %v)", tc.number, tc.prefix, tc.carrier, tc.ok, prefix, carrier, ok)
+		}
+	}
+
+	if err := tbl.RemovePrefix("4930"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prefix, _, _ := tbl.Route("+49301234567"); prefix != "49" {
+		t.Errorf("expected 49 after removing 4930 but got %q", prefix)
+	}
+	if err := tbl.AddPrefix("49+30", ""); !errors.Is(err, e164.ErrInvalidNumber) {
+		t.Errorf("expected ErrInvalidNumber but got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Package env resolves configuration from environment variables like
+// APP_DB_PRIMARY_TIMEOUT=5s. The names are split at a separator and stored in
+// a String trie, so variables can be scoped to a prefix and settings can be
+// given defaults at any level: a lookup of TIMEOUT in the scope DB_PRIMARY
+// falls back to DB_TIMEOUT and then to TIMEOUT.
+package env
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"moehl.dev/trie"
+)
+
+// Env contains the variables of one application, optionally scoped to a
+// prefix of their names. Names are case-insensitive. An Env is safe for
+// concurrent use.
+type Env struct {
+	vars      trie.String[string]
+	separator string
+	scope     []string
+}
+
+// Load creates an Env containing the variables in environ, in the format
+// returned by os.Environ, whose names start with prefix followed by
+// separator. The prefix is removed from the names. If prefix is empty, all
+// variables are loaded. If environ is nil, os.Environ is used.
+func Load(prefix, separator string, environ []string) *Env {
+	if environ == nil {
+		environ = os.Environ()
+	}
+	e := &Env{
+		vars:      trie.New[string](separator, trie.WithCaseFolding()),
+		separator: separator,
+	}
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if prefix != "" {
+			if name, ok = strings.CutPrefix(name, prefix+separator); !ok {
+				continue
+			}
+		}
+		e.vars.Put(name, value)
+	}
+	return e
+}
+
+// Scope returns an Env for the variables below scope, e.g. DB_PRIMARY. Lookups
+// in the returned Env fall back to the parents of the scope.
+func (e *Env) Scope(scope string) *Env {
+	segments := strings.Split(scope, e.separator)
+	return &Env{
+		vars:      e.vars,
+		separator: e.separator,
+		scope:     append(e.scope[:len(e.scope):len(e.scope)], segments...),
+	}
+}
+
+// Lookup returns the value of the variable name in the scope of e or, if it
+// is not set there, in the nearest parent scope.
+func (e *Env) Lookup(name string) (value string, found bool) {
+	for i := len(e.scope); i >= 0; i-- {
+		key := strings.Join(append(e.scope[:i:i], name), e.separator)
+		if value, state := e.vars.Lookup(key); state == trie.Exists {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Names returns the names of all variables in the scope of e, without the
+// scope, in lower case and in sorted order. Variables of parent scopes are not included.
+func (e *Env) Names() []string {
+	vars := e.vars
+	if len(e.scope) > 0 {
+		vars = trie.Namespace(e.vars, strings.Join(e.scope, e.separator))
+	}
+	var names []string
+	vars.Walk(func(path string, _ string) bool {
+		if path != "" {
+			names = append(names, path)
+		}
+		return true
+	})
+	return names
+}
+
+// String returns the value of the variable name, see Lookup, or def if it is
+// not set.
+func (e *Env) String(name, def string) string {
+	if value, found := e.Lookup(name); found {
+		return value
+	}
+	return def
+}
+
+// Int is like String for integers.
+func (e *Env) Int(name string, def int) (int, error) {
+	return get(e, name, def, strconv.Atoi)
+}
+
+// Float64 is like String for floating point numbers.
+func (e *Env) Float64(name string, def float64) (float64, error) {
+	return get(e, name, def, func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+}
+
+// Bool is like String for booleans in the format accepted by
+// strconv.ParseBool.
+func (e *Env) Bool(name string, def bool) (bool, error) {
+	return get(e, name, def, strconv.ParseBool)
+}
+
+// Duration is like String for durations in the format accepted by
+// time.ParseDuration.
+func (e *Env) Duration(name string, def time.Duration) (time.Duration, error) {
+	return get(e, name, def, time.ParseDuration)
+}
+
+// get parses the value of name, it returns def if name is not set.
+func get[T any](e *Env, name string, def T, parse func(string) (T, error)) (T, error) {
+	value, found := e.Lookup(name)
+	if !found {
+		return def, nil
+	}
+	v, err := parse(value)
+	if err != nil {
+		return def, fmt.Errorf("env: %s: %w", strings.Join(append(e.scope[:len(e.scope):len(e.scope)], name), e.separator), err)
+	}
+	return v, nil
+}
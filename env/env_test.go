@@ -0,0 +1,49 @@
+package env_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"moehl.dev/trie/env"
+)
+
+func TestEnv(t *testing.T) {
+	e := env.Load("APP", "_", []string{
+		"APP_TIMEOUT=10s",
+		"APP_DB_TIMEOUT=5s",
+		"APP_DB_PRIMARY_HOST=db1",
+		"APP_DB_PRIMARY_MAX=20",
+		"APP_DB_REPLICA_HOST=db2",
+		"APP_DEBUG=true",
+		"OTHER_TIMEOUT=1s",
+		"INVALID",
+	})
+
+	primary := e.Scope("DB_PRIMARY")
+	if got, _ := primary.Duration("TIMEOUT", 0); got != 5*time.Second {
+		t.Errorf("expected 5s from DB_TIMEOUT but got %v", got)
+	}
+	if got, _ := e.Scope("CACHE").Duration("timeout", 0); got != 10*time.Second {
+		t.Errorf("expected 10s from TIMEOUT but got %v", got)
+	}
+	if got := primary.String("HOST", ""); got != "db1" {
+		t.Errorf("expected db1 but got %q", got)
+	}
+	if got, err := primary.Int("MAX", 1); got != 20 || err != nil {
+		t.Errorf("expected 20 but got %v, %v", got, err)
+	}
+	if got, err := e.Scope("DB").Scope("REPLICA").Int("MAX", 1); got != 1 || err != nil {
+		t.Errorf("expected the default but got %v, %v", got, err)
+	}
+	if got, err := e.Bool("DEBUG", false); !got || err != nil {
+		t.Errorf("expected true but got %v, %v", got, err)
+	}
+	if _, err := primary.Int("HOST", 0); err == nil {
+		t.Errorf("expected an error for an invalid integer")
+	}
+
+	if got := e.Scope("DB").Names(); !reflect.DeepEqual(got, []string{"primary_host", "primary_max", "replica_host", "timeout"}) {
+		t.Errorf("unexpected names %v", got)
+	}
+}
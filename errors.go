@@ -0,0 +1,18 @@
+package trie
+
+import (
+	"errors"
+)
+
+// Errors wrapped by the error-returning variants of the methods of a trie, use
+// errors.Is to check for them.
+var (
+	// ErrNotFound is returned if a path does not exist in the trie.
+	ErrNotFound = errors.New("trie: not found")
+	// ErrInvalidKey is returned if a path is rejected, e.g. by the key
+	// validator.
+	ErrInvalidKey = errors.New("trie: invalid key")
+	// ErrReadOnly is returned by the write methods of read-only tries such
+	// as Mapped and Segment.
+	ErrReadOnly = errors.New("trie: read-only")
+)
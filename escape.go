@@ -0,0 +1,103 @@
+package trie
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// EscapeSegment escapes all occurrences of delimiter and escape in segment so
+// that it can be used as a single segment of a path in a trie created with
+// WithEscape.
+func EscapeSegment(segment, delimiter string, escape rune) string {
+	e := string(escape)
+	if !strings.Contains(segment, delimiter) && !strings.Contains(segment, e) {
+		return segment
+	}
+
+	var b strings.Builder
+	for segment != "" {
+		switch {
+		case strings.HasPrefix(segment, e):
+			b.WriteString(e)
+			b.WriteString(e)
+			segment = segment[len(e):]
+		case strings.HasPrefix(segment, delimiter):
+			b.WriteString(e)
+			b.WriteString(delimiter)
+			segment = segment[len(delimiter):]
+		default:
+			_, size := utf8.DecodeRuneInString(segment)
+			b.WriteString(segment[:size])
+			segment = segment[size:]
+		}
+	}
+	return b.String()
+}
+
+// cutEscaped is like strings.Cut but skips delimiters that are preceded by
+// escape. The escape characters are removed from key. An escape at the end
+// of path is kept as is.
+func cutEscaped(path, delimiter string, escape rune) (key, rest string) {
+	e := string(escape)
+	escaped := false
+
+	for i := 0; i < len(path); {
+		switch {
+		case strings.HasPrefix(path[i:], e) && i+len(e) < len(path):
+			escaped = true
+			i += len(e) + escapedLen(path[i+len(e):], delimiter)
+		case strings.HasPrefix(path[i:], delimiter):
+			key, rest = path[:i], path[i+len(delimiter):]
+			if escaped {
+				key = unescape(key, delimiter, e)
+			}
+			return key, rest
+		default:
+			i++
+		}
+	}
+
+	if escaped {
+		path = unescape(path, delimiter, e)
+	}
+	return path, ""
+}
+
+// escapedSuffix returns whether s ends with an odd number of escapes, i.e.
+// whether whatever follows s is escaped.
+func escapedSuffix(s string, escape rune) bool {
+	e := string(escape)
+	n := 0
+	for strings.HasSuffix(s, e) {
+		s = s[:len(s)-len(e)]
+		n++
+	}
+	return n%2 == 1
+}
+
+// escapedLen returns the length of the escaped delimiter or rune at the start
+// of s.
+func escapedLen(s, delimiter string) int {
+	if strings.HasPrefix(s, delimiter) {
+		return len(delimiter)
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return size
+}
+
+// unescape removes the escape characters e from key.
+func unescape(key, delimiter, e string) string {
+	var b strings.Builder
+	for key != "" {
+		if strings.HasPrefix(key, e) && len(key) > len(e) {
+			key = key[len(e):]
+			size := escapedLen(key, delimiter)
+			b.WriteString(key[:size])
+			key = key[size:]
+			continue
+		}
+		b.WriteByte(key[0])
+		key = key[1:]
+	}
+	return b.String()
+}
@@ -0,0 +1,167 @@
+// Package flags implements hierarchical feature flags. A flag is set at a path
+// like org/team/service/flag, where the last segment is the name of the flag
+// and the segments before it are the scope the setting applies to. A flag is
+// evaluated for a scope by finding the nearest explicit setting, i.e. the one
+// with the longest scope that is a prefix of the scope being evaluated.
+package flags
+
+import (
+	"strings"
+	"sync"
+
+	"moehl.dev/trie"
+)
+
+// Store contains the settings of all flags and notifies watchers about
+// changes. It is safe for concurrent use.
+//
+// Internally the settings are keyed by the name of the flag followed by the
+// scope, so all settings of a flag that apply to a scope lie on its path from
+// the root and are found by a single WalkPath.
+type Store[V any] struct {
+	delimiter string
+
+	// lock serializes changes and the notifications about them.
+	lock     sync.Mutex
+	settings trie.String[setting[V]]
+	watchers trie.String[[]*watcher[V]]
+}
+
+// setting is the value of a flag in a scope. Unset flags of scopes that
+// contain other scopes are kept in the trie with set being false.
+type setting[V any] struct {
+	value V
+	set   bool
+}
+
+type watcher[V any] struct {
+	key string
+	fn  func(value V, ok bool)
+	// value and ok are the result of the last evaluation.
+	value V
+	ok    bool
+}
+
+// New creates an empty Store for paths delimited by delimiter.
+func New[V any](delimiter string) *Store[V] {
+	return &Store[V]{
+		delimiter: delimiter,
+		settings:  trie.New[setting[V]](delimiter),
+		watchers:  trie.New[[]*watcher[V]](delimiter),
+	}
+}
+
+// key returns the internal key of path, the name of the flag followed by the
+// scope.
+func (s *Store[V]) key(path string) string {
+	i := strings.LastIndex(path, s.delimiter)
+	if i < 0 {
+		return path
+	}
+	return path[i+len(s.delimiter):] + s.delimiter + path[:i]
+}
+
+// Set the flag at path, the last segment of path is the name of the flag.
+// Watchers of the flag in the scope and all scopes below it are notified if
+// their value changes.
+func (s *Store[V]) Set(path string, value V) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := s.key(path)
+	s.settings.Put(key, setting[V]{value, true})
+	s.notify(key)
+}
+
+// Unset removes the explicit setting of the flag at path, scopes below it are
+// not affected. Watchers are notified like for Set.
+func (s *Store[V]) Unset(path string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := s.key(path)
+	if current, _ := s.settings.Get(key); !current.set {
+		return
+	}
+	if children, _ := s.settings.Children(key); len(children) > 0 {
+		// Deleting the node would remove the settings of the scopes
+		// below as well.
+		s.settings.Put(key, setting[V]{})
+	} else {
+		s.settings.Delete(key)
+	}
+	s.notify(key)
+}
+
+// Evaluate returns the value of the nearest explicit setting of the flag at
+// path, i.e. of the flag in the scope of path or, if it is not set there, in
+// the closest parent scope. ok is false if the flag is not set in any of them.
+func (s *Store[V]) Evaluate(path string) (value V, ok bool) {
+	return s.evaluate(s.key(path))
+}
+
+func (s *Store[V]) evaluate(key string) (value V, ok bool) {
+	// The first segment of the key is the flag itself, the flag without
+	// a scope is stored there as well.
+	s.settings.WalkPath(key, func(_ string, v setting[V]) bool {
+		if v.set {
+			value, ok = v.value, true
+		}
+		return true
+	})
+	return value, ok
+}
+
+// Watch calls fn with the current value of the flag at path, as returned by
+// Evaluate, and again every time it changes. fn is called while the store is
+// locked and must not modify it. The returned function stops watching.
+func (s *Store[V]) Watch(path string, fn func(value V, ok bool)) (cancel func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	w := &watcher[V]{key: s.key(path), fn: fn}
+	w.value, w.ok = s.evaluate(w.key)
+	watchers, _ := s.watchers.Get(w.key)
+	s.watchers.Put(w.key, append(watchers, w))
+	fn(w.value, w.ok)
+
+	return func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		watchers, _ := s.watchers.Get(w.key)
+		for i, other := range watchers {
+			if other == w {
+				watchers = append(watchers[:i:i], watchers[i+1:]...)
+				break
+			}
+		}
+		s.watchers.Put(w.key, watchers)
+	}
+}
+
+// notify calls all watchers at and below key whose value has changed.
+func (s *Store[V]) notify(key string) {
+	trie.Namespace(s.watchers, key).Walk(func(_ string, watchers []*watcher[V]) bool {
+		for _, w := range watchers {
+			value, ok := s.evaluate(w.key)
+			if ok == w.ok && (!ok || equal(value, w.value)) {
+				continue
+			}
+			w.value, w.ok = value, ok
+			w.fn(value, ok)
+		}
+		return true
+	})
+}
+
+// equal compares values of comparable types, values of other types are never
+// equal so that watchers are always notified.
+func equal[V any](a, b V) (eq bool) {
+	defer func() {
+		if recover() != nil {
+			eq = false
+		}
+	}()
+	return any(a) == any(b)
+}
@@ -0,0 +1,65 @@
+package flags_test
+
+import (
+	"reflect"
+	"testing"
+
+	"moehl.dev/trie/flags"
+)
+
+func TestStoreEvaluate(t *testing.T) {
+	s := flags.New[bool]("/")
+	s.Set("beta", false)
+	s.Set("acme/beta", true)
+	s.Set("acme/search/indexer/beta", false)
+
+	for path, expected := range map[string]bool{
+		"beta":                         false,
+		"other/beta":                   false,
+		"acme/beta":                    true,
+		"acme/search/beta":             true,
+		"acme/search/indexer/beta":     false,
+		"acme/search/indexer/x/y/beta": false,
+	} {
+		if got, ok := s.Evaluate(path); !ok || got != expected {
+			t.Errorf("%s: expected %v but got %v, %v", path, expected, got, ok)
+		}
+	}
+	if _, ok := s.Evaluate("acme/search/unknown"); ok {
+		t.Errorf("expected an unknown flag to not be set")
+	}
+
+	s.Unset("acme/beta")
+	if got, _ := s.Evaluate("acme/search/beta"); got {
+		t.Errorf("expected the global setting after unsetting acme/beta")
+	}
+	if got, _ := s.Evaluate("acme/search/indexer/beta"); got {
+		t.Errorf("expected the setting of acme/search/indexer to be kept")
+	}
+}
+
+func TestStoreWatch(t *testing.T) {
+	s := flags.New[string]("/")
+	var got []string
+	cancel := s.Watch("acme/search/mode", func(value string, ok bool) {
+		if !ok {
+			value = "<unset>"
+		}
+		got = append(got, value)
+	})
+
+	s.Set("mode", "a")
+	s.Set("acme/mode", "b")
+	// Neither changes the value for acme/search.
+	s.Set("mode", "c")
+	s.Set("acme/other/mode", "d")
+	s.Set("acme/mode", "b")
+	s.Unset("acme/mode")
+	cancel()
+	s.Set("acme/search/mode", "e")
+
+	expected := []string{"<unset>", "a", "b", "c"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
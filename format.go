@@ -0,0 +1,84 @@
+package trie
+
+import (
+	"fmt"
+	"io"
+)
+
+// Stats describes the size of a trie.
+type Stats struct {
+	// Values is the number of values set by Put.
+	Values int
+	// Nodes is the number of nodes that have been allocated. Chains of nodes
+	// without a value are stored in a single node, so it can be lower than
+	// Values.
+	Nodes int
+	// Depth is the number of segments of the longest path.
+	Depth int
+}
+
+// StatsOf returns the size of t. The nodes are locked one at a time, the
+// result of a trie that is modified concurrently does not necessarily
+// describe any state the trie has been in.
+func StatsOf[V any](t String[V]) Stats {
+	n := t.Root()
+	defer n.t.unpin(n.t.pin())
+	v, ok := n.resolve()
+	if !ok {
+		return Stats{}
+	}
+	return viewStats(v)
+}
+
+// stats returns the size of the whole tree.
+func (t *tree[K, V]) stats() Stats {
+	defer t.unpin(t.pin())
+	return viewStats(view[K, V]{n: t.root})
+}
+
+func viewStats[K comparable, V any](v view[K, V]) Stats {
+	var (
+		s     Stats
+		visit func(v view[K, V], d int)
+	)
+	visit = func(v view[K, V], d int) {
+		_, hasValue, _, children := v.snapshot()
+		if hasValue {
+			s.Values++
+		}
+		if len(v.run) == 0 {
+			s.Nodes++
+		}
+		s.Depth = max(s.Depth, d)
+		for _, child := range children {
+			visit(child, d+1)
+		}
+	}
+	visit(v, 0)
+	return s
+}
+
+// String returns a summary of the trie containing its delimiter, the number of
+// values and the number of segments of the longest path.
+func (t *stringTrie[V]) String() string {
+	s := t.stats()
+	return fmt.Sprintf("trie.String{delimiter: %q, values: %d, depth: %d}", t.delimiter, s.Values, s.Depth)
+}
+
+// Format implements fmt.Formatter. The verb %+v writes the structure of the
+// trie as rendered by Dump, all other verbs write the summary returned by
+// String.
+func (t *stringTrie[V]) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('+') {
+		_ = t.Dump(f)
+		return
+	}
+	_, _ = io.WriteString(f, t.String())
+}
+
+// String returns a summary of the trie containing the number of values and
+// the number of segments of the longest path.
+func (t *sliceTrie[K, V]) String() string {
+	s := t.stats()
+	return fmt.Sprintf("trie.Slice{values: %d, depth: %d}", s.Values, s.Depth)
+}
@@ -0,0 +1,35 @@
+package trie
+
+import (
+	"io/fs"
+)
+
+// FromFS walks fsys and returns a trie containing the fs.FileInfo of every
+// entry indexed by its path. The paths are delimited by "/" as defined by
+// fs.FS, the root directory "." is stored at the empty path.
+func FromFS(fsys fs.FS) (String[fs.FileInfo], error) {
+	t := New[fs.FileInfo]("/")
+
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if path == "." {
+			path = ""
+		}
+		t.Put(path, info)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
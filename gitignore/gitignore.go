@@ -0,0 +1,279 @@
+// Package gitignore matches paths against patterns in the format of
+// .gitignore files. The patterns are stored in a String trie keyed by their
+// segments, so a path is matched by descending the trie once, segment by
+// segment, instead of matching it against every pattern on its own. Only the
+// children of a node that contain wildcards are matched with path.Match, all
+// other children are looked up directly.
+package gitignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"moehl.dev/trie"
+)
+
+// doubleStar is the segment matching any number of segments.
+const doubleStar = "**"
+
+// ErrInvalidPattern is returned by Add if a pattern contains a malformed
+// character class.
+var ErrInvalidPattern = errors.New("gitignore: invalid pattern")
+
+// Matcher contains a list of patterns. Like in a .gitignore file, the last
+// pattern matching a path decides whether it is ignored, and a path cannot be
+// re-included by a negated pattern if one of its parent directories is
+// ignored. A Matcher is safe for concurrent use.
+type Matcher struct {
+	lock     sync.RWMutex
+	patterns trie.String[*entry]
+	count    int
+}
+
+// entry contains the patterns ending at a node and the children of the node
+// that contain wildcards.
+type entry struct {
+	rules []rule
+	globs []string
+	// star is set if the node has a ** child, double if it is one.
+	star, double bool
+}
+
+type rule struct {
+	// index is the position of the pattern, later patterns take precedence.
+	index   int
+	negate  bool
+	dirOnly bool
+	// descendants is set for patterns ending in /**, which match everything
+	// inside a directory but not the directory itself.
+	descendants bool
+}
+
+// New creates a Matcher without any patterns.
+func New() *Matcher {
+	m := &Matcher{patterns: trie.New[*entry]("/")}
+	m.patterns.Put("", &entry{})
+	return m
+}
+
+// Load adds all patterns read from r, one per line.
+func (m *Matcher) Load(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		err := m.Add(s.Text())
+		if err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// Add a single line of a .gitignore file. Empty lines and comments are
+// ignored.
+func (m *Matcher) Add(line string) error {
+	pattern, r, ok := parse(line)
+	if !ok {
+		return nil
+	}
+
+	segments := strings.Split(pattern, "/")
+	for _, s := range segments {
+		if _, err := path.Match(s, ""); err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidPattern, line)
+		}
+	}
+	if len(segments) > 1 && segments[len(segments)-1] == doubleStar {
+		r.descendants = true
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r.index = m.count
+	m.count++
+
+	e, _ := m.patterns.Get("")
+	for i, s := range segments {
+		p := strings.Join(segments[:i+1], "/")
+		child, found := m.patterns.Get(p)
+		if !found || child == nil {
+			child = &entry{double: s == doubleStar}
+			m.patterns.Put(p, child)
+			switch {
+			case s == doubleStar:
+				e.star = true
+			case isGlob(s):
+				e.globs = append(e.globs, s)
+			}
+		}
+		e = child
+	}
+	e.rules = append(e.rules, r)
+	return nil
+}
+
+// parse returns the pattern of a line relative to the root, with ** prepended
+// if it matches at any level, and the rule it describes.
+func parse(line string) (pattern string, r rule, ok bool) {
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return "", r, false
+	}
+
+	if line[0] == '!' {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return "", r, false
+	}
+
+	// Patterns without a slash other than a trailing one match at any
+	// level, all other patterns are relative to the root.
+	if strings.Contains(line, "/") {
+		line = strings.TrimLeft(line, "/")
+	} else {
+		line = doubleStar + "/" + line
+	}
+
+	// Consecutive ** match the same paths as a single one.
+	segments := strings.Split(line, "/")
+	n := 0
+	for _, s := range segments {
+		if s == "" || s == doubleStar && n > 0 && segments[n-1] == doubleStar {
+			continue
+		}
+		segments[n] = s
+		n++
+	}
+	return strings.Join(segments[:n], "/"), r, true
+}
+
+func isGlob(segment string) bool {
+	return strings.ContainsAny(segment, "*?[\\")
+}
+
+// Ignored reports whether the path, relative to the directory of the
+// patterns and using forward slashes, is ignored. A trailing slash marks the
+// path as a directory.
+func (m *Matcher) Ignored(p string) bool {
+	isDir := strings.HasSuffix(p, "/")
+	return m.Match(strings.Trim(p, "/"), isDir)
+}
+
+// Match reports whether the path is ignored, isDir indicates whether it
+// refers to a directory.
+func (m *Matcher) Match(p string, isDir bool) bool {
+	if p == "" {
+		return false
+	}
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	root := m.patterns.Root()
+	e, _ := root.Value()
+	states := closure([]state{{n: root, e: e}})
+	for {
+		segment, rest, more := strings.Cut(p, "/")
+		states = step(states, segment)
+		if len(states) == 0 {
+			return false
+		}
+		if !more {
+			return ignored(states, isDir)
+		}
+		if ignored(states, true) {
+			// Nothing inside an ignored directory can be re-included.
+			return true
+		}
+		p = rest
+	}
+}
+
+// state is a node of the trie that matches the segments consumed so far.
+type state struct {
+	n trie.Node[*entry]
+	e *entry
+	// zero is set if n is a ** node that has not consumed any segment yet.
+	zero bool
+}
+
+// step returns the states reached by consuming segment in any of states.
+func step(states []state, segment string) []state {
+	var next []state
+	for _, s := range states {
+		if s.e.double {
+			next = append(next, state{n: s.n, e: s.e})
+		}
+		if !isGlob(segment) && segment != doubleStar {
+			if c, ok := s.n.Child(segment); ok {
+				e, _ := c.Value()
+				next = append(next, state{n: c, e: e})
+			}
+		}
+		for _, g := range s.e.globs {
+			if ok, _ := path.Match(g, segment); ok {
+				c, _ := s.n.Child(g)
+				e, _ := c.Value()
+				next = append(next, state{n: c, e: e})
+			}
+		}
+	}
+	return closure(next)
+}
+
+// closure adds the ** children of all states, which match zero segments,
+// and removes duplicate states.
+func closure(states []state) []state {
+	type key struct {
+		e    *entry
+		zero bool
+	}
+	seen := make(map[key]bool, len(states))
+	out := make([]state, 0, len(states))
+	for i := 0; i < len(states); i++ {
+		s := states[i]
+		if seen[key{s.e, s.zero}] {
+			continue
+		}
+		seen[key{s.e, s.zero}] = true
+		out = append(out, s)
+		if s.e.star {
+			c, _ := s.n.Child(doubleStar)
+			e, _ := c.Value()
+			states = append(states, state{n: c, e: e, zero: true})
+		}
+	}
+	return out
+}
+
+// ignored returns the result of the last rule matching in any of the states.
+func ignored(states []state, isDir bool) bool {
+	index, result := -1, false
+	for _, s := range states {
+		for _, r := range s.e.rules {
+			if r.dirOnly && !isDir || r.descendants && s.zero {
+				continue
+			}
+			if r.index > index {
+				index, result = r.index, !r.negate
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package gitignore_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"moehl.dev/trie/gitignore"
+)
+
+func TestMatcher(t *testing.T) {
+	m := gitignore.New()
+	err := m.Load(strings.NewReader(`
+# comment
+*.log
+!important.log
+build/
+/root.txt
+docs/**/*.tmp
+vendor/**
+!vendor/keep
+node_modules
+a/**/b
+\#hash
+trailing\ 
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		path    string
+		ignored bool
+	}{
+		{"debug.log", true},
+		{"logs/debug.log", true},
+		{"important.log", false},
+		{"logs/important.log", false},
+		{"build/", true},
+		{"build", false},
+		{"src/build/", true},
+		{"src/build/out.o", true},
+		{"root.txt", true},
+		{"src/root.txt", false},
+		{"docs/a.tmp", true},
+		{"docs/x/y/a.tmp", true},
+		{"src/docs/a.tmp", false},
+		{"vendor/", false},
+		{"vendor/lib/x.go", true},
+		{"vendor/keep", false},
+		{"web/node_modules/x/index.js", true},
+		{"a/b", true},
+		{"a/x/y/b", true},
+		{"x/a/b", false},
+		{"#hash", true},
+		{"trailing ", true},
+		{"main.go", false},
+		{"", false},
+	} {
+		if got := m.Ignored(tc.path); got != tc.ignored {
+			t.Errorf("%q: expected %v but got %v", tc.path, tc.ignored, got)
+		}
+	}
+
+	// Files in an ignored directory cannot be re-included.
+	m = gitignore.New()
+	_ = m.Add("out/")
+	_ = m.Add("!out/keep")
+	if !m.Ignored("out/keep") {
+		t.Errorf("expected out/keep to stay ignored")
+	}
+
+	if err := m.Add("[a"); !errors.Is(err, gitignore.ErrInvalidPattern) {
+		t.Errorf("expected ErrInvalidPattern but got %v", err)
+	}
+}
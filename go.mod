@@ -1,3 +1,5 @@
 module moehl.dev/trie
 
 go 1.21.4
+
+require golang.org/x/text v0.14.0
@@ -0,0 +1,147 @@
+package trie
+
+import (
+	"strings"
+)
+
+// Node is a handle to a node of a String trie that can be used to navigate the
+// trie incrementally, e.g. to match a stream of tokens, without descending
+// from the root for every step. A Node does not hold any locks, each method
+// only locks the node it refers to while it is being accessed.
+//
+// A Node refers to the same node as long as the node is part of the trie. If
+// it is removed by Delete, Clear or Compact, the handle refers to a detached
+// node. In tries that use WithPooling a removed node may be reused, so a Node
+// looks up its path from the root instead and refers to whatever node is at
+// that path. Set writes through the wrapper the handle was obtained from, if
+// any, e.g. it is logged for handles obtained from a WAL.
+type Node[V any] struct {
+	t *stringTrie[V]
+	v view[string, V]
+	// segments is the path of the node.
+	segments []string
+	// put is the Put of the wrapper the handle was obtained from, it is nil
+	// if Set writes to t directly. base is the number of leading segments
+	// that are not part of the paths of the wrapper.
+	put  func(path string, value V)
+	base int
+}
+
+func (t *stringTrie[V]) Root() Node[V] {
+	return Node[V]{t: t, v: view[string, V]{n: t.root}}
+}
+
+// Child returns the child of the node with the given segment.
+func (n Node[V]) Child(segment string) (Node[V], bool) {
+	defer n.t.unpin(n.t.pin())
+	v, ok := n.resolve()
+	if !ok {
+		return Node[V]{}, false
+	}
+	if len(v.run) > 0 {
+		if v.run[0] != segment {
+			return Node[V]{}, false
+		}
+		v.run = v.run[1:]
+	} else {
+		v.n.lock.RLock()
+		assertNotReleased(v.n)
+		child, ok := v.n.children.get(segment)
+		if !ok {
+			v.n.lock.RUnlock()
+			return Node[V]{}, false
+		}
+		run := child.segments[1:]
+		v.n.lock.RUnlock()
+		v = view[string, V]{run: run, n: child}
+	}
+
+	return Node[V]{
+		t:        n.t,
+		v:        v,
+		segments: append(n.segments[:len(n.segments):len(n.segments)], segment),
+		put:      n.put,
+		base:     n.base,
+	}, true
+}
+
+// Value returns the value of the node and whether it has been set by Put.
+func (n Node[V]) Value() (value V, hasValue bool) {
+	if len(n.v.run) > 0 || n.t.pool != nil {
+		// The node was an implicit node when the handle was created, it
+		// might have been given a value since then.
+		return n.t.valueAt(n.segments)
+	}
+
+	n.v.n.lock.RLock()
+	defer n.v.n.lock.RUnlock()
+	assertNotReleased(n.v.n)
+	return n.v.n.value, n.v.n.hasValue
+}
+
+// Path returns the path of the node, the segments are escaped if escaping is
+// enabled.
+func (n Node[V]) Path() string {
+	return strings.Join(n.t.escapeSegments(n.segments), n.t.delimiter)
+}
+
+// Set the value of the node just like Put does for the path of the node.
+func (n Node[V]) Set(value V) {
+	if n.t.checkSegments(n.segments) != nil {
+		return
+	}
+	if n.put != nil {
+		n.put(strings.Join(n.t.escapeSegments(n.segments[n.base:]), n.t.delimiter), value)
+		return
+	}
+	n.t.insert(n.segments, value, true)
+}
+
+// rootWithPut returns the root handle of t whose Set calls put, the Put of a
+// wrapper of t, instead of writing to t directly.
+func rootWithPut[V any](t String[V], put func(path string, value V)) Node[V] {
+	n := t.Root()
+	n.put, n.base = put, len(n.segments)
+	return n
+}
+
+// joinPath joins segments to a path of t, escaping them if t has an escape
+// rune, such that Put of the path puts the node at segments.
+func joinPath[V any](t String[V], segments []string) string {
+	return t.Root().t.joinSegments(segments)
+}
+
+// resolve returns a view of the node. If pooling is enabled, the node is looked
+// up from the root and the caller must pin the tree.
+func (n Node[V]) resolve() (view[string, V], bool) {
+	if n.t.pool == nil {
+		return n.v, true
+	}
+	return n.t.viewAt(n.segments)
+}
+
+// viewAt returns a view of the node at segments.
+func (t *stringTrie[V]) viewAt(segments []string) (view[string, V], bool) {
+	c := t.cursor()
+	for _, key := range segments {
+		if !c.next(key) {
+			return view[string, V]{}, false
+		}
+	}
+	v := c.view()
+	c.close()
+	return v, true
+}
+
+// valueAt returns the value of the node at segments.
+func (t *stringTrie[V]) valueAt(segments []string) (value V, hasValue bool) {
+	c := t.cursor()
+	for _, key := range segments {
+		if !c.next(key) {
+			return value, false
+		}
+	}
+	value, hasValue = c.value()
+	c.close()
+	return value, hasValue
+}
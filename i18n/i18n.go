@@ -0,0 +1,106 @@
+// Package i18n implements a message catalog with locale fallback. Messages
+// are stored in a String trie by their id followed by the subtags of their
+// locale, so all translations of a message share a subtree and the most
+// specific translation for a locale is found by descending its subtags, e.g.
+// de-CH falls back to de and then to the default locale.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+
+	"moehl.dev/trie"
+)
+
+// Catalog contains the messages of all locales. A Catalog is safe for
+// concurrent use.
+type Catalog struct {
+	defaultLocale []string
+	messages      trie.String[string]
+}
+
+// New creates an empty Catalog. Messages that are not available in the
+// requested locale or any of its parents are taken from defaultLocale, which
+// may be empty to use messages that have been set without a locale.
+func New(defaultLocale string) *Catalog {
+	return &Catalog{
+		defaultLocale: subtags(defaultLocale),
+		messages:      trie.New[string]("/"),
+	}
+}
+
+// subtags returns the lower case subtags of locale, both - and _ are accepted
+// as separators.
+func subtags(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if locale == "" {
+		return nil
+	}
+	return strings.Split(locale, "-")
+}
+
+// Set the message with the given id for locale. The id is used as a single
+// segment, so it may contain slashes.
+func (c *Catalog) Set(locale, id, message string) {
+	c.messages.PutSegments(message, append([]string{id}, subtags(locale)...)...)
+}
+
+// Load sets all messages, which are keyed by their locale and id separated by
+// a slash, e.g. de-CH/greeting/morning.
+func (c *Catalog) Load(messages map[string]string) error {
+	for key, message := range messages {
+		locale, id, ok := strings.Cut(key, "/")
+		if !ok || id == "" {
+			return fmt.Errorf("i18n: invalid key %q: expected locale/id", key)
+		}
+		c.Set(locale, id, message)
+	}
+	return nil
+}
+
+// Lookup returns the message with the given id in the most specific locale
+// available, i.e. locale or one of its parents, or in the default locale.
+func (c *Catalog) Lookup(locale, id string) (message string, found bool) {
+	n, ok := c.messages.Root().Child(id)
+	if !ok {
+		return "", false
+	}
+
+	if message, found = nearest(n, subtags(locale)); found {
+		return message, true
+	}
+	if message, found = nearest(n, c.defaultLocale); found {
+		return message, true
+	}
+	// The message without a locale is the last resort.
+	return n.Value()
+}
+
+// nearest returns the value of the deepest node with a value along the path
+// of the subtags below n, excluding n itself.
+func nearest(n trie.Node[string], subtags []string) (message string, found bool) {
+	for _, tag := range subtags {
+		child, ok := n.Child(tag)
+		if !ok {
+			break
+		}
+		n = child
+		if value, ok := n.Value(); ok {
+			message, found = value, true
+		}
+	}
+	return message, found
+}
+
+// Sprintf formats the message with the given id in locale like fmt.Sprintf.
+// If the message does not exist, the id is used as the format.
+func (c *Catalog) Sprintf(locale, id string, args ...any) string {
+	format, found := c.Lookup(locale, id)
+	if !found {
+		format = id
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,53 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"moehl.dev/trie/i18n"
+)
+
+func TestCatalogLookup(t *testing.T) {
+	c := i18n.New("en")
+	err := c.Load(map[string]string{
+		"en/greeting":       "Hello %s",
+		"de/greeting":       "Hallo %s",
+		"de-CH/greeting":    "Grüezi %s",
+		"en/app/title":      "Trie",
+		"de/app/title":      "Präfixbaum",
+		"/footer":           "moehl.dev",
+		"fr-CA/only/quebec": "Bonjour",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		locale, id, expected string
+		found                bool
+	}{
+		{"de-CH", "greeting", "Grüezi %s", true},
+		{"de_ch", "greeting", "Grüezi %s", true},
+		{"de-AT", "greeting", "Hallo %s", true},
+		{"de-CH-1996", "app/title", "Präfixbaum", true},
+		{"fr", "greeting", "Hello %s", true},
+		{"fr", "footer", "moehl.dev", true},
+		{"fr", "only/quebec", "", false},
+		{"fr-CA", "only/quebec", "Bonjour", true},
+		{"de", "missing", "", false},
+	} {
+		got, found := c.Lookup(tc.locale, tc.id)
+		if got != tc.expected || found != tc.found {
+			t.Errorf("%s %s: expected (%q, %v) but got (%q, %v)", tc.locale, tc.id, tc.expected, tc.found, got, found)
+		}
+	}
+
+	if got := c.Sprintf("de-CH", "greeting", "Welt"); got != "Grüezi Welt" {
+		t.Errorf("expected 'Grüezi Welt' but got %q", got)
+	}
+	if got := c.Sprintf("de", "missing"); got != "missing" {
+		t.Errorf("expected the id for a missing message but got %q", got)
+	}
+	if err := c.Load(map[string]string{"de": "x"}); err == nil {
+		t.Errorf("expected an error for a key without an id")
+	}
+}
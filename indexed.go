@@ -0,0 +1,276 @@
+package trie
+
+import (
+	"math"
+	"strings"
+	"sync"
+)
+
+// Indexed is a string trie for very large data sets. Instead of allocating
+// every node on its own, all nodes are stored in a single slice and reference
+// each other by index, and all segments are stored in a single byte slice.
+// Neither slice contains pointers, so the garbage collector does not have to
+// scan the nodes at all, only the values if V contains pointers.
+//
+// The children of a node are kept in a sorted linked list, which makes
+// lookups in nodes with many children slower than in the default trie. An
+// Indexed trie is protected by a single lock, it is safe for concurrent use
+// but writes are not executed in parallel. The memory of deleted nodes and
+// values is reused by subsequent writes, the segments of deleted nodes are
+// dropped once they make up more than half of all segments.
+//
+// Nodes, values and segments are addressed by 32 bit integers, Put panics if
+// the trie would exceed 2^32-1 nodes or values or 4GiB of segments.
+type Indexed[V any] struct {
+	lock      sync.RWMutex
+	delimiter string
+
+	// nodes contains the root at index 0 followed by all other nodes.
+	nodes []indexedNode
+	// keys contains the segments of all nodes.
+	keys []byte
+	// values contains the values of all nodes that have a value set.
+	values []V
+
+	freeNodes  []uint32
+	freeValues []uint32
+	// deadKeys is the number of bytes in keys that belong to deleted nodes.
+	deadKeys int
+}
+
+// indexedNode is a node of an Indexed trie. Since the root can never be a child
+// or sibling, the index 0 denotes the absence of a node. value is the index
+// of the value plus one, zero means that the node has no value.
+type indexedNode struct {
+	keyOff, keyLen uint32
+	child, next    uint32
+	value          uint32
+}
+
+// NewIndexed creates a new empty Indexed trie.
+func NewIndexed[V any](delimiter string) *Indexed[V] {
+	return &Indexed[V]{
+		delimiter: delimiter,
+		nodes:     make([]indexedNode, 1),
+	}
+}
+
+// Delimiter that has been specified on creation of the trie.
+func (t *Indexed[V]) Delimiter() string {
+	return t.delimiter
+}
+
+func (t *Indexed[V]) key(n uint32) []byte {
+	node := &t.nodes[n]
+	return t.keys[node.keyOff : node.keyOff+node.keyLen]
+}
+
+// child returns the child of n with the given key. If there is no such child,
+// prev is the child after which it would have to be inserted or 0 if it
+// would become the first child.
+func (t *Indexed[V]) child(n uint32, key string) (child, prev uint32, ok bool) {
+	for c := t.nodes[n].child; c != 0; c = t.nodes[c].next {
+		// The conversions do not allocate when used in comparisons.
+		k := t.key(c)
+		if string(k) == key {
+			return c, prev, true
+		}
+		if string(k) > key {
+			break
+		}
+		prev = c
+	}
+	return 0, prev, false
+}
+
+// Put a new key into the trie. The path is split at the delimiter.
+func (t *Indexed[V]) Put(path string, value V) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var n uint32
+	for path != "" {
+		var key string
+		key, path, _ = strings.Cut(path, t.delimiter)
+
+		child, prev, ok := t.child(n, key)
+		if !ok {
+			child = t.newNode(key)
+			if prev == 0 {
+				t.nodes[child].next = t.nodes[n].child
+				t.nodes[n].child = child
+			} else {
+				t.nodes[child].next = t.nodes[prev].next
+				t.nodes[prev].next = child
+			}
+		}
+		n = child
+	}
+
+	if t.nodes[n].value != 0 {
+		t.values[t.nodes[n].value-1] = value
+		return
+	}
+	if l := len(t.freeValues); l > 0 {
+		t.nodes[n].value = t.freeValues[l-1] + 1
+		t.freeValues = t.freeValues[:l-1]
+		t.values[t.nodes[n].value-1] = value
+		return
+	}
+	if uint64(len(t.values)) == math.MaxUint32 {
+		panic("trie: too many values in Indexed trie")
+	}
+	t.values = append(t.values, value)
+	t.nodes[n].value = uint32(len(t.values))
+}
+
+func (t *Indexed[V]) newNode(key string) uint32 {
+	if uint64(len(t.keys))+uint64(len(key)) > math.MaxUint32 {
+		t.compactKeys()
+		if uint64(len(t.keys))+uint64(len(key)) > math.MaxUint32 {
+			panic("trie: segments of Indexed trie exceed 4GiB")
+		}
+	}
+	off := uint32(len(t.keys))
+	t.keys = append(t.keys, key...)
+	node := indexedNode{keyOff: off, keyLen: uint32(len(key))}
+
+	if l := len(t.freeNodes); l > 0 {
+		n := t.freeNodes[l-1]
+		t.freeNodes = t.freeNodes[:l-1]
+		t.nodes[n] = node
+		return n
+	}
+	if uint64(len(t.nodes)) == math.MaxUint32 {
+		panic("trie: too many nodes in Indexed trie")
+	}
+	t.nodes = append(t.nodes, node)
+	return uint32(len(t.nodes) - 1)
+}
+
+// compactKeys copies the segments of all nodes into a new slice to drop the
+// segments of deleted nodes, whose keys are empty.
+func (t *Indexed[V]) compactKeys() {
+	keys := make([]byte, 0, len(t.keys)-t.deadKeys)
+	for i := range t.nodes {
+		node := &t.nodes[i]
+		off := len(keys)
+		keys = append(keys, t.keys[node.keyOff:node.keyOff+node.keyLen]...)
+		node.keyOff = uint32(off)
+	}
+	t.keys = keys
+	t.deadKeys = 0
+}
+
+// Get the value at a path. `found` indicates whether the node exists in the
+// trie, see String.Get.
+func (t *Indexed[V]) Get(path string) (value V, found bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var n uint32
+	for path != "" {
+		var key string
+		key, path, _ = strings.Cut(path, t.delimiter)
+
+		child, _, ok := t.child(n, key)
+		if !ok {
+			return value, false
+		}
+		n = child
+	}
+
+	v := t.nodes[n].value
+	if v == 0 {
+		return value, n != 0
+	}
+	return t.values[v-1], true
+}
+
+// Delete the node at the given path including all of its children. The empty
+// path refers to the root, see String.Delete.
+func (t *Indexed[V]) Delete(path string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if path == "" {
+		t.freeValue(0)
+		return
+	}
+
+	var parent, n, prev uint32
+	for {
+		var key string
+		key, path, _ = strings.Cut(path, t.delimiter)
+
+		child, p, ok := t.child(n, key)
+		if !ok {
+			return
+		}
+		parent, n, prev = n, child, p
+		if path == "" {
+			break
+		}
+	}
+
+	if prev == 0 {
+		t.nodes[parent].child = t.nodes[n].next
+	} else {
+		t.nodes[prev].next = t.nodes[n].next
+	}
+	t.free(n)
+
+	if t.deadKeys > len(t.keys)/2 {
+		t.compactKeys()
+	}
+}
+
+// free adds n and all of its children to the free lists.
+func (t *Indexed[V]) free(n uint32) {
+	for c := t.nodes[n].child; c != 0; {
+		next := t.nodes[c].next
+		t.free(c)
+		c = next
+	}
+
+	t.freeValue(n)
+	t.deadKeys += int(t.nodes[n].keyLen)
+	t.nodes[n] = indexedNode{}
+	t.freeNodes = append(t.freeNodes, n)
+}
+
+// freeValue removes the value of n and adds it to the free list.
+func (t *Indexed[V]) freeValue(n uint32) {
+	if v := t.nodes[n].value; v != 0 {
+		var zero V
+		t.values[v-1] = zero
+		t.freeValues = append(t.freeValues, v-1)
+		t.nodes[n].value = 0
+	}
+}
+
+// Walk calls fn for every path that has a value set by Put, in sorted order.
+// Walking stops if fn returns false. The trie must not be modified by fn.
+func (t *Indexed[V]) Walk(fn func(path string, value V) bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	t.walk(0, "", fn)
+}
+
+func (t *Indexed[V]) walk(n uint32, path string, fn func(string, V) bool) bool {
+	if v := t.nodes[n].value; v != 0 && !fn(path, t.values[v-1]) {
+		return false
+	}
+
+	for c := t.nodes[n].child; c != 0; c = t.nodes[c].next {
+		childPath := string(t.key(c))
+		if n != 0 {
+			childPath = path + t.delimiter + childPath
+		}
+		if !t.walk(c, childPath, fn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+// Package inspect provides an http.Handler that renders a live String
+// trie for debugging, similar to expvar. It can be mounted on an internal
+// debug server to navigate the children of the nodes of a trie, view their
+// values and the sizes of their subtrees:
+//
+//	http.Handle("/debug/routes/", inspect.New(routes, nil))
+//
+// Nodes are addressed by their segments, which are passed as repeated query
+// parameters named s, e.g. ?s=users&s=42. The response is an HTML page unless
+// the query parameter format is set to json or the Accept header of the
+// request prefers application/json.
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"moehl.dev/trie"
+)
+
+// Handler renders the nodes of a trie, it never modifies the trie.
+type Handler[V any] struct {
+	t      trie.String[V]
+	render func(V) string
+}
+
+// New returns a Handler for t which renders values with render. If render is
+// nil, values are formatted with fmt.Sprint.
+func New[V any](t trie.String[V], render func(V) string) *Handler[V] {
+	if render == nil {
+		render = func(v V) string { return fmt.Sprint(v) }
+	}
+	return &Handler[V]{t: t, render: render}
+}
+
+// Page is the description of a node, it is the JSON representation of the
+// responses of the handler.
+type Page struct {
+	Path     string     `json:"path"`
+	Segments []string   `json:"segments"`
+	HasValue bool       `json:"hasValue"`
+	Value    string     `json:"value,omitempty"`
+	Stats    trie.Stats `json:"stats"`
+	Children []Child    `json:"children"`
+}
+
+// Child is a child of the node described by a Page.
+type Child struct {
+	Segment  string `json:"segment"`
+	HasValue bool   `json:"hasValue"`
+	// Values is the number of values in the subtree of the child,
+	// including its own.
+	Values int `json:"values"`
+}
+
+func (h *Handler[V]) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	segments := req.URL.Query()["s"]
+	page, ok := h.page(segments)
+	if !ok {
+		http.Error(w, fmt.Sprintf("node %q not found", strings.Join(segments, h.t.Delimiter())), http.StatusNotFound)
+		return
+	}
+
+	if wantsJSON(req) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(page)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = pageTemplate.Execute(w, page)
+}
+
+// page describes the node at segments, ok is false if it does not exist.
+func (h *Handler[V]) page(segments []string) (page Page, ok bool) {
+	n := h.t.Root()
+	for _, segment := range segments {
+		n, ok = n.Child(segment)
+		if !ok {
+			return page, false
+		}
+	}
+
+	page.Path = n.Path()
+	page.Segments = append([]string{}, segments...)
+	var value V
+	value, page.HasValue = n.Value()
+	if page.HasValue {
+		page.Value = h.render(value)
+	}
+	page.Stats = trie.StatsOf(trie.Namespace(h.t, page.Path))
+
+	children, _ := h.t.Children(page.Path)
+	page.Children = make([]Child, 0, len(children))
+	for _, segment := range children {
+		child, ok := n.Child(segment)
+		if !ok {
+			// The child has been removed concurrently.
+			continue
+		}
+		_, hasValue := child.Value()
+		page.Children = append(page.Children, Child{
+			Segment:  segment,
+			HasValue: hasValue,
+			Values:   trie.StatsOf(trie.Namespace(h.t, child.Path())).Values,
+		})
+	}
+	return page, true
+}
+
+func wantsJSON(req *http.Request) bool {
+	if f := req.URL.Query().Get("format"); f != "" {
+		return f == "json"
+	}
+	accept := req.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+// link returns the query string addressing the node at segments.
+func link(segments ...string) string {
+	q := url.Values{"s": segments}
+	return "?" + q.Encode()
+}
+
+var pageTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"link": func(segments []string, more ...string) template.URL {
+		return template.URL(link(append(segments[:len(segments):len(segments)], more...)...))
+	},
+	"crumbs": func(segments []string) [][]string {
+		crumbs := make([][]string, len(segments))
+		for i := range segments {
+			crumbs[i] = segments[:i+1]
+		}
+		return crumbs
+	},
+	"last": func(segments []string) string { return segments[len(segments)-1] },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>trie {{.Path}}</title></head>
+<body>
+<h1><a href="?">root</a>{{range crumbs .Segments}} / <a href="{{link .}}">{{last .}}</a>{{end}}</h1>
+<p>{{.Stats.Values}} values, {{.Stats.Nodes}} nodes, depth {{.Stats.Depth}}</p>
+{{if .HasValue}}<pre>{{.Value}}</pre>{{else}}<p><i>no value</i></p>{{end}}
+<table>
+<tr><th>child</th><th>value</th><th>values</th></tr>
+{{range .Children}}<tr><td><a href="{{link $.Segments .Segment}}">{{.Segment}}</a></td><td>{{if .HasValue}}&#10003;{{end}}</td><td>{{.Values}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,74 @@
+package inspect_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"moehl.dev/trie"
+	"moehl.dev/trie/inspect"
+)
+
+func TestHandler(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithEscape('\\'))
+	tr.Put("a/b", 1)
+	tr.Put("a/c/d", 2)
+	tr.PutSegments(3, "a", "x/y")
+	tr.Put("e", 4)
+
+	h := inspect.New(tr, func(v int) string { return strings.Repeat("*", v) })
+
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/?s=a&format=json", nil)
+	var page inspect.Page
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := inspect.Page{
+		Path:     "a",
+		Segments: []string{"a"},
+		Stats:    trie.Stats{Values: 3, Nodes: 4, Depth: 2},
+		Children: []inspect.Child{
+			{Segment: "b", HasValue: true, Values: 1},
+			{Segment: "c", Values: 1},
+			{Segment: "x/y", HasValue: true, Values: 1},
+		},
+	}
+	if !reflect.DeepEqual(page, expected) {
+		t.Errorf("expected %+v but got %+v", expected, page)
+	}
+
+	rec = get("/?s=a&s=x%2Fy", http.Header{"Accept": {"application/json"}})
+	page = inspect.Page{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &page)
+	if page.Path != `a/x\/y` || page.Value != "***" {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	rec = get("/?s=a", nil)
+	body := rec.Body.String()
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected HTML but got %s", ct)
+	}
+	for _, s := range []string{`href="?s=a&amp;s=c"`, `href="?s=a&amp;s=x%2Fy"`, "3 values"} {
+		if !strings.Contains(body, s) {
+			t.Errorf("expected the page to contain %s:\n%s", s, body)
+		}
+	}
+
+	if rec = get("/?s=a&s=z", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 but got %d", rec.Code)
+	}
+}
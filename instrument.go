@@ -0,0 +1,71 @@
+package trie
+
+import (
+	"time"
+)
+
+// Instrumentation is called by a trie after each operation, see
+// NewInstrumented. depth is the number of segments that have been followed
+// and wait is the time spent waiting for the locks of the nodes. The methods
+// are called synchronously and must therefore be cheap, e.g. increment
+// counters or record observations in a histogram.
+type Instrumentation interface {
+	// Put is called after a value has been put into the trie.
+	Put(depth int, wait time.Duration)
+	// Get is called after a lookup. found is false if the lookup missed.
+	Get(depth int, found bool, wait time.Duration)
+	// Delete is called after a deletion. found is false if the node did not
+	// exist.
+	Delete(depth int, found bool, wait time.Duration)
+}
+
+// LatencyInstrumentation can be implemented in addition to Instrumentation to
+// receive the duration of every lookup that descends the trie, including the
+// time spent waiting for locks. Lookups answered by the Bloom filter or the
+// cache are not reported.
+type LatencyInstrumentation interface {
+	GetLatency(latency time.Duration)
+}
+
+// lockNode acquires the write lock of n and adds the time it took to wait if
+// the tree is instrumented.
+func (t *tree[K, V]) lockNode(n *node[K, V], wait *time.Duration) {
+	if t.sched != nil {
+		schedLock(t.sched, n)
+		return
+	}
+	if t.inst == nil {
+		n.lock.Lock()
+		return
+	}
+
+	start := time.Now()
+	n.lock.Lock()
+	*wait += time.Since(start)
+}
+
+// rlockNode is like lockNode but acquires the read lock.
+func rlockNode[K comparable, V any](n *node[K, V], sched Scheduler, timed bool, wait *time.Duration) {
+	if sched != nil {
+		schedRLock(sched, n)
+		return
+	}
+	if !timed {
+		n.lock.RLock()
+		return
+	}
+
+	start := time.Now()
+	n.lock.RLock()
+	*wait += time.Since(start)
+}
+
+// reportGet passes the result of a lookup by c to the instrumentation, if any.
+func (t *tree[K, V]) reportGet(c *cursor[K, V], found bool) {
+	if t.inst != nil {
+		t.inst.Get(c.depth, found, c.wait)
+	}
+	if t.latency != nil {
+		t.latency.GetLatency(time.Since(c.start))
+	}
+}
@@ -0,0 +1,173 @@
+// Package ipfilter implements allow and deny lists of IP prefixes. The
+// prefixes are stored bit by bit in a Slice trie, an address is matched by
+// the longest prefix containing it, so a more specific entry overrides a less
+// specific one regardless of the order in which they have been added.
+package ipfilter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"moehl.dev/trie"
+)
+
+// Decision is the result of checking an address against a Filter.
+type Decision uint8
+
+const (
+	// NoMatch is returned if no prefix of the filter contains the address.
+	NoMatch Decision = iota
+	// Allowed is returned if the most specific prefix containing the
+	// address has been added by Allow.
+	Allowed
+	// Denied is returned if the most specific prefix containing the
+	// address has been added by Deny.
+	Denied
+)
+
+func (d Decision) String() string {
+	switch d {
+	case NoMatch:
+		return "NoMatch"
+	case Allowed:
+		return "Allowed"
+	case Denied:
+		return "Denied"
+	}
+	return fmt.Sprintf("Decision(%d)", uint8(d))
+}
+
+// ErrInvalidPrefix is returned if a prefix passed to the filter is not valid.
+var ErrInvalidPrefix = errors.New("ipfilter: invalid prefix")
+
+// Filter contains a set of allowed and denied prefixes. IPv4 and IPv4-mapped
+// IPv6 addresses are treated the same. A Filter is safe for concurrent use.
+type Filter struct {
+	// AllowByDefault makes Handler accept requests from addresses that do
+	// not match any prefix, by default they are rejected.
+	AllowByDefault bool
+
+	v4 trie.Slice[bool, Decision]
+	v6 trie.Slice[bool, Decision]
+}
+
+// New creates an empty filter.
+func New() *Filter {
+	return &Filter{
+		v4: trie.NewSlice[bool, Decision](),
+		v6: trie.NewSlice[bool, Decision](),
+	}
+}
+
+// Allow adds p to the allowed prefixes, replacing a previous Deny of p.
+func (f *Filter) Allow(p netip.Prefix) error {
+	return f.set(p, Allowed)
+}
+
+// Deny adds p to the denied prefixes, replacing a previous Allow of p.
+func (f *Filter) Deny(p netip.Prefix) error {
+	return f.set(p, Denied)
+}
+
+func (f *Filter) set(p netip.Prefix, d Decision) error {
+	t, path, err := f.path(p)
+	if err != nil {
+		return err
+	}
+	t.Put(path, d)
+	return nil
+}
+
+// Remove removes p if it has been added by Allow or Deny. More specific
+// prefixes contained in p are not affected.
+func (f *Filter) Remove(p netip.Prefix) error {
+	t, path, err := f.path(p)
+	if err != nil {
+		return err
+	}
+	if _, found := t.Get(path); !found {
+		return nil
+	}
+	// Deleting the node would remove the more specific prefixes as well, so
+	// only the decision is reset.
+	t.Put(path, NoMatch)
+	return nil
+}
+
+// Check returns the decision of the most specific prefix containing addr.
+func (f *Filter) Check(addr netip.Addr) Decision {
+	if !addr.IsValid() {
+		return NoMatch
+	}
+	addr = addr.Unmap()
+
+	t := f.v6
+	if addr.Is4() {
+		t = f.v4
+	}
+	// Prefixes without a decision are skipped by walking back from the
+	// longest match, see Remove.
+	path := bits(addr, addr.BitLen())
+	for {
+		n, d, found := t.LongestPrefix(path)
+		if !found || d != NoMatch || n == 0 {
+			return d
+		}
+		path = path[:n-1]
+	}
+}
+
+// Handler returns a handler that calls next for requests from allowed
+// addresses and responds with status 403 otherwise. The address is taken from
+// the RemoteAddr of the request, put the handler behind middleware that
+// rewrites it if requests are forwarded by a proxy.
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := f.Check(remoteAddr(r))
+		if d == Allowed || d == NoMatch && f.AllowByDefault {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
+
+func remoteAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.WithZone("")
+}
+
+// path returns the trie for the address family of p and the bits of p.
+func (f *Filter) path(p netip.Prefix) (trie.Slice[bool, Decision], []bool, error) {
+	if !p.IsValid() {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidPrefix, p)
+	}
+	addr, n := p.Addr(), p.Bits()
+	if addr.Is4In6() && n >= 96 {
+		addr, n = addr.Unmap(), n-96
+	}
+	if addr.Is4() {
+		return f.v4, bits(addr, n), nil
+	}
+	return f.v6, bits(addr.WithZone(""), n), nil
+}
+
+// bits returns the first n bits of addr, most significant first.
+func bits(addr netip.Addr, n int) []bool {
+	b := addr.AsSlice()
+	path := make([]bool, n)
+	for i := range path {
+		path[i] = b[i/8]&(0x80>>(i%8)) != 0
+	}
+	return path
+}
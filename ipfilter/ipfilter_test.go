@@ -0,0 +1,83 @@
+package ipfilter_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"moehl.dev/trie/ipfilter"
+)
+
+func TestFilter(t *testing.T) {
+	f := ipfilter.New()
+	for _, p := range []string{"10.0.0.0/8", "10.1.2.0/24", "2001:db8::/32", "::ffff:192.168.0.0/112"} {
+		if err := f.Allow(netip.MustParsePrefix(p)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for _, p := range []string{"10.1.0.0/16", "10.1.2.3/32", "2001:db8:dead::/48"} {
+		if err := f.Deny(netip.MustParsePrefix(p)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	check := func(addr string, expected ipfilter.Decision) {
+		t.Helper()
+		if d := f.Check(netip.MustParseAddr(addr)); d != expected {
+			t.Errorf("%s: expected %v but got %v", addr, expected, d)
+		}
+	}
+	check("10.0.0.1", ipfilter.Allowed)
+	check("10.1.0.1", ipfilter.Denied)
+	check("10.1.2.1", ipfilter.Allowed)
+	check("10.1.2.3", ipfilter.Denied)
+	check("11.0.0.1", ipfilter.NoMatch)
+	check("::ffff:10.0.0.1", ipfilter.Allowed)
+	check("192.168.1.1", ipfilter.Allowed)
+	check("2001:db8::1", ipfilter.Allowed)
+	check("2001:db8:dead::1", ipfilter.Denied)
+	check("2001:db9::1", ipfilter.NoMatch)
+
+	err := f.Remove(netip.MustParsePrefix("10.1.0.0/16"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	check("10.1.0.1", ipfilter.Allowed)
+	check("10.1.2.3", ipfilter.Denied)
+
+	err = f.Allow(netip.Prefix{})
+	if !errors.Is(err, ipfilter.ErrInvalidPrefix) {
+		t.Errorf("expected ErrInvalidPrefix but got %v", err)
+	}
+}
+
+func TestFilterHandler(t *testing.T) {
+	f := ipfilter.New()
+	_ = f.Allow(netip.MustParsePrefix("10.0.0.0/8"))
+	_ = f.Deny(netip.MustParsePrefix("192.168.0.0/16"))
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		remoteAddr     string
+		allowByDefault bool
+		status         int
+	}{
+		{"10.0.0.1:1234", false, http.StatusOK},
+		{"192.168.0.1:1234", true, http.StatusForbidden},
+		{"172.16.0.1:1234", false, http.StatusForbidden},
+		{"172.16.0.1:1234", true, http.StatusOK},
+		{"[fe80::1%eth0]:1234", true, http.StatusOK},
+		{"invalid", false, http.StatusForbidden},
+	} {
+		f.AllowByDefault = tc.allowByDefault
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d but got %d", tc.remoteAddr, tc.status, w.Code)
+		}
+	}
+}
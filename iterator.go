@@ -0,0 +1,143 @@
+package trie
+
+// Iterator iterates over the paths of a String trie that have a value, in the
+// order of Walk. Unlike Walk the caller pulls one path at a time, which makes
+// it possible to process two tries side by side, e.g. to merge-join them:
+//
+//	it := t.Iterator()
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Println(it.Path(), it.Value())
+//	}
+//
+// Like Walk an Iterator does not hold any locks between calls, changes made
+// while iterating may or may not be observed. An Iterator is not safe for
+// concurrent use and must be closed once it is no longer needed.
+type Iterator[V any] struct {
+	t    *stringTrie[V]
+	root view[string, V]
+	// start indicates whether the root has not been visited yet.
+	start bool
+	stack []iteratorFrame[V]
+	epoch int
+	done  bool
+
+	path  string
+	value V
+}
+
+// iteratorFrame contains the children of a node that are left to be visited.
+type iteratorFrame[V any] struct {
+	path     string
+	root     bool
+	keys     []string
+	children []view[string, V]
+}
+
+func (t *stringTrie[V]) Iterator() *Iterator[V] {
+	return t.iterator(view[string, V]{n: t.root}, t.pin())
+}
+
+// iterator returns an iterator over v, epoch is released by Close.
+func (t *stringTrie[V]) iterator(v view[string, V], epoch int) *Iterator[V] {
+	return &Iterator[V]{t: t, root: v, start: v.n != nil, epoch: epoch}
+}
+
+// Next advances the iterator to the next path that has a value and reports
+// whether there is one.
+func (it *Iterator[V]) Next() bool {
+	if it.done {
+		return false
+	}
+	if it.start {
+		it.start = false
+		value, hasValue, keys, children := it.t.sortedSnapshot(it.root)
+		it.stack = append(it.stack, iteratorFrame[V]{root: true, keys: keys, children: children})
+		if hasValue {
+			it.path, it.value = "", value
+			return true
+		}
+	}
+
+	for len(it.stack) > 0 {
+		f := &it.stack[len(it.stack)-1]
+		if len(f.children) == 0 {
+			it.stack = it.stack[:len(it.stack)-1]
+			continue
+		}
+		key, child := f.keys[0], f.children[0]
+		f.keys, f.children = f.keys[1:], f.children[1:]
+
+		path := it.t.childPath(f.path, key, f.root)
+		value, hasValue, keys, children := it.t.sortedSnapshot(child)
+		it.stack = append(it.stack, iteratorFrame[V]{path: path, keys: keys, children: children})
+		if hasValue {
+			it.path, it.value = path, value
+			return true
+		}
+	}
+
+	var zero V
+	it.path, it.value = "", zero
+	return false
+}
+
+// Seek repositions the iterator such that the following call to Next advances
+// it to the first path that does not sort before path, see WalkFrom.
+func (it *Iterator[V]) Seek(path string) {
+	if it.done || it.root.n == nil {
+		return
+	}
+
+	it.stack = it.stack[:0]
+	from := it.t.segments(it.t.normalize(path))
+	if len(from) == 0 {
+		it.start = true
+		return
+	}
+	it.start = false
+
+	_, _, keys, children := it.t.sortedSnapshot(it.root)
+	f := iteratorFrame[V]{root: true, keys: keys, children: children}
+	for {
+		// The value of the node itself sorts before all of its children.
+		for len(f.children) > 0 && it.t.compareKeys(f.keys[0], from[0]) < 0 {
+			f.keys, f.children = f.keys[1:], f.children[1:]
+		}
+		if len(from) == 1 || len(f.children) == 0 || it.t.compareKeys(f.keys[0], from[0]) != 0 {
+			// The first remaining child is visited by Next including
+			// its own value.
+			it.stack = append(it.stack, f)
+			return
+		}
+
+		// The child is a prefix of path, only some of its children are
+		// visited but not the child itself.
+		path := it.t.childPath(f.path, f.keys[0], f.root)
+		_, _, keys, children := it.t.sortedSnapshot(f.children[0])
+		f.keys, f.children = f.keys[1:], f.children[1:]
+		it.stack = append(it.stack, f)
+		f = iteratorFrame[V]{path: path, keys: keys, children: children}
+		from = from[1:]
+	}
+}
+
+// Path returns the path the iterator is positioned at.
+func (it *Iterator[V]) Path() string {
+	return it.path
+}
+
+// Value returns the value at the path the iterator is positioned at.
+func (it *Iterator[V]) Value() V {
+	return it.value
+}
+
+// Close releases the iterator, Next returns false afterwards.
+func (it *Iterator[V]) Close() {
+	if it.done {
+		return
+	}
+	it.done = true
+	it.stack = nil
+	it.t.unpin(it.epoch)
+}
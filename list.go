@@ -0,0 +1,85 @@
+package trie
+
+import (
+	"sort"
+	"strings"
+)
+
+func (t *stringTrie[V]) List(prefix, delimiter string, maxKeys int, startAfter string) (keys, commonPrefixes []string, truncated bool) {
+	defer t.unpin(t.pin())
+	span := t.startSpan("trie.List")
+	span.SetAttribute("trie.prefix", prefix)
+	defer span.End()
+
+	l := &lister{prefix: prefix, delimiter: delimiter, startAfter: startAfter, common: make(map[string]bool)}
+	listNode(t, l, view[string, V]{n: t.root}, "", true)
+
+	// The order of the trie differs from the byte order of the full paths
+	// if a segment is a prefix of one of its siblings, so the entries are
+	// sorted before the limit is applied.
+	entries := l.keys
+	for p := range l.common {
+		entries = append(entries, p)
+	}
+	sort.Strings(entries)
+	if maxKeys > 0 && len(entries) > maxKeys {
+		entries, truncated = entries[:maxKeys], true
+	}
+
+	for _, e := range entries {
+		if l.common[e] {
+			commonPrefixes = append(commonPrefixes, e)
+		} else {
+			keys = append(keys, e)
+		}
+	}
+	span.SetAttribute("trie.keys", len(keys))
+	span.SetAttribute("trie.commonPrefixes", len(commonPrefixes))
+	return keys, commonPrefixes, truncated
+}
+
+// lister collects the results of List.
+type lister struct {
+	prefix, delimiter, startAfter string
+
+	keys   []string
+	common map[string]bool
+}
+
+// listNode adds v and its children to the results of l. Only the children
+// that can contain paths starting with the prefix and following startAfter are
+// visited, and none below a common prefix.
+func listNode[V any](t *stringTrie[V], l *lister, v view[string, V], path string, root bool) {
+	_, hasValue, keys, children := v.snapshot()
+	if hasValue && !root && strings.HasPrefix(path, l.prefix) && path > l.startAfter {
+		l.keys = append(l.keys, path)
+	}
+
+	for i, child := range children {
+		childPath := keys[i]
+		if t.escape != 0 {
+			childPath = EscapeSegment(childPath, t.delimiter, t.escape)
+		}
+		if !root {
+			childPath = path + t.delimiter + childPath
+		}
+
+		if !strings.HasPrefix(childPath, l.prefix) && !strings.HasPrefix(l.prefix, childPath+t.delimiter) {
+			continue
+		}
+		if childPath < l.startAfter && !strings.HasPrefix(l.startAfter, childPath) {
+			// All paths below the child are before startAfter as well.
+			continue
+		}
+		if l.delimiter != "" && strings.HasPrefix(childPath, l.prefix) {
+			if j := strings.Index(childPath[len(l.prefix):], l.delimiter); j >= 0 {
+				p := childPath[:len(l.prefix)+j+len(l.delimiter)]
+				if p > l.startAfter {
+					l.common[p] = true
+				}
+				continue
+			}
+		}
+		listNode(t, l, child, childPath, false)
+	}
+}
@@ -0,0 +1,47 @@
+package trie
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LoadLines builds a trie from newline-delimited keys read from r, e.g. a
+// dictionary or a list of URLs. Empty lines are skipped and a trailing '\r' is
+// removed from each line.
+func LoadLines(r io.Reader, delimiter string) (String[struct{}], error) {
+	return LoadLinesFunc(r, delimiter, func(line string) (string, struct{}, error) {
+		return line, struct{}{}, nil
+	})
+}
+
+// LoadLinesFunc builds a trie from newline-delimited lines read from r. Each
+// line is passed to parse which returns the key and the value to store. An
+// error returned by parse aborts loading and is returned together with the
+// line number.
+func LoadLinesFunc[V any](r io.Reader, delimiter string, parse func(line string) (key string, value V, err error)) (String[V], error) {
+	t := New[V](delimiter)
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		key, value, err := parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("trie: line %d: %w", n, err)
+		}
+
+		t.Put(key, value)
+	}
+
+	err := s.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
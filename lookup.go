@@ -0,0 +1,32 @@
+package trie
+
+import (
+	"strconv"
+)
+
+// LookupState is the result of Lookup.
+type LookupState int
+
+const (
+	// Missing means that the node does not exist.
+	Missing LookupState = iota
+	// ImplicitNode means that the node exists but has no value, it has only
+	// been created as part of a longer path. The value returned with it is
+	// the zero value.
+	ImplicitNode
+	// Exists means that the node has a value set by Put, which may be the
+	// zero value.
+	Exists
+)
+
+func (s LookupState) String() string {
+	switch s {
+	case Missing:
+		return "Missing"
+	case ImplicitNode:
+		return "ImplicitNode"
+	case Exists:
+		return "Exists"
+	}
+	return "LookupState(" + strconv.Itoa(int(s)) + ")"
+}
@@ -0,0 +1,389 @@
+package trie
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The mapped format is designed to be queried without deserializing it. Nodes
+// are written in post-order so that each node can reference its children by
+// their absolute offset:
+//
+//	header:  magic "TRIM" | uvarint version | string delimiter
+//	node:    byte flags | [string value] | uvarint len(children) | child...
+//	child:   string segment | uvarint offset
+//	trailer: uint64 offset of the root node (little endian)
+//	string:  uvarint length | bytes
+//
+// Children are sorted by their segment.
+const (
+	mappedMagic   = "TRIM"
+	mappedVersion = 1
+)
+
+// WriteMapped writes t in the mapped format to w which can then be opened with
+// OpenMapped.
+func WriteMapped[V any](w io.Writer, t String[V]) (int64, error) {
+	st, ok := t.(*stringTrie[V])
+	if !ok {
+		return 0, fmt.Errorf("trie: cannot encode %T", t)
+	}
+	defer st.unpin(st.pin())
+
+	bw := &binaryWriter{w: bufio.NewWriter(w)}
+
+	_, err := bw.Write([]byte(mappedMagic))
+	if err == nil {
+		err = bw.writeUvarint(mappedVersion)
+	}
+	if err == nil {
+		err = bw.writeBytes([]byte(st.delimiter))
+	}
+	var root int64
+	if err == nil {
+		root, err = writeMappedNode(bw, st.Codec(), view[string, V]{n: st.root})
+	}
+	if err == nil {
+		var trailer [8]byte
+		binary.LittleEndian.PutUint64(trailer[:], uint64(root))
+		_, err = bw.Write(trailer[:])
+	}
+	if err == nil {
+		err = bw.w.Flush()
+	}
+
+	return bw.n, err
+}
+
+// writeMappedNode writes all children of v followed by v itself and returns
+// the offset of v.
+func writeMappedNode[V any](w *binaryWriter, codec ValueCodec[V], v view[string, V]) (int64, error) {
+	value, hasValue, keys, children := sortedSnapshot(v)
+
+	offsets := make([]int64, len(children))
+	for i, child := range children {
+		off, err := writeMappedNode(w, codec, child)
+		if err != nil {
+			return 0, err
+		}
+		offsets[i] = off
+	}
+
+	self := w.n
+
+	var flags byte
+	if hasValue {
+		flags |= flagValue
+	}
+	_, err := w.Write([]byte{flags})
+	if err != nil {
+		return 0, err
+	}
+
+	if hasValue {
+		b, err := codec.Encode(value)
+		if err != nil {
+			return 0, fmt.Errorf("trie: encode value: %w", err)
+		}
+		err = w.writeBytes(b)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = w.writeUvarint(uint64(len(keys)))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, k := range keys {
+		err = w.writeBytes([]byte(k))
+		if err != nil {
+			return 0, err
+		}
+		err = w.writeUvarint(uint64(offsets[i]))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return self, nil
+}
+
+// Mapped is a read-only trie that serves lookups directly from a file in the
+// mapped format without deserializing it into heap objects. Values are only
+// decoded when they are returned. On unix systems the file is memory-mapped,
+// so opening it is cheap and the pages are shared between processes.
+//
+// A Mapped trie is safe for concurrent use until it is closed.
+type Mapped[V any] struct {
+	codec     ValueCodec[V]
+	data      []byte
+	delimiter string
+	root      uint64
+	close     func() error
+}
+
+// OpenMapped opens a file written by WriteMapped.
+func OpenMapped[V any](path string) (*Mapped[V], error) {
+	return OpenMappedCodec[V](path, nil)
+}
+
+// OpenMappedCodec is like OpenMapped but uses codec to decode the values.
+func OpenMappedCodec[V any](path string, codec ValueCodec[V]) (*Mapped[V], error) {
+	data, closeFn, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newMapped[V](data, codec)
+	if err != nil {
+		_ = closeFn()
+		return nil, err
+	}
+	m.close = closeFn
+
+	return m, nil
+}
+
+func newMapped[V any](data []byte, codec ValueCodec[V]) (*Mapped[V], error) {
+	m := &Mapped[V]{data: data, codec: codecOrDefault(codec)}
+
+	if len(data) < len(mappedMagic)+8 || string(data[:len(mappedMagic)]) != mappedMagic {
+		return nil, ErrInvalidFormat
+	}
+	off := uint64(len(mappedMagic))
+
+	version, off, err := m.uvarint(off)
+	if err != nil {
+		return nil, err
+	}
+	if version != mappedVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, version)
+	}
+
+	delimiter, _, err := m.bytes(off)
+	if err != nil {
+		return nil, err
+	}
+	m.delimiter = string(delimiter)
+
+	m.data = data[:len(data)-8]
+	m.root = binary.LittleEndian.Uint64(data[len(data)-8:])
+	if m.root >= uint64(len(m.data)) {
+		return nil, fmt.Errorf("%w: root offset out of range", ErrInvalidFormat)
+	}
+
+	return m, nil
+}
+
+// Close releases the underlying file. The trie must not be used afterwards.
+func (m *Mapped[V]) Close() error {
+	if m.close == nil {
+		return nil
+	}
+	err := m.close()
+	m.close = nil
+	m.data = nil
+	return err
+}
+
+// Delimiter that has been used by the trie the file was written from.
+func (m *Mapped[V]) Delimiter() string {
+	return m.delimiter
+}
+
+// Get the value at a path. `found` has the same meaning as in String.Get, an
+// error is only returned if the file is corrupted or the value cannot be
+// decoded.
+func (m *Mapped[V]) Get(path string) (value V, found bool, err error) {
+	node, found, err := m.find(path)
+	if err != nil || !found {
+		return value, found, err
+	}
+
+	b, hasValue, _, err := m.node(node)
+	if err != nil || !hasValue {
+		return value, path != "", err
+	}
+
+	value, err = m.codec.Decode(b)
+	if err != nil {
+		return value, true, fmt.Errorf("trie: decode value: %w", err)
+	}
+	return value, true, nil
+}
+
+// WalkPrefix calls fn for each node below and including prefix that has a
+// value set, in sorted order. Walking stops if fn returns false.
+func (m *Mapped[V]) WalkPrefix(prefix string, fn func(path string, value V) bool) error {
+	node, found, err := m.find(prefix)
+	if err != nil || !found {
+		return err
+	}
+	_, err = m.walk(node, prefix, fn)
+	return err
+}
+
+// PutE always fails with an error wrapping ErrReadOnly, the file cannot be
+// modified through a Mapped trie.
+func (m *Mapped[V]) PutE(path string, _ V) error {
+	return fmt.Errorf("%w: %q", ErrReadOnly, path)
+}
+
+// DeleteE always fails with an error wrapping ErrReadOnly, see PutE.
+func (m *Mapped[V]) DeleteE(path string) error {
+	return fmt.Errorf("%w: %q", ErrReadOnly, path)
+}
+
+func (m *Mapped[V]) walk(node uint64, path string, fn func(string, V) bool) (bool, error) {
+	b, hasValue, off, err := m.node(node)
+	if err != nil {
+		return false, err
+	}
+
+	if hasValue {
+		value, err := m.codec.Decode(b)
+		if err != nil {
+			return false, fmt.Errorf("trie: decode value: %w", err)
+		}
+		if !fn(path, value) {
+			return false, nil
+		}
+	}
+
+	count, off, err := m.uvarint(off)
+	if err != nil {
+		return false, err
+	}
+
+	for i := uint64(0); i < count; i++ {
+		var key []byte
+		var child uint64
+		key, off, err = m.bytes(off)
+		if err == nil {
+			child, off, err = m.uvarint(off)
+		}
+		if err != nil {
+			return false, err
+		}
+		if child >= node {
+			return false, fmt.Errorf("%w: child offset out of range", ErrInvalidFormat)
+		}
+
+		childPath := string(key)
+		if node != m.root {
+			childPath = path + m.delimiter + childPath
+		}
+
+		cont, err := m.walk(child, childPath, fn)
+		if err != nil || !cont {
+			return cont, err
+		}
+	}
+
+	return true, nil
+}
+
+// find returns the offset of the node at path.
+func (m *Mapped[V]) find(path string) (uint64, bool, error) {
+	node := m.root
+	for path != "" {
+		var key string
+		key, path, _ = strings.Cut(path, m.delimiter)
+
+		child, found, err := m.child(node, key)
+		if err != nil || !found {
+			return 0, false, err
+		}
+		node = child
+	}
+	return node, true, nil
+}
+
+// child returns the offset of the child of node with the given key.
+func (m *Mapped[V]) child(node uint64, key string) (uint64, bool, error) {
+	_, _, off, err := m.node(node)
+	if err != nil {
+		return 0, false, err
+	}
+
+	count, off, err := m.uvarint(off)
+	if err != nil {
+		return 0, false, err
+	}
+
+	for i := uint64(0); i < count; i++ {
+		var k []byte
+		var child uint64
+		k, off, err = m.bytes(off)
+		if err == nil {
+			child, off, err = m.uvarint(off)
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		if child >= node {
+			return 0, false, fmt.Errorf("%w: child offset out of range", ErrInvalidFormat)
+		}
+
+		switch strings.Compare(string(k), key) {
+		case 0:
+			return child, true, nil
+		case 1:
+			// Children are sorted, there is no need to look any further.
+			return 0, false, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// node parses the header of the node at off and returns the encoded value and
+// the offset of the children.
+func (m *Mapped[V]) node(off uint64) (value []byte, hasValue bool, children uint64, err error) {
+	if off >= uint64(len(m.data)) {
+		return nil, false, 0, fmt.Errorf("%w: node offset out of range", ErrInvalidFormat)
+	}
+
+	flags := m.data[off]
+	if flags&^flagValue != 0 {
+		return nil, false, 0, fmt.Errorf("%w: unknown flags %x", ErrInvalidFormat, flags)
+	}
+	off++
+
+	if flags&flagValue != 0 {
+		value, off, err = m.bytes(off)
+		if err != nil {
+			return nil, false, 0, err
+		}
+		hasValue = true
+	}
+
+	return value, hasValue, off, nil
+}
+
+func (m *Mapped[V]) uvarint(off uint64) (uint64, uint64, error) {
+	if off >= uint64(len(m.data)) {
+		return 0, 0, fmt.Errorf("%w: offset out of range", ErrInvalidFormat)
+	}
+	x, n := binary.Uvarint(m.data[off:])
+	if n <= 0 {
+		return 0, 0, fmt.Errorf("%w: invalid varint", ErrInvalidFormat)
+	}
+	return x, off + uint64(n), nil
+}
+
+func (m *Mapped[V]) bytes(off uint64) ([]byte, uint64, error) {
+	l, off, err := m.uvarint(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	if l > uint64(len(m.data))-off {
+		return nil, 0, fmt.Errorf("%w: length %d exceeds data", ErrInvalidFormat, l)
+	}
+	return m.data[off : off+l], off + l, nil
+}
@@ -0,0 +1,186 @@
+// Package metrics exposes the operations and the size of a trie as an
+// expvar.Var and as a prometheus.Collector. It lives in its own module to
+// avoid forcing the Prometheus dependency on users of the trie package.
+package metrics
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"moehl.dev/trie"
+)
+
+// latencyBuckets are the upper bounds of the buckets of the lookup latency
+// histogram, from 1µs to about 1s.
+var latencyBuckets = prometheus.ExponentialBuckets(1e-6, 4, 11)
+
+// Metrics counts the operations of a trie it has been passed to with
+// trie.WithInstrumentation and records the latencies of its lookups. The size
+// of the trie is collected on demand by the function passed to Track.
+type Metrics struct {
+	puts, hits, misses, deletes atomic.Uint64
+	// latencies contains the number of lookups per bucket of
+	// latencyBuckets, the last element counts the lookups exceeding the
+	// last bucket.
+	latencies  [12]atomic.Uint64
+	latencySum atomic.Int64
+
+	lock  sync.Mutex
+	stats func() trie.Stats
+
+	descs descs
+}
+
+type descs struct {
+	puts, gets, deletes, values, nodes, latency *prometheus.Desc
+}
+
+var (
+	_ trie.Instrumentation        = (*Metrics)(nil)
+	_ trie.LatencyInstrumentation = (*Metrics)(nil)
+	_ prometheus.Collector        = (*Metrics)(nil)
+)
+
+// New returns metrics that are labeled with the given name of the trie.
+func New(name string) *Metrics {
+	labels := prometheus.Labels{"trie": name}
+	return &Metrics{
+		descs: descs{
+			puts:    prometheus.NewDesc("trie_puts_total", "Number of values put into the trie.", nil, labels),
+			gets:    prometheus.NewDesc("trie_gets_total", "Number of lookups by result.", []string{"result"}, labels),
+			deletes: prometheus.NewDesc("trie_deletes_total", "Number of deletions.", nil, labels),
+			values:  prometheus.NewDesc("trie_values", "Number of values in the trie.", nil, labels),
+			nodes:   prometheus.NewDesc("trie_nodes", "Number of nodes allocated by the trie.", nil, labels),
+			latency: prometheus.NewDesc("trie_lookup_duration_seconds", "Latency of lookups that descend the trie.", nil, labels),
+		},
+	}
+}
+
+// Track makes the metrics include the size of the trie as returned by stats,
+// e.g. a closure calling trie.StatsOf. Since it walks the whole trie, stats is
+// only called when the metrics are read.
+func (m *Metrics) Track(stats func() trie.Stats) {
+	m.lock.Lock()
+	m.stats = stats
+	m.lock.Unlock()
+}
+
+func (m *Metrics) Put(int, time.Duration) {
+	m.puts.Add(1)
+}
+
+func (m *Metrics) Get(_ int, found bool, _ time.Duration) {
+	if found {
+		m.hits.Add(1)
+	} else {
+		m.misses.Add(1)
+	}
+}
+
+func (m *Metrics) Delete(int, bool, time.Duration) {
+	m.deletes.Add(1)
+}
+
+func (m *Metrics) GetLatency(latency time.Duration) {
+	seconds := latency.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && seconds > latencyBuckets[i] {
+		i++
+	}
+	m.latencies[i].Add(1)
+	m.latencySum.Add(int64(latency))
+}
+
+// currentStats returns the size of the trie and whether Track has been called.
+func (m *Metrics) currentStats() (trie.Stats, bool) {
+	m.lock.Lock()
+	stats := m.stats
+	m.lock.Unlock()
+	if stats == nil {
+		return trie.Stats{}, false
+	}
+	return stats(), true
+}
+
+// histogram returns the cumulative counts of the latency buckets, the total
+// count and the sum of all latencies in seconds.
+func (m *Metrics) histogram() (buckets map[float64]uint64, count uint64, sum float64) {
+	buckets = make(map[float64]uint64, len(latencyBuckets))
+	for i, bound := range latencyBuckets {
+		count += m.latencies[i].Load()
+		buckets[bound] = count
+	}
+	count += m.latencies[len(latencyBuckets)].Load()
+	return buckets, count, time.Duration(m.latencySum.Load()).Seconds()
+}
+
+// String returns the metrics as a JSON object, so they can be published with
+// expvar.Publish.
+func (m *Metrics) String() string {
+	v := struct {
+		Puts    uint64 `json:"puts"`
+		Hits    uint64 `json:"hits"`
+		Misses  uint64 `json:"misses"`
+		Deletes uint64 `json:"deletes"`
+		Values  *int   `json:"values,omitempty"`
+		Nodes   *int   `json:"nodes,omitempty"`
+		Latency struct {
+			Buckets map[string]uint64 `json:"buckets"`
+			Count   uint64            `json:"count"`
+			Sum     float64           `json:"sum"`
+		} `json:"lookup_latency"`
+	}{
+		Puts:    m.puts.Load(),
+		Hits:    m.hits.Load(),
+		Misses:  m.misses.Load(),
+		Deletes: m.deletes.Load(),
+	}
+	if stats, ok := m.currentStats(); ok {
+		v.Values, v.Nodes = &stats.Values, &stats.Nodes
+	}
+
+	buckets, count, sum := m.histogram()
+	v.Latency.Buckets = make(map[string]uint64, len(buckets)+1)
+	for bound, n := range buckets {
+		v.Latency.Buckets[strconv.FormatFloat(bound, 'g', -1, 64)] = n
+	}
+	v.Latency.Buckets["+Inf"] = count
+	v.Latency.Count, v.Latency.Sum = count, sum
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		// The value only contains numbers and strings.
+		panic(err)
+	}
+	return string(b)
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.descs.puts
+	ch <- m.descs.gets
+	ch <- m.descs.deletes
+	ch <- m.descs.values
+	ch <- m.descs.nodes
+	ch <- m.descs.latency
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(m.descs.puts, prometheus.CounterValue, float64(m.puts.Load()))
+	ch <- prometheus.MustNewConstMetric(m.descs.gets, prometheus.CounterValue, float64(m.hits.Load()), "hit")
+	ch <- prometheus.MustNewConstMetric(m.descs.gets, prometheus.CounterValue, float64(m.misses.Load()), "miss")
+	ch <- prometheus.MustNewConstMetric(m.descs.deletes, prometheus.CounterValue, float64(m.deletes.Load()))
+	if stats, ok := m.currentStats(); ok {
+		ch <- prometheus.MustNewConstMetric(m.descs.values, prometheus.GaugeValue, float64(stats.Values))
+		ch <- prometheus.MustNewConstMetric(m.descs.nodes, prometheus.GaugeValue, float64(stats.Nodes))
+	}
+
+	buckets, count, sum := m.histogram()
+	ch <- prometheus.MustNewConstHistogram(m.descs.latency, count, sum, buckets)
+}
@@ -0,0 +1,53 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"moehl.dev/trie"
+	"moehl.dev/trie/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New("test")
+	tr := trie.New[int]("/", trie.WithInstrumentation(m))
+	m.Track(func() trie.Stats { return trie.StatsOf(tr) })
+
+	tr.Put("a/b/c", 1)
+	tr.Put("a/d", 2)
+	tr.Get("a/b/c")
+	tr.Get("x")
+	tr.Delete("a/d")
+
+	var v struct {
+		Puts, Hits, Misses, Deletes, Values, Nodes int
+		Latency                                    struct{ Count int } `json:"lookup_latency"`
+	}
+	err := json.Unmarshal([]byte(m.String()), &v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.Puts != 2 || v.Hits != 1 || v.Misses != 1 || v.Deletes != 1 || v.Values != 1 || v.Latency.Count != 2 {
+		t.Errorf("unexpected metrics: %s", m.String())
+	}
+
+	expected := `
+# HELP trie_gets_total Number of lookups by result.
+# TYPE trie_gets_total counter
+trie_gets_total{result="hit",trie="test"} 1
+trie_gets_total{result="miss",trie="test"} 1
+# HELP trie_values Number of values in the trie.
+# TYPE trie_values gauge
+trie_values{trie="test"} 1
+`
+	err = testutil.CollectAndCompare(m, strings.NewReader(expected), "trie_gets_total", "trie_values")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := testutil.CollectAndCount(m, "trie_lookup_duration_seconds"); n != 1 {
+		t.Errorf("expected 1 histogram but got %d", n)
+	}
+}
@@ -0,0 +1,180 @@
+// Package mimematch matches media types against patterns like text/*,
+// application/*+json or application/vnd.api+json. The patterns are stored in
+// a String trie keyed by type and subtype, so a media type is matched by
+// looking up its type once and then the few subtypes that can match it.
+package mimematch
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"moehl.dev/trie"
+)
+
+// Wildcard matches any type or subtype, *+suffix matches any subtype with
+// the structured syntax suffix, e.g. *+json matches vnd.api+json.
+const Wildcard = "*"
+
+// Specificity of a pattern, patterns with a higher specificity take
+// precedence over patterns with a lower one.
+type Specificity uint8
+
+const (
+	// AnyType is the specificity of */*.
+	AnyType Specificity = iota
+	// AnySubtype is the specificity of patterns like text/*.
+	AnySubtype
+	// Suffix is the specificity of patterns like application/*+json.
+	Suffix
+	// Exact is the specificity of patterns without wildcards.
+	Exact
+)
+
+// ErrInvalidPattern is returned by Add if a pattern cannot be parsed.
+var ErrInvalidPattern = errors.New("mimematch: invalid pattern")
+
+// Match is a pattern that matched a media type.
+type Match[V any] struct {
+	// Pattern is the media type of the pattern without parameters.
+	Pattern string
+	// Quality is the value of the q parameter of the pattern, 1 if it
+	// has none.
+	Quality float64
+	// Specificity of the pattern.
+	Specificity Specificity
+	// Value that has been added with the pattern.
+	Value V
+}
+
+// Matcher stores patterns with a value each. It is safe for concurrent use.
+type Matcher[V any] struct {
+	lock     sync.Mutex
+	patterns trie.String[Match[V]]
+}
+
+// New creates a Matcher without any patterns.
+func New[V any]() *Matcher[V] {
+	return &Matcher[V]{
+		patterns: trie.New[Match[V]]("/"),
+	}
+}
+
+// Add a pattern with the given value, replacing the value of an equal
+// pattern. The pattern is a media type in which the type or subtype may be a
+// wildcard and which may have a q parameter between 0 and 1 like in an Accept
+// header. All other parameters are ignored. A pattern with quality 0 makes
+// the media types it matches unacceptable, unless they are matched by a more
+// specific pattern.
+func (m *Matcher[V]) Add(pattern string, value V) error {
+	mediaType, params, err := mime.ParseMediaType(pattern)
+	if err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidPattern, pattern, err)
+	}
+	typ, subtype, found := strings.Cut(mediaType, "/")
+	if !found {
+		return fmt.Errorf("%w: %q: missing subtype", ErrInvalidPattern, pattern)
+	}
+
+	match := Match[V]{Pattern: mediaType, Quality: 1, Value: value}
+	switch {
+	case typ == Wildcard && subtype == Wildcard:
+		match.Specificity = AnyType
+	case typ == Wildcard:
+		return fmt.Errorf("%w: %q: wildcard type with subtype", ErrInvalidPattern, pattern)
+	case subtype == Wildcard:
+		match.Specificity = AnySubtype
+	case strings.HasPrefix(subtype, Wildcard+"+"):
+		match.Specificity = Suffix
+	case strings.Contains(subtype, Wildcard):
+		return fmt.Errorf("%w: %q: wildcard within subtype", ErrInvalidPattern, pattern)
+	default:
+		match.Specificity = Exact
+	}
+
+	if q, ok := params["q"]; ok {
+		match.Quality, err = strconv.ParseFloat(q, 64)
+		if err != nil || match.Quality < 0 || match.Quality > 1 {
+			return fmt.Errorf("%w: %q: quality %q", ErrInvalidPattern, pattern, q)
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.patterns.Put(mediaType, match)
+	return nil
+}
+
+// Remove the pattern, parameters of the pattern are ignored. Patterns that
+// would be rejected by Add for lacking a subtype are ignored as well, they
+// would otherwise remove all patterns of the type.
+func (m *Matcher[V]) Remove(pattern string) {
+	mediaType, _, err := mime.ParseMediaType(pattern)
+	if err != nil {
+		return
+	}
+	if _, _, found := strings.Cut(mediaType, "/"); !found {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.patterns.Delete(mediaType)
+}
+
+// BestMatch returns the most specific pattern matching contentType, which may
+// contain parameters. ok is false if there is no such pattern or if its
+// quality is 0.
+func (m *Matcher[V]) BestMatch(contentType string) (match Match[V], ok bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return match, false
+	}
+	typ, subtype, found := strings.Cut(mediaType, "/")
+	if !found || typ == Wildcard || subtype == Wildcard {
+		return match, false
+	}
+
+	subtypes := []string{subtype}
+	if i := strings.LastIndex(subtype, "+"); i >= 0 {
+		subtypes = append(subtypes, Wildcard+subtype[i:])
+	}
+	subtypes = append(subtypes, Wildcard)
+
+	root := m.patterns.Root()
+	for _, t := range []string{typ, Wildcard} {
+		n, found := root.Child(t)
+		if !found {
+			continue
+		}
+		for _, s := range subtypes {
+			c, found := n.Child(s)
+			if !found {
+				continue
+			}
+			if match, ok = c.Value(); ok {
+				return match, match.Quality > 0
+			}
+		}
+	}
+	return match, false
+}
+
+// Negotiate returns the offered content type with the highest quality and
+// its match. Offers with the same quality are ordered by the specificity of
+// their match and then by their position in offers. ok is false if none of
+// the offers is acceptable.
+func (m *Matcher[V]) Negotiate(offers ...string) (offer string, match Match[V], ok bool) {
+	for _, o := range offers {
+		mt, found := m.BestMatch(o)
+		if !found {
+			continue
+		}
+		if !ok || mt.Quality > match.Quality || mt.Quality == match.Quality && mt.Specificity > match.Specificity {
+			offer, match, ok = o, mt, true
+		}
+	}
+	return offer, match, ok
+}
@@ -0,0 +1,72 @@
+package mimematch_test
+
+import (
+	"errors"
+	"testing"
+
+	"moehl.dev/trie/mimematch"
+)
+
+func TestMatcher(t *testing.T) {
+	m := mimematch.New[string]()
+	for _, p := range []string{
+		"*/*;q=0.1",
+		"text/*",
+		"text/plain; charset=utf-8",
+		"application/*+json;q=0.8",
+		"application/vnd.api+json",
+		"image/*;q=0",
+		"image/png",
+	} {
+		if err := m.Add(p, p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		contentType string
+		pattern     string
+		ok          bool
+	}{
+		{"text/plain", "text/plain; charset=utf-8", true},
+		{"Text/HTML; charset=utf-8", "text/*", true},
+		{"application/vnd.api+json", "application/vnd.api+json", true},
+		{"application/problem+json", "application/*+json;q=0.8", true},
+		{"application/json", "*/*;q=0.1", true},
+		{"image/png", "image/png", true},
+		{"image/gif", "image/*;q=0", false},
+		{"invalid", "", false},
+	} {
+		match, ok := m.BestMatch(tc.contentType)
+		if ok != tc.ok || match.Value != tc.pattern {
+			t.Errorf("%s: expected (%q, %v) but got (%q, %v)", tc.contentType, tc.pattern, tc.ok, match.Value, ok)
+		}
+	}
+
+	offer, match, ok := m.Negotiate("application/json", "application/problem+json", "text/html", "text/plain")
+	if !ok || offer != "text/plain" || match.Specificity != mimematch.Exact {
+		t.Errorf("expected text/plain with an exact match but got %q, %+v", offer, match)
+	}
+	offer, _, _ = m.Negotiate("application/json", "application/problem+json")
+	if offer != "application/problem+json" {
+		t.Errorf("expected application/problem+json but got %q", offer)
+	}
+	if _, _, ok = m.Negotiate("image/gif"); ok {
+		t.Errorf("expected image/gif to be unacceptable")
+	}
+
+	m.Remove("text/plain")
+	if match, _ := m.BestMatch("text/plain"); match.Pattern != "text/*" {
+		t.Errorf("expected text/* after removing text/plain but got %q", match.Pattern)
+	}
+	m.Remove("text")
+	if match, _ := m.BestMatch("text/plain"); match.Pattern != "text/*" {
+		t.Errorf("expected text/* to be kept when removing text but got %q", match.Pattern)
+	}
+
+	for _, p := range []string{"*/json", "text/p*", "text/*;q=2", "text"} {
+		if err := m.Add(p, p); !errors.Is(err, mimematch.ErrInvalidPattern) {
+			t.Errorf("%s: expected ErrInvalidPattern but got %v", p, err)
+		}
+	}
+}
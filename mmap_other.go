@@ -0,0 +1,17 @@
+//go:build !unix
+
+package trie
+
+import (
+	"os"
+)
+
+// mapFile reads the file at path into memory on systems where mmap is not
+// available.
+func mapFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
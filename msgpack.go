@@ -0,0 +1,111 @@
+package trie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+type msgpackWriter struct {
+	buf *bytes.Buffer
+}
+
+func newMsgpackWriter(buf *bytes.Buffer) wireWriter {
+	return msgpackWriter{buf}
+}
+
+// length writes a length prefix using the fix format if n is smaller than
+// fixMax and the 8, 16 or 32 bit format otherwise. A code of zero means that
+// the format does not exist.
+func (w msgpackWriter) length(n int, fix byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n < fixMax:
+		w.buf.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		w.buf.Write([]byte{code8, byte(n)})
+	case n <= math.MaxUint16:
+		w.buf.WriteByte(code16)
+		w.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		w.buf.WriteByte(code32)
+		w.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func (w msgpackWriter) writeNil() {
+	w.buf.WriteByte(0xc0)
+}
+
+func (w msgpackWriter) writeBool(b bool) {
+	if b {
+		w.buf.WriteByte(0xc3)
+	} else {
+		w.buf.WriteByte(0xc2)
+	}
+}
+
+func (w msgpackWriter) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		w.writeUint(uint64(i))
+	case i >= -32:
+		w.buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8:
+		w.buf.Write([]byte{0xd0, byte(int8(i))})
+	case i >= math.MinInt16:
+		w.buf.WriteByte(0xd1)
+		w.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(int16(i))))
+	case i >= math.MinInt32:
+		w.buf.WriteByte(0xd2)
+		w.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(int32(i))))
+	default:
+		w.buf.WriteByte(0xd3)
+		w.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
+
+func (w msgpackWriter) writeUint(u uint64) {
+	switch {
+	case u < 128:
+		w.buf.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		w.buf.Write([]byte{0xcc, byte(u)})
+	case u <= math.MaxUint16:
+		w.buf.WriteByte(0xcd)
+		w.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(u)))
+	case u <= math.MaxUint32:
+		w.buf.WriteByte(0xce)
+		w.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(u)))
+	default:
+		w.buf.WriteByte(0xcf)
+		w.buf.Write(binary.BigEndian.AppendUint64(nil, u))
+	}
+}
+
+func (w msgpackWriter) writeFloat32(f float32) {
+	w.buf.WriteByte(0xca)
+	w.buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(f)))
+}
+
+func (w msgpackWriter) writeFloat64(f float64) {
+	w.buf.WriteByte(0xcb)
+	w.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func (w msgpackWriter) writeString(s string) {
+	w.length(len(s), 0xa0, 32, 0xd9, 0xda, 0xdb)
+	w.buf.WriteString(s)
+}
+
+func (w msgpackWriter) writeBytes(b []byte) {
+	w.length(len(b), 0, 0, 0xc4, 0xc5, 0xc6)
+	w.buf.Write(b)
+}
+
+func (w msgpackWriter) writeArrayHeader(n int) {
+	w.length(n, 0x90, 16, 0, 0xdc, 0xdd)
+}
+
+func (w msgpackWriter) writeMapHeader(n int) {
+	w.length(n, 0x80, 16, 0, 0xde, 0xdf)
+}
@@ -0,0 +1,402 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Namespace returns a trie that confines all operations to the subtree of t at
+// prefix. The prefix is prepended to all paths passed to the returned trie and
+// stripped from all paths it returns, so a library can be handed a part of a
+// shared trie without being able to access anything outside of it. If prefix
+// is empty, t is returned.
+//
+// All modifications go through t, so wrappers such as WAL see the full paths.
+// Deleting the empty path deletes the node at prefix including all of its
+// children just like Clear, since t cannot remove only the value of a node
+// other than its root. Compact compacts all of t. The handle returned by Root
+// refers to the node at prefix, its Path still contains the prefix.
+func Namespace[V any](t String[V], prefix string) String[V] {
+	st := t.Root().t
+	segments := st.segments(st.normalize(prefix))
+	if len(segments) == 0 {
+		return t
+	}
+	return &namespace[V]{
+		t:        t,
+		st:       st,
+		prefix:   st.joinSegments(segments),
+		segments: segments,
+	}
+}
+
+// namespace prefixes all paths before passing them to t. Reads that are not
+// part of the String interface, like walking a subtree, are done on st which
+// is the trie underlying t.
+type namespace[V any] struct {
+	t        String[V]
+	st       *stringTrie[V]
+	prefix   string
+	segments []string
+}
+
+// path returns the full path of p in the underlying trie.
+func (ns *namespace[V]) path(p string) string {
+	p = ns.st.normalize(p)
+	if p == "" {
+		return ns.prefix
+	}
+	return ns.prefix + ns.st.delimiter + p
+}
+
+func (ns *namespace[V]) Put(path string, value V) {
+	ns.t.Put(ns.path(path), value)
+}
+
+func (ns *namespace[V]) PutE(path string, value V) error {
+	return ns.t.PutE(ns.path(path), value)
+}
+
+func (ns *namespace[V]) CheckPath(path string) error {
+	return ns.t.CheckPath(ns.path(path))
+}
+
+func (ns *namespace[V]) PutSegments(value V, segments ...string) {
+	ns.t.PutSegments(value, ns.join(segments)...)
+}
+
+func (ns *namespace[V]) Get(path string) (value V, found bool) {
+	value, found = ns.t.Get(ns.path(path))
+	if found && ns.st.normalize(path) == "" {
+		// Like the root of a trie, the root of the namespace is only found
+		// if it has a value.
+		_, hasValue := ns.st.valueAt(ns.segments)
+		found = hasValue
+	}
+	return value, found
+}
+
+func (ns *namespace[V]) GetE(path string) (V, error) {
+	value, found := ns.Get(path)
+	if !found {
+		return value, fmt.Errorf("%w: %q", ErrNotFound, path)
+	}
+	return value, nil
+}
+
+func (ns *namespace[V]) Lookup(path string) (V, LookupState) {
+	return ns.t.Lookup(ns.path(path))
+}
+
+func (ns *namespace[V]) GetSegments(segments ...string) (value V, found bool) {
+	if len(segments) == 0 {
+		return ns.Get("")
+	}
+	return ns.t.GetSegments(ns.join(segments)...)
+}
+
+func (ns *namespace[V]) GetBatch(paths []string, out []V) []bool {
+	full := make([]string, len(paths))
+	for i, path := range paths {
+		full[i] = ns.path(path)
+	}
+	found := ns.t.GetBatch(full, out)
+	for i, path := range paths {
+		if found[i] && ns.st.normalize(path) == "" {
+			_, found[i] = ns.st.valueAt(ns.segments)
+		}
+	}
+	return found
+}
+
+// join returns the segments of the namespace followed by segments.
+func (ns *namespace[V]) join(segments []string) []string {
+	return append(ns.segments[:len(ns.segments):len(ns.segments)], segments...)
+}
+
+func (ns *namespace[V]) Delete(path string) {
+	ns.t.Delete(ns.path(path))
+}
+
+func (ns *namespace[V]) DeleteE(path string) error {
+	err := ns.t.DeleteE(ns.path(path))
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %q", ErrNotFound, path)
+	}
+	return err
+}
+
+func (ns *namespace[V]) Clear() {
+	ns.t.Delete(ns.prefix)
+}
+
+// Rekey is applied as a sequence of Delete and Put operations on t, unlike the
+// Rekey of the String trie it is not atomic.
+func (ns *namespace[V]) Rekey(fn func(oldPath string) (newPath string, keep bool)) error {
+	return rekeyEach[V](ns, fn)
+}
+
+func (ns *namespace[V]) Compact() {
+	ns.t.Compact()
+}
+
+func (ns *namespace[V]) Delimiter() string {
+	return ns.t.Delimiter()
+}
+
+func (ns *namespace[V]) Codec() ValueCodec[V] {
+	return ns.t.Codec()
+}
+
+func (ns *namespace[V]) Walk(fn func(path string, value V) bool) {
+	defer ns.st.unpin(ns.st.pin())
+	fn, end := ns.st.traceWalk(ns.prefix, fn)
+	defer end()
+
+	v, ok := ns.st.find(ns.prefix)
+	if !ok {
+		return
+	}
+	ns.st.walk(v, "", true, fn)
+}
+
+// Iterator only iterates over the paths at and below the prefix, they are
+// relative to the prefix like the paths passed to Walk.
+func (ns *namespace[V]) Iterator() *Iterator[V] {
+	epoch := ns.st.pin()
+	v, ok := ns.st.find(ns.prefix)
+	if !ok {
+		v = view[string, V]{}
+	}
+	return ns.st.iterator(v, epoch)
+}
+
+func (ns *namespace[V]) WalkFrom(from string, fn func(path string, value V) bool) {
+	defer ns.st.unpin(ns.st.pin())
+	fn, end := ns.st.traceWalk(ns.prefix, fn)
+	defer end()
+
+	v, ok := ns.st.find(ns.prefix)
+	if !ok {
+		return
+	}
+	ns.st.walkFrom(v, "", true, ns.st.segments(ns.st.normalize(from)), fn)
+}
+
+// WalkPath only reports the nodes at and below the prefix.
+func (ns *namespace[V]) WalkPath(path string, fn func(path string, value V) bool) {
+	ns.t.WalkPath(ns.path(path), func(p string, value V) bool {
+		if len(p) < len(ns.prefix) {
+			return true
+		}
+		return fn(strings.TrimPrefix(p[len(ns.prefix):], ns.st.delimiter), value)
+	})
+}
+
+// GetShortestPrefix only considers the nodes at and below the prefix.
+func (ns *namespace[V]) GetShortestPrefix(path string) (value V, prefix string, found bool) {
+	ns.WalkPath(path, func(p string, v V) bool {
+		value, prefix, found = v, p, true
+		return false
+	})
+	return value, prefix, found
+}
+
+// ContainsPrefixOf only considers the nodes at and below the prefix.
+func (ns *namespace[V]) ContainsPrefixOf(path string) bool {
+	_, _, found := ns.GetShortestPrefix(path)
+	return found
+}
+
+func (ns *namespace[V]) HasKeysWithPrefix(path string) bool {
+	return ns.t.HasKeysWithPrefix(ns.path(path))
+}
+
+func (ns *namespace[V]) ShortestKeys(k int) []string {
+	defer ns.st.unpin(ns.st.pin())
+	v, ok := ns.st.find(ns.prefix)
+	if !ok {
+		return nil
+	}
+	return ns.st.shortestKeys(v, k)
+}
+
+func (ns *namespace[V]) LongestKeys(k int) []string {
+	defer ns.st.unpin(ns.st.pin())
+	v, ok := ns.st.find(ns.prefix)
+	if !ok {
+		return nil
+	}
+	return ns.st.longestKeys(v, k)
+}
+
+func (ns *namespace[V]) Meta(path string) (createdAt, updatedAt time.Time, ok bool) {
+	return ns.t.Meta(ns.path(path))
+}
+
+func (ns *namespace[V]) Sample(n int) []string {
+	if ns.st.counts == nil {
+		return ns.st.sampleWeighted(ns.Walk, n, nil)
+	}
+	defer ns.st.unpin(ns.st.pin())
+	ns.st.counts.Lock()
+	defer ns.st.counts.Unlock()
+	v, ok := ns.st.find(ns.prefix)
+	if !ok {
+		return nil
+	}
+	return ns.st.sampleCounted(v, n)
+}
+
+func (ns *namespace[V]) SampleWeighted(n int, weight func(V) float64) []string {
+	return ns.st.sampleWeighted(ns.Walk, n, weight)
+}
+
+// HotKeys only reports the paths at and below the prefix, including reads
+// that have not been done through the namespace.
+func (ns *namespace[V]) HotKeys(k int) []HotKey {
+	if ns.st.access == nil {
+		return nil
+	}
+
+	base := ns.prefix + ns.st.delimiter
+	hits := ns.st.access.hits(func(path string) bool {
+		return path == ns.prefix || strings.HasPrefix(path, base)
+	})
+	relative := make(map[string]int, len(hits))
+	for path, n := range hits {
+		relative[strings.TrimPrefix(strings.TrimPrefix(path, ns.prefix), ns.st.delimiter)] = n
+	}
+	return topHits(relative, k)
+}
+
+// GetLongestSuffix walks the namespace, the suffix index is not used.
+func (ns *namespace[V]) GetLongestSuffix(path string) (value V, suffix string, found bool) {
+	return ns.st.scanLongestSuffix(ns.Walk, path)
+}
+
+// SuffixKeys walks the namespace, the suffix index is not used.
+func (ns *namespace[V]) SuffixKeys(suffix string) []string {
+	return ns.st.scanSuffixKeys(ns.Walk, suffix)
+}
+
+func (ns *namespace[V]) List(prefix, delimiter string, maxKeys int, startAfter string) (keys, commonPrefixes []string, truncated bool) {
+	base := ns.prefix + ns.st.delimiter
+	if startAfter != "" {
+		startAfter = base + startAfter
+	}
+	keys, commonPrefixes, truncated = ns.t.List(base+prefix, delimiter, maxKeys, startAfter)
+	for i := range keys {
+		keys[i] = keys[i][len(base):]
+	}
+	for i := range commonPrefixes {
+		commonPrefixes[i] = commonPrefixes[i][len(base):]
+	}
+	return keys, commonPrefixes, truncated
+}
+
+func (ns *namespace[V]) Children(path string) (segments []string, found bool) {
+	return ns.t.Children(ns.path(path))
+}
+
+func (ns *namespace[V]) ToMap() map[string]V {
+	m := make(map[string]V)
+	ns.Walk(func(path string, value V) bool {
+		m[path] = value
+		return true
+	})
+	return m
+}
+
+// Root returns a handle to the node at the prefix. If the node does not
+// exist, the handle refers to a detached empty node and Set creates it.
+func (ns *namespace[V]) Root() Node[V] {
+	n := ns.t.Root()
+	for _, key := range ns.segments {
+		child, ok := n.Child(key)
+		if !ok {
+			n.v, n.segments = view[string, V]{n: &node[string, V]{}}, ns.segments
+			return n
+		}
+		n = child
+	}
+	return n
+}
+
+// subtree returns a copy of the namespace in a separate trie, it is used to
+// serialize the namespace.
+func (ns *namespace[V]) subtree() *stringTrie[V] {
+	t := newStringTrie[V](ns.st.delimiter)
+	t.codec = ns.st.codec
+	t.escape = ns.st.escape
+	ns.Walk(func(path string, value V) bool {
+		t.insert(t.segments(path), value, true)
+		return true
+	})
+	return t
+}
+
+func (ns *namespace[V]) WriteTo(w io.Writer) (int64, error) {
+	return ns.subtree().WriteTo(w)
+}
+
+// ReadFrom reads a trie in the binary format and puts all of its values into
+// the namespace one by one.
+func (ns *namespace[V]) ReadFrom(r io.Reader) (int64, error) {
+	t := newStringTrie[V](ns.st.delimiter)
+	t.codec = ns.st.codec
+	t.escape = ns.st.escape
+	n, err := t.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+
+	t.Walk(func(path string, value V) bool {
+		ns.Put(path, value)
+		return true
+	})
+	return n, nil
+}
+
+func (ns *namespace[V]) WriteDOT(w io.Writer, opts ...DotOption) error {
+	return ns.subtree().WriteDOT(w, opts...)
+}
+
+func (ns *namespace[V]) Dump(w io.Writer) error {
+	return ns.subtree().Dump(w)
+}
+
+// DumpCanonical renders the node at the prefix as the root, it only contains
+// the header if the node does not exist.
+func (ns *namespace[V]) DumpCanonical() string {
+	defer ns.st.unpin(ns.st.pin())
+	v, ok := ns.st.find(ns.prefix)
+	if !ok {
+		return ns.st.dumpCanonical(view[string, V]{n: &node[string, V]{}})
+	}
+	return ns.st.dumpCanonical(v)
+}
+
+// Validate checks all of t, not just the namespace.
+func (ns *namespace[V]) Validate() error {
+	return ns.t.Validate()
+}
+
+// String returns a summary of the namespace containing its prefix, the number
+// of values and the number of segments of the longest path.
+func (ns *namespace[V]) String() string {
+	s := ns.subtree().stats()
+	return fmt.Sprintf("trie.Namespace{prefix: %q, values: %d, depth: %d}", ns.prefix, s.Values, s.Depth)
+}
+
+// Format implements fmt.Formatter like the Format method of the String trie.
+func (ns *namespace[V]) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('+') {
+		_ = ns.Dump(f)
+		return
+	}
+	_, _ = io.WriteString(f, ns.String())
+}
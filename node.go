@@ -0,0 +1,533 @@
+package trie
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// node is a node of a path-compressed trie. Chains of nodes that have no value
+// and only a single child are merged into one node which stores the keys of
+// the whole chain in segments. The nodes that are part of such a chain but
+// have been merged into the node at its end are called implicit nodes, they
+// are still found by all operations but do not allocate any memory.
+//
+// The lock of a node protects its children, value and hasValue. The segments
+// of a node are protected by the lock of its parent, they are never modified
+// in place so a copy of the slice remains valid after the lock is released.
+// All operations descend the trie using lock coupling, i.e. the lock of a
+// child is acquired before the lock of its parent is released.
+type node[K comparable, V any] struct {
+	// debug is only used by builds with the triedebug tag, it is the
+	// first field since a trailing field of size zero takes up space.
+	debug    nodeDebug
+	lock     sync.RWMutex
+	segments []K
+	children nodeChildren[K, V]
+
+	value V
+	// hasValue is true if value has been set explicitly by Put and false if
+	// the node was only created as part of a longer path.
+	hasValue bool
+	// meta contains the timestamps of the value if they are recorded, see
+	// WithTimestamps. It is protected by the lock of the node.
+	meta *nodeMeta
+	// count is the number of values in the subtree of the node including
+	// its own. It is only maintained and accessed while holding the counts
+	// lock of the tree.
+	count int
+}
+
+// tree contains the root node of a trie and the functionality shared by all
+// implementations.
+type tree[K comparable, V any] struct {
+	root *node[K, V]
+	// pool is used to allocate nodes if pooling has been enabled, it is nil
+	// otherwise.
+	pool *sync.Pool
+	// reclaim delays returning removed nodes to the pool until no reader
+	// can reach them anymore, it is set together with pool.
+	reclaim *reclaimer[K, V]
+	// arena is used to allocate nodes if arena allocation has been enabled,
+	// it is nil otherwise.
+	arena *arena[K, V]
+	// inst is called after each operation if it is not nil.
+	inst Instrumentation
+	// sched is called before locks are acquired if it is not nil, see
+	// WithScheduler.
+	sched Scheduler
+	// latency is set if inst also implements LatencyInstrumentation.
+	latency LatencyInstrumentation
+	// removed is called by discard and clearRoot for every value that has
+	// been removed from the tree if it is not nil.
+	removed func(segments []K, value V)
+	// counts is held while the count of any node is accessed if it is not
+	// nil, the counts are not maintained otherwise. See WithSubtreeCounts.
+	counts *sync.Mutex
+	// now returns the time recorded for writes to values if it is not nil,
+	// see WithTimestamps.
+	now func() time.Time
+}
+
+func newTree[K comparable, V any]() tree[K, V] {
+	return tree[K, V]{
+		root: &node[K, V]{},
+	}
+}
+
+// enablePool makes the tree reuse nodes that have been removed.
+func (t *tree[K, V]) enablePool() {
+	t.pool = &sync.Pool{
+		New: func() any {
+			return &node[K, V]{}
+		},
+	}
+	t.reclaim = &reclaimer[K, V]{}
+}
+
+// enableArena makes the tree allocate its nodes in chunks which are dropped
+// as a whole by clear.
+func (t *tree[K, V]) enableArena() {
+	t.arena = &arena[K, V]{}
+}
+
+// newNode creates a new node with the given segments.
+func (t *tree[K, V]) newNode(segments []K) *node[K, V] {
+	if t.arena != nil {
+		n := t.arena.alloc()
+		n.segments = segments
+		return n
+	}
+	if t.pool != nil {
+		n := t.pool.Get().(*node[K, V])
+		n.segments = segments
+		return n
+	}
+
+	return &node[K, V]{segments: segments}
+}
+
+// release returns n and all of its children to the pool if pooling is
+// enabled, as soon as no reader can reach them anymore. n must not be
+// reachable from the root anymore.
+func (t *tree[K, V]) release(n *node[K, V]) {
+	if t.pool == nil {
+		return
+	}
+	t.recycle(t.reclaim.retire(n)...)
+}
+
+// recycle returns the nodes and all of their children to the pool.
+func (t *tree[K, V]) recycle(nodes ...*node[K, V]) {
+	for _, n := range nodes {
+		t.recycleNode(n)
+	}
+}
+
+func (t *tree[K, V]) recycleNode(n *node[K, V]) {
+	n.lock.Lock()
+	children := n.children
+	n.children = nodeChildren[K, V]{}
+	n.lock.Unlock()
+
+	children.each(func(_ K, child *node[K, V]) {
+		t.recycleNode(child)
+	})
+
+	var zero V
+	n.segments = nil
+	n.value = zero
+	n.hasValue = false
+	n.meta = nil
+	n.count = 0
+	if debugEnabled {
+		// Released nodes are never reused so that stale references to
+		// them are detected, see debug_on.go.
+		n.debug.markReleased()
+		return
+	}
+	t.pool.Put(n)
+}
+
+// discard calls removed for every value of the subtree n, which has been
+// detached from the tree at path, and releases its nodes.
+func (t *tree[K, V]) discard(path []K, n *node[K, V]) {
+	if t.removed != nil {
+		t.visitRemoved(path, n)
+	}
+	t.release(n)
+}
+
+func (t *tree[K, V]) visitRemoved(path []K, n *node[K, V]) {
+	n.lock.RLock()
+	value, hasValue := n.value, n.hasValue
+	var children []*node[K, V]
+	n.children.each(func(_ K, child *node[K, V]) {
+		children = append(children, child)
+	})
+	n.lock.RUnlock()
+
+	if hasValue {
+		t.removed(path, value)
+	}
+	for _, child := range children {
+		// The segments of child are protected by the lock of n, but n is no
+		// longer reachable so nobody can modify them anymore.
+		t.visitRemoved(append(path[:len(path):len(path)], child.segments...), child)
+	}
+}
+
+// put stores value at the node reached by following segments, creating all
+// nodes on the way. If set is false, the node is only created and its value
+// remains unchanged. It returns the previous value of the node and whether it
+// had one.
+func (t *tree[K, V]) put(segments []K, value V, set bool) (old V, existed bool) {
+	var wait time.Duration
+	if t.inst != nil {
+		depth := len(segments)
+		defer func() { t.inst.Put(depth, wait) }()
+	}
+
+	n := t.root
+	t.lockNode(n, &wait)
+
+	for len(segments) > 0 {
+		child, ok := n.children.get(segments[0])
+		if !ok {
+			child = t.newNode(append([]K(nil), segments...))
+			child.value = value
+			child.hasValue = set
+			if set {
+				t.stamp(child, false)
+			}
+			n.children.set(segments[0], child)
+			assertChildren(n)
+			n.lock.Unlock()
+			return old, false
+		}
+
+		run := child.segments
+		i := 1
+		for i < len(run) && i < len(segments) && run[i] == segments[i] {
+			i++
+		}
+
+		if i < len(run) {
+			// The path diverges from or ends within the run of the child,
+			// split it into a new node containing the common part and the
+			// child which keeps the remaining part.
+			split := t.newNode(append([]K(nil), run[:i]...))
+			split.children.set(run[i], child)
+			if t.counts != nil {
+				split.count = child.count
+			}
+			child.segments = run[i:]
+			n.children.set(segments[0], split)
+			assertChildren(n)
+			child = split
+		}
+
+		t.lockNode(child, &wait)
+		n.lock.Unlock()
+		n = child
+		segments = segments[i:]
+	}
+
+	old, existed = n.value, n.hasValue
+	if set {
+		n.value = value
+		n.hasValue = true
+		t.stamp(n, existed)
+	}
+	n.lock.Unlock()
+	return old, existed
+}
+
+// remove detaches the node reached by following segments including all of its
+// children. segments must not be empty. If the node existed, it returns the
+// detached subtree and its path, which must be passed to discard.
+func (t *tree[K, V]) remove(segments []K) (removed *node[K, V], path []K) {
+	var (
+		wait  time.Duration
+		depth int
+	)
+	if t.inst != nil {
+		defer func() { t.inst.Delete(depth, removed != nil, wait) }()
+	}
+
+	all := segments
+	n := t.root
+	t.lockNode(n, &wait)
+
+	for {
+		child, ok := n.children.get(segments[0])
+		if !ok {
+			n.lock.Unlock()
+			return
+		}
+
+		run := child.segments
+		i := 1
+		for i < len(run) && i < len(segments) && run[i] == segments[i] {
+			i++
+		}
+		depth += i
+
+		switch {
+		case i < len(run) && i < len(segments):
+			// The path diverges from the run.
+			n.lock.Unlock()
+			return
+		case i == len(segments) && i == 1:
+			n.children.delete(segments[0])
+			assertChildren(n)
+			n.lock.Unlock()
+			return child, detachedPath(all[:len(all)-len(segments)], run)
+		case i == len(segments):
+			// The node is an implicit node within the run, its parent
+			// becomes the end of the run. It keeps the count of the run
+			// until the caller subtracts the removed values.
+			parent := t.newNode(append([]K(nil), run[:i-1]...))
+			if t.counts != nil {
+				parent.count = child.count
+			}
+			n.children.set(segments[0], parent)
+			assertChildren(n)
+			n.lock.Unlock()
+			return child, detachedPath(all[:len(all)-len(segments)], run)
+		}
+
+		t.lockNode(child, &wait)
+		n.lock.Unlock()
+		n = child
+		segments = segments[i:]
+	}
+}
+
+// detachedPath returns the path of a child with the given run whose parent is
+// at path.
+func detachedPath[K comparable](path, run []K) []K {
+	return append(append(make([]K, 0, len(path)+len(run)), path...), run...)
+}
+
+// clearRoot removes the value of the root node, its children are kept. It
+// returns whether the root had a value.
+func (t *tree[K, V]) clearRoot() bool {
+	old, hadValue := t.takeRoot()
+	if hadValue && t.removed != nil {
+		t.removed(nil, old)
+	}
+	return hadValue
+}
+
+// takeRoot is like clearRoot but returns the old value instead of passing it
+// to removed.
+func (t *tree[K, V]) takeRoot() (old V, hadValue bool) {
+	var wait time.Duration
+	t.lockNode(t.root, &wait)
+	old, hadValue = t.root.value, t.root.hasValue
+	var zero V
+	t.root.value = zero
+	t.root.hasValue = false
+	t.root.meta = nil
+	t.root.lock.Unlock()
+	return old, hadValue
+}
+
+// unset removes the value of the node reached by following segments but, unlike
+// remove, keeps the node and its children. Implicit nodes have no value, so
+// they are left unchanged.
+func (t *tree[K, V]) unset(segments []K) {
+	n := t.root
+	n.lock.Lock()
+	for len(segments) > 0 {
+		child, ok := n.children.get(segments[0])
+		if !ok || len(child.segments) > len(segments) || !slices.Equal(child.segments, segments[:len(child.segments)]) {
+			n.lock.Unlock()
+			return
+		}
+		child.lock.Lock()
+		n.lock.Unlock()
+		n = child
+		segments = segments[len(child.segments):]
+	}
+
+	var zero V
+	n.value = zero
+	n.hasValue = false
+	n.meta = nil
+	n.lock.Unlock()
+}
+
+// clear detaches all nodes and the value of the root node. It returns them as
+// a detached root which must be passed to discard.
+func (t *tree[K, V]) clear() *node[K, V] {
+	t.root.lock.Lock()
+	old := &node[K, V]{
+		children: t.root.children,
+		value:    t.root.value,
+		hasValue: t.root.hasValue,
+	}
+	t.root.children = nodeChildren[K, V]{}
+	var zero V
+	t.root.value = zero
+	t.root.hasValue = false
+	t.root.meta = nil
+	t.root.lock.Unlock()
+
+	if t.arena != nil {
+		t.arena.reset()
+	}
+	return old
+}
+
+// compact drops all nodes without a value that have no children with a value,
+// merges chains that became compressible and shrinks the storage of the
+// children of all nodes.
+func (t *tree[K, V]) compact() {
+	t.root.lock.Lock()
+	t.compactNode(t.root)
+	t.root.lock.Unlock()
+}
+
+// compactNode compacts the children of n. The caller must hold the lock of n.
+func (t *tree[K, V]) compactNode(n *node[K, V]) {
+	assertLocked(n)
+	var (
+		keys     []K
+		children []*node[K, V]
+	)
+	n.children.each(func(k K, child *node[K, V]) {
+		keys = append(keys, k)
+		children = append(children, child)
+	})
+
+	for i, child := range children {
+		child.lock.Lock()
+		t.compactNode(child)
+
+		switch {
+		case child.hasValue:
+		case child.children.len() == 0:
+			n.children.delete(keys[i])
+			child.lock.Unlock()
+			t.release(child)
+			continue
+		case child.children.len() == 1:
+			// Merge the child with its only child. The segments of the
+			// grandchild are protected by the lock of child which is held.
+			child.children.each(func(_ K, grandchild *node[K, V]) {
+				segments := make([]K, 0, len(child.segments)+len(grandchild.segments))
+				segments = append(segments, child.segments...)
+				grandchild.segments = append(segments, grandchild.segments...)
+				n.children.set(keys[i], grandchild)
+			})
+		}
+		child.lock.Unlock()
+	}
+
+	n.children.shrink()
+	assertChildren(n)
+}
+
+// cursor descends a tree one key at a time using lock coupling. It holds the
+// read lock of the node it points to until close is called or a call to next
+// returns false.
+type cursor[K comparable, V any] struct {
+	n *node[K, V]
+	// run contains the segments of n that have not been followed yet. If it
+	// is not empty, the cursor points to an implicit node.
+	run []K
+
+	// depth and wait are only tracked if timed is true, see Instrumentation.
+	timed bool
+	depth int
+	wait  time.Duration
+	// start is only set if the tree reports latencies, see
+	// LatencyInstrumentation.
+	start time.Time
+	sched Scheduler
+}
+
+func (t *tree[K, V]) cursor() cursor[K, V] {
+	c := cursor[K, V]{n: t.root, timed: t.inst != nil, sched: t.sched}
+	if t.latency != nil {
+		c.start = time.Now()
+	}
+	rlockNode(c.n, c.sched, c.timed, &c.wait)
+	return c
+}
+
+// next moves the cursor to the child with the given key. If there is no such
+// child, the lock is released and false is returned.
+func (c *cursor[K, V]) next(key K) bool {
+	if len(c.run) > 0 {
+		if c.run[0] != key {
+			c.n.lock.RUnlock()
+			return false
+		}
+		c.run = c.run[1:]
+		c.depth++
+		return true
+	}
+
+	child, ok := c.n.children.get(key)
+	if !ok {
+		c.n.lock.RUnlock()
+		return false
+	}
+
+	c.run = child.segments[1:]
+	rlockNode(child, c.sched, c.timed, &c.wait)
+	assertNotReleased(child)
+	c.n.lock.RUnlock()
+	c.n = child
+	c.depth++
+	return true
+}
+
+// value returns the value of the node the cursor points to.
+func (c *cursor[K, V]) value() (value V, hasValue bool) {
+	assertLocked(c.n)
+	if len(c.run) > 0 {
+		return value, false
+	}
+	return c.n.value, c.n.hasValue
+}
+
+func (c *cursor[K, V]) view() view[K, V] {
+	return view[K, V]{run: c.run, n: c.n}
+}
+
+func (c *cursor[K, V]) close() {
+	c.n.lock.RUnlock()
+}
+
+// view is a reference to a node that can be used without holding any locks.
+// It either points to n itself or, if run is not empty, to the implicit node
+// that is followed by the segments in run to reach n.
+type view[K comparable, V any] struct {
+	run []K
+	n   *node[K, V]
+}
+
+// snapshot returns the value of the node and its children in no particular
+// order. The lock of n is only held while the snapshot is taken.
+func (v view[K, V]) snapshot() (value V, hasValue bool, keys []K, children []view[K, V]) {
+	if len(v.run) > 0 {
+		return value, false, v.run[:1], []view[K, V]{{run: v.run[1:], n: v.n}}
+	}
+
+	v.n.lock.RLock()
+	defer v.n.lock.RUnlock()
+	assertNotReleased(v.n)
+
+	keys = make([]K, 0, v.n.children.len())
+	children = make([]view[K, V], 0, v.n.children.len())
+	v.n.children.each(func(k K, child *node[K, V]) {
+		keys = append(keys, k)
+		children = append(children, view[K, V]{run: child.segments[1:], n: child})
+	})
+
+	return v.n.value, v.n.hasValue, keys, children
+}
@@ -0,0 +1,394 @@
+package trie
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Option configures a trie created by New or NewSlice.
+type Option func(*options)
+
+type options struct {
+	codec any
+	pool  bool
+	arena bool
+
+	bloomNodes        int
+	bloomFalsePosRate float64
+	cacheSize         int
+
+	inst   Instrumentation
+	escape rune
+	trim   bool
+
+	validator   func(segment string) error
+	validateGet bool
+	maxDepth    int
+	maxKeyLen   int
+
+	normalize bool
+	form      norm.Form
+	fold      bool
+	collation *language.Tag
+
+	hooks any
+	evict any
+
+	tracer Tracer
+	logger *slog.Logger
+	sched  Scheduler
+
+	suffixIndex   bool
+	subtreeCounts bool
+	timestamps    bool
+
+	accessWindow time.Duration
+	accessClock  func() time.Time
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithCodec makes the trie use codec to serialize its values. The type of the
+// values of codec must match the type of the values of the trie. It does not
+// apply to Slice.
+func WithCodec[V any](codec ValueCodec[V]) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithPooling makes the trie reuse the nodes removed by Delete and Clear for
+// subsequent insertions to reduce the pressure on the garbage collector for
+// workloads that churn keys. It replaces WithArena.
+//
+// Removed nodes are only reused once no concurrent operation can reach them
+// anymore, so the trie remains safe for concurrent use. Handles returned by
+// Root look up their path again on every access. Use Detach to take a subtree
+// out of the trie and Release to return its nodes to the pool explicitly.
+func WithPooling() Option {
+	return func(o *options) {
+		o.pool, o.arena = true, false
+	}
+}
+
+// WithArena makes the trie allocate its nodes in chunks instead of one at a
+// time. Clear drops all chunks at once, which makes building, using and
+// discarding a trie as a unit considerably cheaper for the allocator and the
+// garbage collector. Nodes removed by Delete are not reused, their memory is
+// only released once the whole chunk is unreachable. It replaces WithPooling.
+func WithArena() Option {
+	return func(o *options) {
+		o.arena, o.pool = true, false
+	}
+}
+
+// WithBloomFilter makes the trie maintain a Bloom filter over the paths of all
+// nodes which is consulted by Get before descending into the trie. This makes
+// lookups of paths that are not in the trie considerably cheaper for workloads
+// dominated by misses. The filter is sized for the given number of nodes and
+// false positive rate, adding more nodes increases the false positive rate.
+// Deleted paths remain in the filter until Clear is called, so they degrade to
+// a regular lookup. It does not apply to Slice.
+func WithBloomFilter(nodes int, falsePositiveRate float64) Option {
+	return func(o *options) {
+		o.bloomNodes, o.bloomFalsePosRate = nodes, falsePositiveRate
+	}
+}
+
+// WithCache makes the trie cache the results of up to size recent lookups.
+// Writes invalidate the cached results of all paths they affect, so repeated
+// lookups of hot paths skip the descent into the trie without ever returning
+// stale results. Unlike for the other tries, a Get that is not served from the
+// cache allocates. It does not apply to Slice.
+func WithCache(size int) Option {
+	return func(o *options) {
+		o.cacheSize = size
+	}
+}
+
+// WithInstrumentation makes the trie call inst after each Put, Get and Delete.
+// If inst implements LatencyInstrumentation, the latencies of lookups are
+// reported as well.
+func WithInstrumentation(inst Instrumentation) Option {
+	return func(o *options) {
+		o.inst = inst
+	}
+}
+
+// WithEscape makes the trie treat delimiters preceded by escape as part of the
+// segment instead of splitting the path there. The escape itself is escaped by
+// repeating it. Segments are stored without the escapes and the paths passed
+// to Walk and returned by ToMap are escaped again, see EscapeSegment. It does
+// not apply to Slice.
+func WithEscape(escape rune) Option {
+	return func(o *options) {
+		o.escape = escape
+	}
+}
+
+// WithTrimmedDelimiters makes the trie remove all leading and trailing
+// delimiters from paths, so that e.g. "foo/bar", "/foo/bar" and "foo/bar/"
+// refer to the same node instead of creating nodes with empty segments. It
+// does not apply to Slice.
+func WithTrimmedDelimiters() Option {
+	return func(o *options) {
+		o.trim = true
+	}
+}
+
+// WithKeyValidator makes the trie pass every segment of the paths passed to
+// Put and PutE, and of the paths read by ReadFrom, to validator. Paths with a
+// segment for which validator returns an error are rejected. It does not
+// apply to Slice.
+func WithKeyValidator(validator func(segment string) error) Option {
+	return func(o *options) {
+		o.validator = validator
+	}
+}
+
+// WithMaxDepth limits the number of segments of the paths passed to Put and
+// PutE, and of the paths read by ReadFrom, to n. Deeper paths are rejected
+// just like paths rejected by the key validator. It does not apply to Slice.
+func WithMaxDepth(n int) Option {
+	return func(o *options) {
+		o.maxDepth = n
+	}
+}
+
+// WithMaxKeyLen limits the length in bytes of the segments of the paths
+// passed to Put and PutE, and of the paths read by ReadFrom, to n. Paths with
+// a longer segment are rejected just like paths rejected by the key
+// validator. It does not apply to Slice.
+func WithMaxKeyLen(n int) Option {
+	return func(o *options) {
+		o.maxKeyLen = n
+	}
+}
+
+// WithValidatedGet makes Get pass the segments to the key validator as well,
+// paths that are rejected are reported as not found without descending into
+// the trie.
+func WithValidatedGet() Option {
+	return func(o *options) {
+		o.validateGet = true
+	}
+}
+
+// WithUnicodeNormalization makes the trie convert all segments to the given
+// normalization form, so that e.g. the composed and decomposed forms of the
+// same text refer to the same node. Segments that are already normalized are
+// used as is without allocating. It does not apply to Slice.
+func WithUnicodeNormalization(form norm.Form) Option {
+	return func(o *options) {
+		o.normalize, o.form = true, form
+	}
+}
+
+// WithCaseFolding makes the trie apply full Unicode case folding to all
+// segments, so that paths that only differ in case refer to the same node.
+// Case folding is applied after the normalization of WithUnicodeNormalization.
+// It does not apply to Slice.
+func WithCaseFolding() Option {
+	return func(o *options) {
+		o.fold = true
+	}
+}
+
+// WithCollation makes Walk and Children return the segments in the order
+// defined by the collation rules of the given language instead of byte order.
+// The binary formats are not affected. It does not apply to Slice.
+func WithCollation(tag language.Tag) Option {
+	return func(o *options) {
+		o.collation = &tag
+	}
+}
+
+// hooks are the callbacks passed to WithHooks.
+type hooks[V any] struct {
+	onPut    func(path string, old, new V, existed bool)
+	onDelete func(path string, value V)
+}
+
+// WithHooks makes the trie call onPut after every value that has been set by
+// Put, ReadFrom or any other method that sets values, with the previous value
+// of the node and whether it had one. onDelete is called for every value
+// removed by Delete and Clear, including the values of all children of a
+// deleted node. Both are called synchronously after the modification has been
+// applied and may be nil. The paths are escaped like the ones passed to Walk.
+// The value types of the hooks must match the type of the values of the trie.
+// It does not apply to Slice.
+func WithHooks[V any](onPut func(path string, old, new V, existed bool), onDelete func(path string, value V)) Option {
+	return func(o *options) {
+		o.hooks = hooks[V]{onPut, onDelete}
+	}
+}
+
+// WithEvict makes the trie call evict for every value that is removed from the
+// trie by Delete or Clear, including the values of all children of a deleted
+// node, and for every value that is replaced by a new one, so that values
+// holding resources can be released deterministically. It is called
+// synchronously after the modification has been applied and after the hooks
+// of WithHooks. The value type of evict must match the type of the values of
+// the trie. It does not apply to Slice.
+func WithEvict[V any](evict func(path string, value V)) Option {
+	return func(o *options) {
+		o.evict = evict
+	}
+}
+
+// WithTracer makes the trie start a span with tracer for every operation that
+// visits large parts of the trie, see Tracer. It does not apply to Slice.
+func WithTracer(tracer Tracer) Option {
+	return func(o *options) {
+		o.tracer = tracer
+	}
+}
+
+// WithLogger makes the trie log structural changes, i.e. deleted subtrees,
+// Clear, Compact and Rekey, at debug level to logger. The size of the affected
+// parts of the trie is only computed if debug messages are enabled. It does
+// not apply to Slice.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// applyTree applies the options shared by all tries to t.
+func applyTree[K comparable, V any](t *tree[K, V], o *options) {
+	switch {
+	case o.pool:
+		t.enablePool()
+	case o.arena:
+		t.enableArena()
+	}
+	t.inst = o.inst
+	t.latency, _ = o.inst.(LatencyInstrumentation)
+	t.sched = o.sched
+}
+
+// applyString applies all options to t. It panics if the codec, the hooks or
+// the evict callback do not match the type of the values of t.
+func applyString[V any](t *stringTrie[V], o *options) {
+	applyTree(&t.tree, o)
+
+	if o.codec != nil {
+		codec, ok := o.codec.(ValueCodec[V])
+		if !ok {
+			panic(fmt.Sprintf("trie: codec %T cannot be used for values of type %T", o.codec, *new(V)))
+		}
+		t.codec = codecOrDefault(codec)
+	}
+	var onDelete func(string, V)
+	if o.hooks != nil {
+		h, ok := o.hooks.(hooks[V])
+		if !ok {
+			panic(fmt.Sprintf("trie: hooks %T cannot be used for values of type %T", o.hooks, *new(V)))
+		}
+		t.onPut = h.onPut
+		onDelete = h.onDelete
+	}
+	if o.evict != nil {
+		evict, ok := o.evict.(func(string, V))
+		if !ok {
+			panic(fmt.Sprintf("trie: evict callback %T cannot be used for values of type %T", o.evict, *new(V)))
+		}
+		t.evict = evict
+	}
+	if onDelete != nil || t.evict != nil {
+		t.removed = func(segments []string, value V) {
+			path := t.joinSegments(segments)
+			if onDelete != nil {
+				onDelete(path, value)
+			}
+			if t.evict != nil {
+				t.evict(path, value)
+			}
+		}
+	}
+	t.tracer = o.tracer
+	t.logger = o.logger
+	if o.suffixIndex {
+		t.suffix = &suffixIndex{keys: newTree[string, struct{}]()}
+	}
+	if o.subtreeCounts {
+		t.counts = &sync.Mutex{}
+	}
+	if o.timestamps {
+		t.now = time.Now
+	}
+	if o.accessWindow > 0 {
+		t.access = newAccessStats(o.accessWindow, o.accessClock)
+	}
+	t.escape = o.escape
+	t.trim = o.trim
+	t.validator = o.validator
+	t.validateGet = o.validateGet && o.validator != nil
+	t.maxDepth = o.maxDepth
+	t.maxKeyLen = o.maxKeyLen
+	var transforms []func(string) string
+	if o.normalize {
+		form := o.form
+		transforms = append(transforms, func(segment string) string {
+			if form.QuickSpanString(segment) == len(segment) {
+				return segment
+			}
+			return form.String(segment)
+		})
+	}
+	if o.fold {
+		caser := cases.Fold()
+		transforms = append(transforms, func(segment string) string {
+			if !hasUpperOrNonASCII(segment) {
+				return segment
+			}
+			return caser.String(segment)
+		})
+	}
+	switch len(transforms) {
+	case 0:
+	case 1:
+		t.transform = transforms[0]
+	default:
+		t.transform = func(segment string) string {
+			for _, transform := range transforms {
+				segment = transform(segment)
+			}
+			return segment
+		}
+	}
+	if o.collation != nil {
+		t.collator = &collator{c: collate.New(*o.collation)}
+	}
+	if o.bloomNodes > 0 {
+		t.bloom = newBloomFilter(o.bloomNodes, o.bloomFalsePosRate)
+	}
+	if o.cacheSize > 0 {
+		t.cache = newLookupCache[V](o.cacheSize)
+	}
+}
+
+// hasUpperOrNonASCII returns whether s contains characters that might be
+// changed by case folding.
+func hasUpperOrNonASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= utf8.RuneSelf || 'A' <= c && c <= 'Z' {
+			return true
+		}
+	}
+	return false
+}
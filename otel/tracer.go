@@ -0,0 +1,54 @@
+// Package otel implements trie.Tracer on top of an OpenTelemetry tracer.
+// It lives in its own module to avoid forcing the OpenTelemetry dependency on
+// users of the trie package.
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"moehl.dev/trie"
+)
+
+// Tracer starts the spans of a trie with an OpenTelemetry tracer.
+type Tracer struct {
+	ctx    context.Context
+	tracer trace.Tracer
+}
+
+var _ trie.Tracer = (*Tracer)(nil)
+
+// New returns a tracer which starts all spans with tracer as children of the
+// span in ctx, if any. The operations of a trie do not take a context, so the
+// spans cannot be children of the span of the request they belong to.
+func New(ctx context.Context, tracer trace.Tracer) *Tracer {
+	return &Tracer{ctx: ctx, tracer: tracer}
+}
+
+func (t *Tracer) Start(name string) trie.Span {
+	_, span := t.tracer.Start(t.ctx, name)
+	return otelSpan{span}
+}
+
+// otelSpan converts the attributes of a trie.Span.
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttribute(key string, value any) {
+	switch v := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
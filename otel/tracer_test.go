@@ -0,0 +1,32 @@
+package otel_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"moehl.dev/trie"
+	"moehl.dev/trie/otel"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	tr := trie.New[int]("/", trie.WithTracer(otel.New(context.Background(), provider.Tracer("test"))))
+	tr.Put("a/b", 1)
+	tr.Walk(func(string, int) bool { return true })
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "trie.Walk" {
+		t.Fatalf("expected a single trie.Walk span but got %v", spans)
+	}
+	expected := []attribute.KeyValue{attribute.String("trie.prefix", ""), attribute.Int("trie.values", 1)}
+	got := spans[0].Attributes()
+	if len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
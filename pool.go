@@ -0,0 +1,131 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// reclaimer implements a simple epoch based reclamation of the nodes of a
+// pooled tree. Operations that access nodes through views, which do not hold
+// any locks, pin the current epoch while they run. Nodes that are removed
+// from the tree are retired in the current epoch and only returned to the
+// pool once all readers that may have seen them have unpinned, i.e. all
+// readers of the epoch they have been retired in and of the one before.
+//
+// Operations that descend the tree using lock coupling do not have to pin,
+// recycling a node acquires its lock and thereby waits for them.
+type reclaimer[K comparable, V any] struct {
+	lock sync.Mutex
+	// epoch is the parity of the current epoch.
+	epoch   int
+	readers [2]int
+	retired [2][]*node[K, V]
+}
+
+// pin makes sure that no node reachable from the root is returned to the pool
+// until unpin is called with the returned epoch.
+func (t *tree[K, V]) pin() int {
+	r := t.reclaim
+	if r == nil {
+		return 0
+	}
+	r.lock.Lock()
+	e := r.epoch
+	r.readers[e]++
+	r.lock.Unlock()
+	return e
+}
+
+func (t *tree[K, V]) unpin(epoch int) {
+	r := t.reclaim
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	r.readers[epoch]--
+	free := r.advance()
+	r.lock.Unlock()
+	t.recycle(free...)
+}
+
+// retire adds n to the current epoch and returns all nodes that can be
+// recycled.
+func (r *reclaimer[K, V]) retire(n *node[K, V]) []*node[K, V] {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.retired[r.epoch] = append(r.retired[r.epoch], n)
+	return r.advance()
+}
+
+// advance moves on to the next epoch for as long as no reader of the previous
+// one is left and returns the nodes retired in it. The lock must be held by
+// the caller.
+func (r *reclaimer[K, V]) advance() []*node[K, V] {
+	var free []*node[K, V]
+	for i := 0; i < 2; i++ {
+		prev := 1 - r.epoch
+		if r.readers[prev] > 0 {
+			break
+		}
+		free = append(free, r.retired[prev]...)
+		r.retired[prev] = nil
+		r.epoch = prev
+	}
+	return free
+}
+
+// Detached is a subtree that has been removed from a trie by Detach. It is not
+// safe for concurrent use.
+type Detached[V any] struct {
+	t *stringTrie[V]
+	// v points to the detached node, which may be an implicit node within
+	// the run of n if the node has been detached from the middle of a run.
+	v view[string, V]
+}
+
+// Detach removes the node at path including all of its children from t just
+// like Delete, but instead of discarding them returns them as a Detached
+// subtree. If t uses WithPooling, the nodes are only reused once Release has
+// been called. Detach only supports tries created by New, path must not be
+// empty. found is false if the node does not exist.
+func Detach[V any](t String[V], path string) (d *Detached[V], found bool, err error) {
+	st, ok := t.(*stringTrie[V])
+	if !ok {
+		return nil, false, fmt.Errorf("trie: cannot detach from %T", t)
+	}
+	segments := st.segments(st.normalize(path))
+	if len(segments) == 0 {
+		return nil, false, errors.New("trie: cannot detach the root")
+	}
+
+	n, removed := st.detach(segments)
+	if n == nil {
+		return nil, false, nil
+	}
+	if st.removed != nil {
+		st.visitRemoved(removed, n)
+	}
+	return &Detached[V]{t: st, v: view[string, V]{run: removed[len(segments):], n: n}}, true, nil
+}
+
+// Walk calls fn for every path of the subtree that has a value, in the order
+// of the Walk of the trie it has been detached from. Paths are relative to the
+// root of the subtree. Walk does nothing once the subtree has been released.
+func (d *Detached[V]) Walk(fn func(path string, value V) bool) {
+	if d.v.n == nil {
+		return
+	}
+	d.t.walk(d.v, "", true, fn)
+}
+
+// Release returns the nodes of the subtree to the pool of the trie it has been
+// detached from, if it uses WithPooling. The subtree must not be used
+// afterwards.
+func (d *Detached[V]) Release() {
+	if d.v.n == nil {
+		return
+	}
+	d.t.release(d.v.n)
+	d.v = view[string, V]{}
+}
@@ -0,0 +1,12 @@
+module moehl.dev/trie/proto
+
+go 1.21.4
+
+require (
+	google.golang.org/protobuf v1.34.2
+	moehl.dev/trie v0.0.0
+)
+
+require golang.org/x/text v0.14.0 // indirect
+
+replace moehl.dev/trie => ../
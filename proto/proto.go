@@ -0,0 +1,144 @@
+// Package proto converts tries to and from the Protocol Buffers message
+// Trie defined in trie.proto, so tries can be exchanged with services written
+// in other languages. It lives in its own module to avoid forcing the protobuf
+// dependency on users of the trie package.
+package proto
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"moehl.dev/trie"
+)
+
+// Field numbers as defined in trie.proto.
+const (
+	trieDelimiter protowire.Number = 1
+	trieEntries   protowire.Number = 2
+
+	entryPath  protowire.Number = 1
+	entryValue protowire.Number = 2
+)
+
+var errInvalid = errors.New("proto: invalid message")
+
+// ToProto returns the wire encoding of a Trie message containing all paths of
+// t that have a value set. Values are encoded using the codec of t.
+func ToProto[V any](t trie.String[V]) ([]byte, error) {
+	codec := t.Codec()
+
+	var b []byte
+	b = protowire.AppendTag(b, trieDelimiter, protowire.BytesType)
+	b = protowire.AppendString(b, t.Delimiter())
+
+	var err error
+	t.Walk(func(path string, value V) bool {
+		var v []byte
+		v, err = codec.Encode(value)
+		if err != nil {
+			err = fmt.Errorf("proto: encode value: %w", err)
+			return false
+		}
+
+		var entry []byte
+		entry = protowire.AppendTag(entry, entryPath, protowire.BytesType)
+		entry = protowire.AppendString(entry, path)
+		entry = protowire.AppendTag(entry, entryValue, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, v)
+
+		b = protowire.AppendTag(b, trieEntries, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// FromProto builds a new trie from the wire encoding of a Trie message.
+// Values are decoded using codec which is also used by the returned trie. If
+// codec is nil, trie.DefaultCodec is used. The trie is created with opts like
+// FromMap, the options that affect the paths, e.g. trie.WithEscape, must match
+// the ones of the trie passed to ToProto for the paths to round-trip.
+func FromProto[V any](b []byte, codec trie.ValueCodec[V], opts ...trie.Option) (trie.String[V], error) {
+	if codec == nil {
+		codec = trie.DefaultCodec[V]{}
+	}
+
+	var (
+		delimiter string
+		entries   [][]byte
+	)
+
+	err := fields(b, func(num protowire.Number, v []byte) {
+		switch num {
+		case trieDelimiter:
+			delimiter = string(v)
+		case trieEntries:
+			entries = append(entries, v)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t := trie.New[V](delimiter, append(opts[:len(opts):len(opts)], trie.WithCodec(codec))...)
+	for _, entry := range entries {
+		var path string
+		var value []byte
+		err = fields(entry, func(num protowire.Number, v []byte) {
+			switch num {
+			case entryPath:
+				path = string(v)
+			case entryValue:
+				value = v
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := codec.Decode(value)
+		if err != nil {
+			return nil, fmt.Errorf("proto: decode value: %w", err)
+		}
+		t.Put(path, v)
+	}
+
+	return t, nil
+}
+
+// fields calls fn for every length-delimited field in b. Fields of other types
+// are skipped as unknown fields.
+func fields(b []byte, fn func(protowire.Number, []byte)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("%w: %v", errInvalid, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return fmt.Errorf("%w: %v", errInvalid, protowire.ParseError(n))
+			}
+			b = b[n:]
+			continue
+		}
+
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return fmt.Errorf("%w: %v", errInvalid, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		fn(num, v)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package proto_test
+
+import (
+	"testing"
+
+	"moehl.dev/trie"
+	"moehl.dev/trie/proto"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar", 1)
+	tr.Put("qux", 2)
+
+	b, err := proto.ToProto(tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := proto.FromProto[int](b, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for key, value := range map[string]int{"foo/bar": 1, "qux": 2} {
+		gotValue, ok := got.Get(key)
+		if !ok || gotValue != value {
+			t.Errorf("expected value to be '%v' but got '%v'", value, gotValue)
+		}
+	}
+}
+
+func TestRoundTripEscaped(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithEscape('\\'))
+	tr.PutSegments(1, "foo/bar", "baz")
+
+	b, err := proto.ToProto(tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := proto.FromProto[int](b, nil, trie.WithEscape('\\'))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotValue, ok := got.GetSegments("foo/bar", "baz"); !ok || gotValue != 1 {
+		t.Errorf("expected value to be '1' but got '%v'", gotValue)
+	}
+	if _, ok := got.GetSegments("foo", "bar", "baz"); ok {
+		t.Errorf("expected foo/bar/baz to not be found")
+	}
+}
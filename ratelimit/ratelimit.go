@@ -0,0 +1,129 @@
+// Package ratelimit implements rate limits for hierarchical keys such as
+// tenant/api/endpoint. Limits can be configured at any level, a request is
+// counted against the most specific limit configured for its path. Each limit
+// is a token bucket stored as the value of its node in a String trie, so all
+// paths below a node without a limit of their own share its bucket.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"moehl.dev/trie"
+)
+
+// Limit allows Rate requests per second on average and bursts of up to Burst
+// requests.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limiter enforces the limits of all paths. A Limiter is safe for concurrent
+// use.
+type Limiter struct {
+	clock func() time.Time
+
+	// lock serializes changes to the limits, buckets have locks of their
+	// own for the requests.
+	lock    sync.Mutex
+	buckets trie.String[*bucket]
+}
+
+type bucket struct {
+	lock   sync.Mutex
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// New creates a Limiter without any limits for paths delimited by delimiter.
+// If clock is nil, time.Now is used.
+func New(delimiter string, clock func() time.Time) *Limiter {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &Limiter{clock: clock, buckets: trie.New[*bucket](delimiter)}
+}
+
+// Set the limit of path. A new bucket starts full, the tokens of an existing
+// one are kept but capped at the new burst.
+func (l *Limiter) Set(path string, limit Limit) {
+	l.Update(path, func(Limit, bool) Limit { return limit })
+}
+
+// Update replaces the limit of path with the result of fn, which is called
+// with the current limit and whether there is one. Concurrent calls of Set,
+// Update and Remove are serialized and requests never see a partially
+// updated limit.
+func (l *Limiter) Update(path string, fn func(old Limit, ok bool) Limit) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	b, _ := l.buckets.Get(path)
+	if b == nil {
+		limit := fn(Limit{}, false)
+		l.buckets.Put(path, &bucket{limit: limit, tokens: float64(limit.Burst), last: l.clock()})
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.limit = fn(b.limit, true)
+	b.tokens = min(b.tokens, float64(b.limit.Burst))
+}
+
+// Remove the limit of path, requests below it are counted against the limit
+// of the nearest parent instead. Limits of paths below path are kept.
+func (l *Limiter) Remove(path string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if b, _ := l.buckets.Get(path); b == nil {
+		return
+	}
+	if children, _ := l.buckets.Children(path); len(children) > 0 {
+		l.buckets.Put(path, nil)
+		return
+	}
+	l.buckets.Delete(path)
+}
+
+// Allow reports whether a request for path is allowed and, if so, takes a
+// token from the bucket of the most specific limit. Requests for paths
+// without any limit are always allowed.
+func (l *Limiter) Allow(path string) bool {
+	return l.AllowN(path, 1)
+}
+
+// AllowN is like Allow for n requests at once.
+func (l *Limiter) AllowN(path string, n int) bool {
+	var b *bucket
+	l.buckets.WalkPath(path, func(_ string, v *bucket) bool {
+		if v != nil {
+			b = v
+		}
+		return true
+	})
+	if b == nil {
+		return true
+	}
+	return b.take(l.clock(), float64(n))
+}
+
+// take refills the bucket for the time passed since the last request and
+// takes n tokens if there are enough.
+func (b *bucket) take(now time.Time, n float64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(b.limit.Burst), b.tokens+elapsed.Seconds()*b.limit.Rate)
+		b.last = now
+	}
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
@@ -0,0 +1,61 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"moehl.dev/trie/ratelimit"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := ratelimit.New("/", func() time.Time { return now })
+	l.Set("acme", ratelimit.Limit{Rate: 1, Burst: 3})
+	l.Set("acme/api/search", ratelimit.Limit{Rate: 10, Burst: 1})
+
+	allow := func(path string, n int) int {
+		allowed := 0
+		for i := 0; i < n; i++ {
+			if l.Allow(path) {
+				allowed++
+			}
+		}
+		return allowed
+	}
+
+	// acme/api and acme/web share the bucket of acme.
+	if got := allow("acme/api/users", 2) + allow("acme/web", 2); got != 3 {
+		t.Errorf("expected 3 requests to be allowed but got %d", got)
+	}
+	if got := allow("acme/api/search/x", 2); got != 1 {
+		t.Errorf("expected 1 request to be allowed but got %d", got)
+	}
+	if got := allow("other", 100); got != 100 {
+		t.Errorf("expected requests without a limit to be allowed but got %d", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	if got := allow("acme", 5); got != 2 {
+		t.Errorf("expected 2 refilled tokens but got %d", got)
+	}
+
+	l.Update("acme", func(old ratelimit.Limit, ok bool) ratelimit.Limit {
+		if !ok || old.Burst != 3 {
+			t.Errorf("expected the old limit but got %+v, %v", old, ok)
+		}
+		old.Rate = 100
+		return old
+	})
+	now = now.Add(time.Second)
+	if got := allow("acme", 5); got != 3 {
+		t.Errorf("expected 3 requests to be allowed with the new rate but got %d", got)
+	}
+
+	l.Remove("acme")
+	if got := allow("acme/web", 10); got != 10 {
+		t.Errorf("expected acme/web to be unlimited but got %d", got)
+	}
+	if got := allow("acme/api/search", 2); got != 1 {
+		t.Errorf("expected the limit of acme/api/search to be kept but got %d", got)
+	}
+}
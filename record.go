@@ -0,0 +1,315 @@
+package trie
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The recording written by a Recorder consists of records of the following
+// form, strings are encoded like in the binary format:
+//
+//	record:  byte op | uvarint unix nanoseconds | uvarint goroutine | args
+//	put:     'P' | string path | string value
+//	delete:  'D' | string path
+//	clear:   'C'
+
+// RecordOp is the operation of a Record.
+type RecordOp byte
+
+const (
+	RecordPut    RecordOp = 'P'
+	RecordDelete RecordOp = 'D'
+	RecordClear  RecordOp = 'C'
+)
+
+func (op RecordOp) String() string {
+	switch op {
+	case RecordPut:
+		return "Put"
+	case RecordDelete:
+		return "Delete"
+	case RecordClear:
+		return "Clear"
+	default:
+		return fmt.Sprintf("RecordOp(%q)", byte(op))
+	}
+}
+
+// Record is a mutation captured by a Recorder.
+type Record[V any] struct {
+	Op RecordOp
+	// Time at which the mutation has been applied.
+	Time time.Time
+	// Goroutine is the ID of the goroutine that applied the mutation, as
+	// printed in stack traces.
+	Goroutine uint64
+	// Path is empty for RecordClear.
+	Path string
+	// Value is only set for RecordPut.
+	Value V
+}
+
+func (r Record[V]) String() string {
+	prefix := fmt.Sprintf("%s goroutine %d %s", r.Time.UTC().Format(time.RFC3339Nano), r.Goroutine, r.Op)
+	switch r.Op {
+	case RecordPut:
+		return fmt.Sprintf("%s %q %v", prefix, r.Path, r.Value)
+	case RecordDelete:
+		return fmt.Sprintf("%s %q", prefix, r.Path)
+	default:
+		return prefix
+	}
+}
+
+// Recorder wraps a String trie and writes a record of every mutation to a
+// log, including the time and the goroutine it has been made by. Unlike the
+// log of a WAL, the recording is meant to debug how a trie reached its state:
+// the records can be inspected with ReadRecords and replayed up to any point
+// with Replay. Mutations are applied in the order of their records.
+//
+// Mutations that cannot be recorded are not applied to the trie, the first
+// error that occurred is returned by Err.
+type Recorder[V any] struct {
+	String[V]
+
+	lock *sync.Mutex
+	w    io.Writer
+	err  error
+}
+
+// NewRecorder wraps t and records every mutation to w.
+func NewRecorder[V any](t String[V], w io.Writer) *Recorder[V] {
+	return &Recorder[V]{
+		String: t,
+		lock:   new(sync.Mutex),
+		w:      w,
+	}
+}
+
+// Err returns the first error that occurred while writing to the log.
+func (r *Recorder[V]) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+func (r *Recorder[V]) Put(path string, value V) {
+	if r.CheckPath(path) != nil {
+		return
+	}
+
+	v, err := r.Codec().Encode(value)
+	if err != nil {
+		r.lock.Lock()
+		r.setErr(fmt.Errorf("trie: encode value: %w", err))
+		r.lock.Unlock()
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.record(RecordPut, []byte(path), v) {
+		r.String.Put(path, value)
+	}
+}
+
+// PutSegments is recorded as a Put of the joined path.
+func (r *Recorder[V]) PutSegments(value V, segments ...string) {
+	r.Put(joinPath(r.String, segments), value)
+}
+
+// PutE is like Put but returns the error of the key validator if the path is
+// rejected.
+func (r *Recorder[V]) PutE(path string, value V) error {
+	err := r.CheckPath(path)
+	if err != nil {
+		return err
+	}
+	r.Put(path, value)
+	return nil
+}
+
+func (r *Recorder[V]) Delete(path string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.record(RecordDelete, []byte(path)) {
+		r.String.Delete(path)
+	}
+}
+
+// DeleteE is like Delete but returns an error wrapping ErrNotFound if the
+// node does not exist.
+func (r *Recorder[V]) DeleteE(path string) error {
+	_, err := r.GetE(path)
+	if err != nil {
+		return err
+	}
+	r.Delete(path)
+	return nil
+}
+
+// Root returns a handle to the root of the trie, its Set is recorded just
+// like Put.
+func (r *Recorder[V]) Root() Node[V] {
+	return rootWithPut(r.String, r.Put)
+}
+
+func (r *Recorder[V]) Clear() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.record(RecordClear) {
+		r.String.Clear()
+	}
+}
+
+// Rekey is applied as a sequence of recorded Delete and Put operations, unlike
+// the Rekey of the String trie it is not atomic.
+func (r *Recorder[V]) Rekey(fn func(oldPath string) (newPath string, keep bool)) error {
+	return rekeyEach[V](r, fn)
+}
+
+// ReadFrom reads a trie in the binary format and puts all of its values into
+// the recorder one by one.
+func (r *Recorder[V]) ReadFrom(rd io.Reader) (int64, error) {
+	t := newStringTrie[V](r.Delimiter())
+	t.codec = r.Codec()
+	n, err := t.ReadFrom(rd)
+	if err != nil {
+		return n, err
+	}
+
+	t.Walk(func(path string, value V) bool {
+		r.Put(path, value)
+		return true
+	})
+	return n, nil
+}
+
+// Replay applies all records read from log to the trie without recording
+// them. If the last record is incomplete, all previous records are applied
+// and io.ErrUnexpectedEOF is returned.
+func (r *Recorder[V]) Replay(log io.Reader) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return ReadRecords(log, r.Codec(), func(rec Record[V]) bool {
+		switch rec.Op {
+		case RecordPut:
+			r.String.Put(rec.Path, rec.Value)
+		case RecordDelete:
+			r.String.Delete(rec.Path)
+		case RecordClear:
+			r.String.Clear()
+		}
+		return true
+	})
+}
+
+// ReadRecords calls fn for every record read from log until fn returns false.
+// Values are decoded with codec, nil selects the default codec. If the last
+// record is incomplete, io.ErrUnexpectedEOF is returned after fn has been
+// called for all previous records.
+func ReadRecords[V any](log io.Reader, codec ValueCodec[V], fn func(Record[V]) bool) error {
+	codec = codecOrDefault(codec)
+	br := &binaryReader{r: bufio.NewReader(log)}
+	for {
+		op, err := br.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rec := Record[V]{Op: RecordOp(op)}
+		nanos, err := br.readUvarint()
+		if err != nil {
+			return err
+		}
+		rec.Time = time.Unix(0, int64(nanos))
+		rec.Goroutine, err = br.readUvarint()
+		if err != nil {
+			return err
+		}
+
+		switch rec.Op {
+		case RecordPut:
+			path, err := br.readBytes()
+			if err != nil {
+				return err
+			}
+			b, err := br.readBytes()
+			if err != nil {
+				return err
+			}
+			rec.Path = string(path)
+			rec.Value, err = codec.Decode(b)
+			if err != nil {
+				return fmt.Errorf("trie: decode value: %w", err)
+			}
+		case RecordDelete:
+			path, err := br.readBytes()
+			if err != nil {
+				return err
+			}
+			rec.Path = string(path)
+		case RecordClear:
+		default:
+			return fmt.Errorf("%w: unknown record %x", ErrInvalidFormat, op)
+		}
+
+		if !fn(rec) {
+			return nil
+		}
+	}
+}
+
+// record writes a single record to the log and reports whether it was
+// successful. The lock must be held by the caller.
+func (r *Recorder[V]) record(op RecordOp, args ...[]byte) bool {
+	var buf bytes.Buffer
+	bw := &binaryWriter{w: bufio.NewWriter(&buf)}
+	_, _ = bw.Write([]byte{byte(op)})
+	_ = bw.writeUvarint(uint64(time.Now().UnixNano()))
+	_ = bw.writeUvarint(goroutineID())
+	for _, arg := range args {
+		_ = bw.writeBytes(arg)
+	}
+	_ = bw.w.Flush()
+
+	_, err := r.w.Write(buf.Bytes())
+	if err != nil {
+		r.setErr(err)
+		return false
+	}
+	return true
+}
+
+func (r *Recorder[V]) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// goroutineID returns the ID of the calling goroutine, which the runtime only
+// exposes in stack traces starting with "goroutine <id> [".
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
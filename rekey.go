@@ -0,0 +1,162 @@
+package trie
+
+import (
+	"fmt"
+)
+
+func (t *stringTrie[V]) Rekey(fn func(oldPath string) (newPath string, keep bool)) error {
+	span := t.startSpan("trie.Rekey")
+	defer span.End()
+
+	// The suffix index is locked first like in insert.
+	if t.suffix != nil {
+		t.suffix.lock.Lock()
+	}
+	t.lockCounts()
+
+	// Holding the lock of the root keeps all other operations out of the
+	// trie. Operations that have already passed the root finish before the
+	// walk reaches the nodes they have locked.
+	t.root.lock.Lock()
+	old := &node[string, V]{
+		children: t.root.children,
+		value:    t.root.value,
+		hasValue: t.root.hasValue,
+	}
+
+	aside := tree[string, V]{root: &node[string, V]{}, pool: t.pool, arena: t.arena}
+	previous := tree[string, V]{root: old}
+	var (
+		moved   [][]string
+		dropped []string
+		values  []V
+		sources = make(map[string]string)
+		err     error
+	)
+	t.walk(view[string, V]{n: old}, "", true, func(path string, value V) bool {
+		newPath, keep := fn(path)
+		if !keep {
+			dropped = append(dropped, path)
+			values = append(values, value)
+			return true
+		}
+
+		segments := t.segments(t.normalize(newPath))
+		err = t.checkSegments(segments)
+		if err != nil {
+			return false
+		}
+		key := t.joinSegments(segments)
+		if source, ok := sources[key]; ok {
+			err = fmt.Errorf("trie: rekey: %q and %q are both moved to %q", source, path, key)
+			return false
+		}
+		sources[key] = path
+
+		aside.put(segments, value, true)
+		if t.now != nil {
+			// Moved values keep their timestamps.
+			if meta, ok := previous.meta(t.segments(path)); ok {
+				aside.setMeta(segments, meta)
+			}
+		}
+		moved = append(moved, segments)
+		return true
+	})
+	if err != nil {
+		t.root.lock.Unlock()
+		t.unlockCounts()
+		t.unlockSuffix()
+		return err
+	}
+	span.SetAttribute("trie.moved", len(moved))
+	span.SetAttribute("trie.removed", len(dropped))
+	if t.debugEnabled() {
+		t.logger.Debug("trie: rekeyed", "moved", len(moved), "removed", len(dropped))
+	}
+
+	t.root.children = aside.root.children
+	t.root.value = aside.root.value
+	t.root.hasValue = aside.root.hasValue
+	t.root.meta = aside.root.meta
+	if t.bloom != nil {
+		t.bloom.reset()
+		for _, segments := range moved {
+			t.addToBloom(segments)
+		}
+	}
+	if t.cache != nil {
+		t.cache.purge()
+	}
+	if t.suffix != nil {
+		t.suffix.keys.clear()
+		for _, segments := range moved {
+			t.suffix.keys.put(reversed(segments), struct{}{}, true)
+		}
+	}
+	t.root.lock.Unlock()
+	if t.counts != nil {
+		t.recount(t.root)
+	}
+	t.unlockCounts()
+	t.unlockSuffix()
+
+	t.release(old)
+	if t.removed != nil {
+		for i, path := range dropped {
+			t.removed(t.segments(path), values[i])
+		}
+	}
+	return nil
+}
+
+// pathTrie contains the methods of String used by rekeyEach, it is implemented
+// by all wrappers.
+type pathTrie[V any] interface {
+	Put(path string, value V)
+	CheckPath(path string) error
+	Delete(path string)
+	Walk(fn func(path string, value V) bool)
+}
+
+// rekeyEach implements Rekey for wrappers by deleting all values and putting
+// them again at their new paths, so that every change goes through t. Unlike
+// the Rekey of the String trie it is not atomic.
+func rekeyEach[V any](t pathTrie[V], fn func(oldPath string) (newPath string, keep bool)) error {
+	var (
+		paths   []string
+		entries = make(map[string]V)
+		sources = make(map[string]string)
+		err     error
+	)
+	t.Walk(func(path string, value V) bool {
+		paths = append(paths, path)
+		newPath, keep := fn(path)
+		if !keep {
+			return true
+		}
+
+		err = t.CheckPath(newPath)
+		if err != nil {
+			return false
+		}
+		if source, ok := sources[newPath]; ok {
+			err = fmt.Errorf("trie: rekey: %q and %q are both moved to %q", source, path, newPath)
+			return false
+		}
+		sources[newPath] = path
+		entries[newPath] = value
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		t.Delete(path)
+	}
+	for path, value := range entries {
+		t.Put(path, value)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Package rollup aggregates measurements by hierarchical labels such as
+// region/cluster/service. Every node of a String trie holds the aggregate of
+// all measurements recorded at or below it, so the usage of any subtree can be
+// queried without scanning the measurements.
+package rollup
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"moehl.dev/trie"
+)
+
+// relativeAccuracy is the maximum relative error of the quantiles returned by
+// Summary.Quantile.
+const relativeAccuracy = 0.01
+
+// Rollup contains the aggregates of all nodes. It is safe for concurrent use.
+type Rollup struct {
+	delimiter string
+
+	// lock serializes the creation of nodes, aggregates have locks of
+	// their own.
+	lock  sync.Mutex
+	nodes trie.String[*aggregate]
+}
+
+// New creates an empty Rollup for labels delimited by delimiter.
+func New(delimiter string) *Rollup {
+	r := &Rollup{delimiter: delimiter, nodes: trie.New[*aggregate](delimiter)}
+	r.nodes.Put("", newAggregate())
+	return r
+}
+
+// Record a measurement for path. It is added to the aggregates of the root,
+// of path and of every node in between.
+func (r *Rollup) Record(path string, value float64) {
+	r.aggregate("").add(value)
+	if path == "" {
+		return
+	}
+	for i := 0; ; {
+		j := strings.Index(path[i:], r.delimiter)
+		if j < 0 {
+			break
+		}
+		r.aggregate(path[:i+j]).add(value)
+		i += j + len(r.delimiter)
+	}
+	r.aggregate(path).add(value)
+}
+
+// aggregate returns the aggregate of path, creating it if needed.
+func (r *Rollup) aggregate(path string) *aggregate {
+	if a, _ := r.nodes.Get(path); a != nil {
+		return a
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if a, _ := r.nodes.Get(path); a != nil {
+		return a
+	}
+	a := newAggregate()
+	r.nodes.Put(path, a)
+	return a
+}
+
+// Query returns the summary of all measurements recorded at or below path.
+// found is false if no measurement has been recorded there.
+func (r *Rollup) Query(path string) (s Summary, found bool) {
+	a, _ := r.nodes.Get(path)
+	if a == nil {
+		return s, false
+	}
+	s = a.summary()
+	return s, s.Count > 0
+}
+
+// Children returns the labels directly below path for which measurements
+// have been recorded, to drill down into a subtree.
+func (r *Rollup) Children(path string) []string {
+	children, _ := r.nodes.Children(path)
+	return children
+}
+
+// Delete removes the aggregates of path and all nodes below it. The
+// measurements remain part of the aggregates of the parents of path.
+func (r *Rollup) Delete(path string) {
+	if path == "" {
+		r.nodes.Clear()
+		r.nodes.Put("", newAggregate())
+		return
+	}
+	r.nodes.Delete(path)
+}
+
+// Summary of the measurements of a node.
+type Summary struct {
+	Count         uint64
+	Sum, Min, Max float64
+	sketch        sketch
+}
+
+// Mean returns the average of the measurements.
+func (s Summary) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// Quantile returns an estimate of the q-quantile of the measurements, e.g.
+// 0.99 for the 99th percentile, with a relative error of at most 1%.
+func (s Summary) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	v := s.sketch.quantile(q, s.Count)
+	// The estimate of a bucket may lie outside of the observed range.
+	return min(max(v, s.Min), s.Max)
+}
+
+type aggregate struct {
+	lock sync.Mutex
+	s    Summary
+}
+
+func newAggregate() *aggregate {
+	return &aggregate{s: Summary{sketch: sketch{buckets: make(map[int]uint64)}}}
+}
+
+func (a *aggregate) add(v float64) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.s.Count == 0 || v < a.s.Min {
+		a.s.Min = v
+	}
+	if a.s.Count == 0 || v > a.s.Max {
+		a.s.Max = v
+	}
+	a.s.Count++
+	a.s.Sum += v
+	a.s.sketch.add(v)
+}
+
+func (a *aggregate) summary() Summary {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	s := a.s
+	s.sketch = a.s.sketch.clone()
+	return s
+}
+
+// sketch is a histogram with logarithmically sized buckets, like DDSketch.
+// The bucket with index i contains the values in (gamma^(i-1), gamma^i], so
+// every value is within the relative accuracy of the middle of its bucket.
+// Negative values are kept in buckets of their own and zeros are counted.
+type sketch struct {
+	buckets  map[int]uint64
+	negative map[int]uint64
+	zeros    uint64
+}
+
+var gamma = (1 + relativeAccuracy) / (1 - relativeAccuracy)
+
+func bucketIndex(v float64) int {
+	return int(math.Ceil(math.Log(v) / math.Log(gamma)))
+}
+
+func bucketValue(i int) float64 {
+	return 2 * math.Pow(gamma, float64(i)) / (gamma + 1)
+}
+
+func (s *sketch) add(v float64) {
+	switch {
+	case v > 0:
+		s.buckets[bucketIndex(v)]++
+	case v < 0:
+		if s.negative == nil {
+			s.negative = make(map[int]uint64)
+		}
+		s.negative[bucketIndex(-v)]++
+	default:
+		s.zeros++
+	}
+}
+
+func (s sketch) clone() sketch {
+	c := sketch{buckets: make(map[int]uint64, len(s.buckets)), zeros: s.zeros}
+	for i, n := range s.buckets {
+		c.buckets[i] = n
+	}
+	if s.negative != nil {
+		c.negative = make(map[int]uint64, len(s.negative))
+		for i, n := range s.negative {
+			c.negative[i] = n
+		}
+	}
+	return c
+}
+
+// quantile returns the value of the bucket containing the value with rank
+// q*(count-1) in ascending order.
+func (s sketch) quantile(q float64, count uint64) float64 {
+	rank := uint64(min(max(q, 0), 1) * float64(count-1))
+
+	// Negative values in ascending order are the buckets with the highest
+	// indices first.
+	neg := sortedIndices(s.negative)
+	for i := len(neg) - 1; i >= 0; i-- {
+		n := s.negative[neg[i]]
+		if rank < n {
+			return -bucketValue(neg[i])
+		}
+		rank -= n
+	}
+	if rank < s.zeros {
+		return 0
+	}
+	rank -= s.zeros
+	var last float64
+	for _, i := range sortedIndices(s.buckets) {
+		n := s.buckets[i]
+		last = bucketValue(i)
+		if rank < n {
+			return last
+		}
+		rank -= n
+	}
+	return last
+}
+
+func sortedIndices(buckets map[int]uint64) []int {
+	indices := make([]int, 0, len(buckets))
+	for i := range buckets {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices
+}
@@ -0,0 +1,64 @@
+package rollup_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"moehl.dev/trie/rollup"
+)
+
+func TestRollup(t *testing.T) {
+	r := rollup.New("/")
+	for i := 1; i <= 1000; i++ {
+		r.Record("eu/fra/api", float64(i))
+	}
+	r.Record("eu/ams/db", 5000)
+	r.Record("us/db", -10)
+
+	for _, tc := range []struct {
+		path       string
+		count      uint64
+		sum        float64
+		minV, maxV float64
+	}{
+		{"", 1002, 500500 + 5000 - 10, -10, 5000},
+		{"eu", 1001, 505500, 1, 5000},
+		{"eu/fra", 1000, 500500, 1, 1000},
+		{"eu/fra/api", 1000, 500500, 1, 1000},
+		{"us/db", 1, -10, -10, -10},
+	} {
+		s, found := r.Query(tc.path)
+		if !found || s.Count != tc.count || s.Sum != tc.sum || s.Min != tc.minV || s.Max != tc.maxV {
+			t.Errorf("%q: expected %d, %v, %v, %v but got %+v", tc.path, tc.count, tc.sum, tc.minV, tc.maxV, s)
+		}
+	}
+
+	s, _ := r.Query("eu/fra")
+	if mean := s.Mean(); mean != 500.5 {
+		t.Errorf("expected a mean of 500.5 but got %v", mean)
+	}
+	for q, expected := range map[float64]float64{0.5: 500, 0.99: 990, 1: 1000, 0: 1} {
+		if got := s.Quantile(q); math.Abs(got-expected) > expected*0.01+1 {
+			t.Errorf("q%v: expected about %v but got %v", q, expected, got)
+		}
+	}
+	if s, _ := r.Query(""); s.Quantile(0) != -10 {
+		t.Errorf("expected the minimum to be -10 but got %v", s.Quantile(0))
+	}
+
+	if got := r.Children("eu"); !reflect.DeepEqual(got, []string{"ams", "fra"}) {
+		t.Errorf("expected [ams fra] but got %v", got)
+	}
+	if _, found := r.Query("asia"); found {
+		t.Errorf("expected asia to not be found")
+	}
+
+	r.Delete("eu/fra")
+	if _, found := r.Query("eu/fra/api"); found {
+		t.Errorf("expected eu/fra/api to be deleted")
+	}
+	if s, _ := r.Query("eu"); s.Count != 1001 {
+		t.Errorf("expected the aggregate of eu to be kept but got %d", s.Count)
+	}
+}
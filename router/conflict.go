@@ -0,0 +1,66 @@
+package router
+
+import (
+	"fmt"
+)
+
+// ConflictError is returned by Handle if a template conflicts with a template
+// that has already been registered for the same method.
+type ConflictError struct {
+	Method string
+	// Pattern is the template that has been rejected.
+	Pattern string
+	// Existing is the template it conflicts with.
+	Existing string
+	// Overlap is true if the templates are not equivalent but there are
+	// paths matching both, this is only reported by strict routers.
+	Overlap bool
+}
+
+func (e *ConflictError) Error() string {
+	if e.Overlap {
+		return fmt.Sprintf("router: %s %s overlaps with %s", e.Method, e.Pattern, e.Existing)
+	}
+	return fmt.Sprintf("router: %s %s conflicts with %s", e.Method, e.Pattern, e.Existing)
+}
+
+// checkConflicts returns a *ConflictError if the template with the given key
+// conflicts with a route registered for method. The lock must be held by the
+// caller.
+func (r *Router) checkConflicts(method, pattern, key string) error {
+	if e, _ := r.routes.Get(key); e != nil {
+		if rt, ok := e.routes[method]; ok {
+			return &ConflictError{Method: method, Pattern: pattern, Existing: rt.pattern}
+		}
+	}
+	if !r.Strict {
+		return nil
+	}
+
+	var err error
+	segments := splitPath(key)
+	r.routes.Walk(func(path string, e *endpoint) bool {
+		rt, ok := e.routes[method]
+		if ok && path != key && overlap(segments, splitPath(path)) {
+			err = &ConflictError{Method: method, Pattern: pattern, Existing: rt.pattern, Overlap: true}
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// overlap returns whether there is a path that matches the templates with the
+// segments a and b, which are keys as returned by parsePattern.
+func overlap(a, b []string) bool {
+	for i := 0; ; i++ {
+		switch {
+		case i < len(a) && a[i] == catchAllKey, i < len(b) && b[i] == catchAllKey:
+			return true
+		case i == len(a) || i == len(b):
+			return len(a) == len(b)
+		case a[i] != b[i] && a[i] != paramKey && b[i] != paramKey:
+			return false
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// RouteFormat is the output format of ExportRoutes.
+type RouteFormat int
+
+const (
+	// Text writes one line per route containing the method and the
+	// pattern, prefixed by the host pattern for routes of host routers.
+	Text RouteFormat = iota
+	// JSON writes an array of objects with the fields method, pattern and,
+	// for routes of host routers, host.
+	JSON
+	// OpenAPI writes an OpenAPI 3 document containing a stub operation for
+	// each route. Parameters and catch-alls become path parameters, routes
+	// of host routers are not included since a path can only be described
+	// once per document.
+	OpenAPI
+)
+
+// exportedRoute is a route as written by ExportRoutes.
+type exportedRoute struct {
+	Host    string `json:"host,omitempty"`
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	params  []string
+}
+
+// ExportRoutes writes all registered routes to w in the given format, e.g. to
+// generate documentation or the configuration of a reverse proxy. Routes are
+// ordered by their key in the trie and then by method, the routes of host
+// routers follow the ones of r ordered by their host pattern.
+func (r *Router) ExportRoutes(w io.Writer, format RouteFormat) error {
+	switch format {
+	case Text:
+		for _, rt := range r.exportedRoutes(true) {
+			_, err := fmt.Fprintf(w, "%s %s%s\n", rt.Method, rt.Host, rt.Pattern)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case JSON:
+		routes := r.exportedRoutes(true)
+		if routes == nil {
+			routes = []exportedRoute{}
+		}
+		return writeJSON(w, routes)
+	case OpenAPI:
+		return writeJSON(w, openAPIDocument(r.exportedRoutes(false)))
+	default:
+		return fmt.Errorf("router: unknown route format %d", format)
+	}
+}
+
+// exportedRoutes returns the routes of r and, if hosts is set, of its host
+// routers.
+func (r *Router) exportedRoutes(hosts bool) []exportedRoute {
+	var routes []exportedRoute
+	r.routes.Walk(func(_ string, e *endpoint) bool {
+		methods := make([]string, 0, len(e.routes))
+		for m := range e.routes {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		for _, m := range methods {
+			rt := e.routes[m]
+			routes = append(routes, exportedRoute{
+				Method:  m,
+				Pattern: "/" + strings.Join(splitPath(rt.pattern), "/"),
+				params:  rt.params,
+			})
+		}
+		return true
+	})
+	if !hosts {
+		return routes
+	}
+
+	r.hosts.Walk(func(key string, h *Router) bool {
+		labels := strings.Split(key, ".")
+		reverse(labels)
+		host := strings.Join(labels, ".")
+		for _, rt := range h.exportedRoutes(false) {
+			rt.Host = host
+			routes = append(routes, rt)
+		}
+		return true
+	})
+	return routes
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type openAPIParameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+type openAPIOperation struct {
+	Parameters []openAPIParameter         `json:"parameters,omitempty"`
+	Responses  map[string]json.RawMessage `json:"responses"`
+}
+
+// openAPIDocument returns an OpenAPI document describing routes. Parameters
+// are written as {name}, the title and version of the document are left empty
+// to be filled in by the caller.
+func openAPIDocument(routes []exportedRoute) any {
+	paths := make(map[string]map[string]openAPIOperation)
+	for _, rt := range routes {
+		segments := splitPath(rt.Pattern)
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, paramKey) || strings.HasPrefix(segment, catchAllKey) {
+				segments[i] = "{" + segment[1:] + "}"
+			}
+		}
+		path := "/" + strings.Join(segments, "/")
+
+		op := openAPIOperation{
+			Responses: map[string]json.RawMessage{"default": json.RawMessage(`{"description":""}`)},
+		}
+		for _, name := range rt.params {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   map[string]string{"type": "string"},
+			})
+		}
+		if paths[path] == nil {
+			paths[path] = make(map[string]openAPIOperation)
+		}
+		paths[path][strings.ToLower(rt.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": "", "version": ""},
+		"paths":   paths,
+	}
+}
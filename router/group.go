@@ -0,0 +1,46 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group registers routes below a common path prefix which share a chain of
+// middleware. Middleware is applied when a route is registered, so only the
+// requests matching the routes of a group pass through its middleware.
+type Group struct {
+	r          *Router
+	prefix     string
+	middleware []func(http.Handler) http.Handler
+}
+
+// Group returns a group of routes below prefix wrapped by middleware. The first
+// middleware is the outermost one, i.e. it is called first. The prefix may
+// contain parameters but no catch-all.
+func (r *Router) Group(prefix string, middleware ...func(http.Handler) http.Handler) *Group {
+	return &Group{r: r, prefix: strings.TrimSuffix(prefix, "/"), middleware: middleware}
+}
+
+// Group returns a nested group whose prefix and middleware are appended to the
+// ones of g.
+func (g *Group) Group(prefix string, middleware ...func(http.Handler) http.Handler) *Group {
+	return &Group{
+		r:          g.r,
+		prefix:     g.prefix + strings.TrimSuffix(prefix, "/"),
+		middleware: append(g.middleware[:len(g.middleware):len(g.middleware)], middleware...),
+	}
+}
+
+// Handle registers handler wrapped by the middleware of the group for the
+// pattern appended to the prefix of the group, see Router.Handle.
+func (g *Group) Handle(method, pattern string, handler http.Handler) error {
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		handler = g.middleware[i](handler)
+	}
+	return g.r.Handle(method, g.prefix+pattern, handler)
+}
+
+// HandleFunc is like Handle but takes a handler function.
+func (g *Group) HandleFunc(method, pattern string, handler func(http.ResponseWriter, *http.Request)) error {
+	return g.Handle(method, pattern, http.HandlerFunc(handler))
+}
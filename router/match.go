@@ -0,0 +1,76 @@
+package router
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"moehl.dev/trie"
+)
+
+// Match is a route whose template matches a path, see LookupAll.
+type Match struct {
+	Method  string
+	Pattern string
+	Handler http.Handler
+	// Params contains the values the parameters of the pattern matched.
+	Params Params
+}
+
+// LookupAll returns every route of r whose template matches path, not just the
+// one a request would be dispatched to, e.g. to fan out an event to all
+// matching subscriptions. The matches are ordered by the precedence of their
+// templates, the first one is the route ServeHTTP would choose, and by method
+// for routes of the same template. Host routers are not considered.
+func (r *Router) LookupAll(path string) []Match {
+	var matches []Match
+	matchAll(r.routes.Root(), splitPath(path), nil, func(e *endpoint, values []string) {
+		methods := make([]string, 0, len(e.routes))
+		for m := range e.routes {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+
+		for _, m := range methods {
+			rt := e.routes[m]
+			var params Params
+			if len(rt.params) > 0 {
+				params = make(Params, len(rt.params))
+				for i, name := range rt.params {
+					params[i] = Param{Name: name, Value: values[i]}
+				}
+			}
+			matches = append(matches, Match{Method: m, Pattern: rt.pattern, Handler: rt.handler, Params: params})
+		}
+	})
+	return matches
+}
+
+// matchAll is like match but calls fn for every endpoint matching segments
+// below n in the order of their precedence.
+func matchAll(n trie.Node[*endpoint], segments []string, values []string, fn func(*endpoint, []string)) {
+	if len(segments) == 0 {
+		if e, ok := n.Value(); ok {
+			fn(e, values)
+		}
+		if child, ok := n.Child(catchAllKey); ok {
+			if e, ok := child.Value(); ok {
+				fn(e, append(values[:len(values):len(values)], ""))
+			}
+		}
+		return
+	}
+
+	static := segments[0] != paramKey && segments[0] != catchAllKey
+	if child, ok := n.Child(segments[0]); ok && static {
+		matchAll(child, segments[1:], values, fn)
+	}
+	if child, ok := n.Child(paramKey); ok {
+		matchAll(child, segments[1:], append(values[:len(values):len(values)], segments[0]), fn)
+	}
+	if child, ok := n.Child(catchAllKey); ok {
+		if e, ok := child.Value(); ok {
+			fn(e, append(values[:len(values):len(values)], strings.Join(segments, "/")))
+		}
+	}
+}
@@ -0,0 +1,365 @@
+// Package router implements an HTTP router on top of a String trie. Routes
+// are registered per method and path template, a template consists of static
+// segments, parameters written as :name which match a single segment, and a
+// final catch-all written as *name which matches the rest of the path.
+// Requests can be dispatched by host first, see Router.Host.
+package router
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"moehl.dev/trie"
+)
+
+// Keys under which parameters and catch-alls are stored in the trie. Static
+// segments starting with these characters cannot be registered.
+const (
+	paramKey    = ":"
+	catchAllKey = "*"
+)
+
+// Router is an http.Handler that dispatches requests to the handler that has
+// been registered for the method and path of the request. Static segments take
+// precedence over parameters, which take precedence over catch-alls. Leading
+// and trailing slashes are ignored.
+type Router struct {
+	// NotFound is called if no route matches the path of the request, it
+	// defaults to http.NotFound.
+	NotFound http.Handler
+	// MethodNotAllowed is called if a route matches the path but not the
+	// method of the request. The Allow header is set before it is called.
+	// It defaults to responding with status 405.
+	MethodNotAllowed http.Handler
+	// Strict makes Handle reject templates that overlap with a template
+	// registered for the same method, i.e. if there is a path matching
+	// both, instead of relying on the precedence of static segments. It is
+	// inherited by routers created by Host.
+	Strict bool
+
+	// lock serializes registrations, endpoints are never modified once they
+	// are stored in routes so requests are served without locking.
+	lock   sync.Mutex
+	routes trie.String[*endpoint]
+	// hosts contains the routers of all host patterns keyed by their labels
+	// in reverse order, hasHosts is set once the first one is added.
+	hosts    trie.String[*Router]
+	hasHosts atomic.Bool
+	// parent is the router a host router has been created by, its NotFound
+	// and MethodNotAllowed are used if the ones of the host router are nil.
+	parent *Router
+}
+
+// endpoint contains the routes of all methods registered for one template.
+type endpoint struct {
+	routes map[string]route
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+	// params contains the names of the parameters and the catch-all in the
+	// order they appear in the pattern.
+	params []string
+}
+
+// New returns an empty Router.
+func New() *Router {
+	return &Router{
+		routes: trie.New[*endpoint]("/", trie.WithTrimmedDelimiters()),
+		hosts:  trie.New[*Router]("."),
+	}
+}
+
+// Host returns the router for requests to hosts matching pattern, which is
+// created on first use. A pattern is either a host name or a host name whose
+// first label is a wildcard, e.g. *.example.com, which matches exactly one
+// label. Exact labels take precedence over wildcards. Requests to hosts
+// without a matching pattern are dispatched by the routes of r itself. Host
+// routers cannot have host routers of their own.
+func (r *Router) Host(pattern string) (*Router, error) {
+	if r.parent != nil {
+		return nil, fmt.Errorf("router: host router for %q cannot have host routers", pattern)
+	}
+	labels, err := parseHost(pattern)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join(labels, ".")
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if h, _ := r.hosts.Get(key); h != nil {
+		return h, nil
+	}
+	h := New()
+	h.parent = r
+	h.Strict = r.Strict
+	r.hosts.Put(key, h)
+	r.hasHosts.Store(true)
+	return h, nil
+}
+
+// parseHost returns the labels of a host pattern in reverse order.
+func parseHost(pattern string) ([]string, error) {
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(pattern, ".")), ".")
+	for i, label := range labels {
+		if label == "" {
+			return nil, fmt.Errorf("router: empty label in host pattern %q", pattern)
+		}
+		if strings.Contains(label, catchAllKey) && (i != 0 || label != catchAllKey) {
+			return nil, fmt.Errorf("router: invalid wildcard in host pattern %q", pattern)
+		}
+	}
+	reverse(labels)
+	return labels, nil
+}
+
+// hostLabels returns the labels of the host of req in reverse order.
+func hostLabels(req *http.Request) []string {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(host, ".")), ".")
+	reverse(labels)
+	return labels
+}
+
+func reverse(labels []string) {
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+}
+
+// matchHost returns the router of the host pattern matching labels below n.
+func matchHost(n trie.Node[*Router], labels []string) *Router {
+	if len(labels) == 0 {
+		h, _ := n.Value()
+		return h
+	}
+
+	if labels[0] != catchAllKey {
+		if child, ok := n.Child(labels[0]); ok {
+			if h := matchHost(child, labels[1:]); h != nil {
+				return h
+			}
+		}
+	}
+	if child, ok := n.Child(catchAllKey); ok && len(labels) == 1 {
+		h, _ := child.Value()
+		return h
+	}
+	return nil
+}
+
+// Handle registers handler for requests with the given method whose path
+// matches pattern. It returns an error if pattern is not a valid template and
+// a *ConflictError if a template that only differs in the names of its
+// parameters has already been registered for method, see also Strict.
+func (r *Router) Handle(method, pattern string, handler http.Handler) error {
+	key, params, err := parsePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	err = r.checkConflicts(method, pattern, key)
+	if err != nil {
+		return err
+	}
+
+	e := &endpoint{routes: make(map[string]route)}
+	if old, _ := r.routes.Get(key); old != nil {
+		for m, rt := range old.routes {
+			e.routes[m] = rt
+		}
+	}
+	e.routes[method] = route{pattern: pattern, handler: handler, params: params}
+	r.routes.Put(key, e)
+	return nil
+}
+
+// HandleFunc is like Handle but takes a handler function.
+func (r *Router) HandleFunc(method, pattern string, handler func(http.ResponseWriter, *http.Request)) error {
+	return r.Handle(method, pattern, http.HandlerFunc(handler))
+}
+
+// parsePattern returns the key of pattern in the trie and the names of its
+// parameters.
+func parsePattern(pattern string) (key string, params []string, err error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return "", nil, fmt.Errorf("router: pattern %q does not start with a slash", pattern)
+	}
+
+	segments := splitPath(pattern)
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, paramKey):
+			segments[i] = paramKey
+		case strings.HasPrefix(segment, catchAllKey):
+			if i != len(segments)-1 {
+				return "", nil, fmt.Errorf("router: catch-all %q is not the last segment of pattern %q", segment, pattern)
+			}
+			segments[i] = catchAllKey
+		default:
+			continue
+		}
+		if len(segment) == 1 {
+			return "", nil, fmt.Errorf("router: unnamed parameter in pattern %q", pattern)
+		}
+		params = append(params, segment[1:])
+	}
+	return strings.Join(segments, "/"), params, nil
+}
+
+// splitPath returns the segments of path without leading and trailing slashes.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.hasHosts.Load() {
+		if h := matchHost(r.hosts.Root(), hostLabels(req)); h != nil {
+			h.ServeHTTP(w, req)
+			return
+		}
+	}
+
+	e, values := match(r.routes.Root(), splitPath(req.URL.Path), nil)
+	if e == nil {
+		r.notFound(w, req)
+		return
+	}
+
+	rt, ok := e.routes[req.Method]
+	if !ok {
+		r.methodNotAllowed(w, req, e)
+		return
+	}
+
+	if len(rt.params) > 0 {
+		params := make(Params, len(rt.params))
+		for i, name := range rt.params {
+			params[i] = Param{Name: name, Value: values[i]}
+		}
+		req = req.WithContext(context.WithValue(req.Context(), paramsKey{}, params))
+	}
+	rt.handler.ServeHTTP(w, req)
+}
+
+// match returns the endpoint matching segments below n and the values of its
+// parameters appended to values. Alternatives are tried in the order of their
+// precedence, the first one that leads to an endpoint wins.
+func match(n trie.Node[*endpoint], segments []string, values []string) (*endpoint, []string) {
+	if len(segments) == 0 {
+		if e, ok := n.Value(); ok {
+			return e, values
+		}
+		// A catch-all also matches an empty rest.
+		if child, ok := n.Child(catchAllKey); ok {
+			if e, ok := child.Value(); ok {
+				return e, append(values, "")
+			}
+		}
+		return nil, nil
+	}
+
+	// Segments that look like the keys of parameters cannot be static.
+	static := segments[0] != paramKey && segments[0] != catchAllKey
+	if child, ok := n.Child(segments[0]); ok && static {
+		if e, v := match(child, segments[1:], values); e != nil {
+			return e, v
+		}
+	}
+	if child, ok := n.Child(paramKey); ok {
+		if e, v := match(child, segments[1:], append(values, segments[0])); e != nil {
+			return e, v
+		}
+	}
+	if child, ok := n.Child(catchAllKey); ok {
+		if e, ok := child.Value(); ok {
+			return e, append(values, strings.Join(segments, "/"))
+		}
+	}
+	return nil, nil
+}
+
+func (r *Router) notFound(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case r.NotFound != nil:
+		r.NotFound.ServeHTTP(w, req)
+	case r.parent != nil:
+		r.parent.notFound(w, req)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (r *Router) methodNotAllowed(w http.ResponseWriter, req *http.Request, e *endpoint) {
+	methods := make([]string, 0, len(e.routes))
+	for m := range e.routes {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+
+	for h := r; h != nil; h = h.parent {
+		if h.MethodNotAllowed != nil {
+			h.MethodNotAllowed.ServeHTTP(w, req)
+			return
+		}
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// Param is a parameter of a route and the value it matched.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Params contains the parameters of the route that matched a request in the
+// order they appear in its pattern.
+type Params []Param
+
+// Get returns the value of the parameter with the given name or the empty
+// string if there is no such parameter.
+func (p Params) Get(name string) string {
+	for _, param := range p {
+		if param.Name == name {
+			return param.Value
+		}
+	}
+	return ""
+}
+
+type paramsKey struct{}
+
+// ParamsFromContext returns the parameters stored in the context of a request
+// by Router.
+func ParamsFromContext(ctx context.Context) Params {
+	params, _ := ctx.Value(paramsKey{}).(Params)
+	return params
+}
+
+// URLParam returns the value of the parameter with the given name of the route
+// that matched req.
+func URLParam(req *http.Request, name string) string {
+	return ParamsFromContext(req.Context()).Get(name)
+}
+
+var _ http.Handler = (*Router)(nil)
@@ -0,0 +1,297 @@
+package router_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"moehl.dev/trie/router"
+)
+
+func TestRouter(t *testing.T) {
+	r := router.New()
+	handle := func(method, pattern string) {
+		err := r.HandleFunc(method, pattern, func(w http.ResponseWriter, req *http.Request) {
+			_, _ = io.WriteString(w, method+" "+pattern)
+			for _, p := range router.ParamsFromContext(req.Context()) {
+				_, _ = io.WriteString(w, " "+p.Name+"="+p.Value)
+			}
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	handle(http.MethodGet, "/")
+	handle(http.MethodGet, "/users/new")
+	handle(http.MethodGet, "/users/:id")
+	handle(http.MethodPut, "/users/:id")
+	handle(http.MethodGet, "/users/:id/posts/:post")
+	handle(http.MethodGet, "/files/*path")
+
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{"GET", "/", 200, "GET /"},
+		{"GET", "/users/new", 200, "GET /users/new"},
+		{"GET", "/users/42", 200, "GET /users/:id id=42"},
+		{"PUT", "/users/42/", 200, "PUT /users/:id id=42"},
+		{"GET", "/users/new/posts/7", 200, "GET /users/:id/posts/:post id=new post=7"},
+		{"GET", "/files/a/b.txt", 200, "GET /files/*path path=a/b.txt"},
+		{"GET", "/files", 200, "GET /files/*path path="},
+		{"GET", "/users/:/posts/1", 200, "GET /users/:id/posts/:post id=: post=1"},
+		{"GET", "/users", 404, "404 page not found\n"},
+		{"DELETE", "/users/42", 405, "Method Not Allowed\n"},
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.status || rec.Body.String() != tc.body {
+			t.Errorf("%s %s: expected %d '%s' but got %d '%s'", tc.method, tc.path, tc.status, tc.body, rec.Code, rec.Body.String())
+		}
+		if tc.status == 405 && rec.Header().Get("Allow") != "GET, PUT" {
+			t.Errorf("expected Allow header 'GET, PUT' but got '%s'", rec.Header().Get("Allow"))
+		}
+	}
+
+	for _, pattern := range []string{"users", "/files/*path/more", "/users/:"} {
+		if err := r.Handle(http.MethodGet, pattern, http.NotFoundHandler()); err == nil {
+			t.Errorf("%s: expected an error", pattern)
+		}
+	}
+}
+
+func TestRouterHost(t *testing.T) {
+	r := router.New()
+	register := func(r *router.Router, body string) {
+		err := r.HandleFunc(http.MethodGet, "/", func(w http.ResponseWriter, req *http.Request) {
+			_, _ = io.WriteString(w, body)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	register(r, "default")
+	for pattern, body := range map[string]string{
+		"example.com":     "apex",
+		"*.example.com":   "wildcard",
+		"api.example.com": "api",
+	} {
+		h, err := r.Host(pattern)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		register(h, body)
+	}
+
+	for host, expected := range map[string]string{
+		"example.com":          "apex",
+		"API.example.com:8080": "api",
+		"www.example.com":      "wildcard",
+		"a.b.example.com":      "default",
+		"example.org":          "default",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Body.String() != expected {
+			t.Errorf("%s: expected '%s' but got '%s'", host, expected, rec.Body.String())
+		}
+	}
+
+	for _, pattern := range []string{"a.*.com", "a..com", "*a.com"} {
+		if _, err := r.Host(pattern); err == nil {
+			t.Errorf("%s: expected an error", pattern)
+		}
+	}
+}
+
+func TestRouterGroup(t *testing.T) {
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				_, _ = io.WriteString(w, name+" ")
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+	ok := func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.WriteString(w, router.URLParam(req, "id"))
+	}
+
+	r := router.New()
+	api := r.Group("/api/", tag("api"))
+	users := api.Group("/users/:id", tag("auth"), tag("log"))
+	for _, err := range []error{
+		r.HandleFunc(http.MethodGet, "/health", ok),
+		api.HandleFunc(http.MethodGet, "/status", ok),
+		users.HandleFunc(http.MethodGet, "/", ok),
+		users.HandleFunc(http.MethodGet, "/posts", ok),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for path, expected := range map[string]string{
+		"/health":            "",
+		"/api/status":        "api ",
+		"/api/users/7":       "api auth log 7",
+		"/api/users/7/posts": "api auth log 7",
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Body.String() != expected {
+			t.Errorf("%s: expected '%s' but got '%s'", path, expected, rec.Body.String())
+		}
+	}
+}
+
+func TestRouterConflicts(t *testing.T) {
+	h := http.NotFoundHandler()
+	r := router.New()
+	if err := r.Handle(http.MethodGet, "/users/:id", h); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var conflict *router.ConflictError
+	err := r.Handle(http.MethodGet, "/users/:name/", h)
+	if !errors.As(err, &conflict) || conflict.Existing != "/users/:id" || conflict.Overlap {
+		t.Errorf("expected a conflict with /users/:id but got '%v'", err)
+	}
+	if err := r.Handle(http.MethodPost, "/users/:name", h); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := r.Handle(http.MethodGet, "/users/new", h); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	strict := router.New()
+	strict.Strict = true
+	for _, tc := range []struct {
+		pattern string
+		overlap bool
+	}{
+		{"/users/:id", false},
+		{"/users/new", true},
+		{"/users/*rest", true},
+		{"/users/:id/posts", false},
+		{"/users", false},
+		{"/*rest", true},
+	} {
+		err := strict.Handle(http.MethodGet, tc.pattern, h)
+		if !tc.overlap {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.pattern, err)
+			}
+			continue
+		}
+		if !errors.As(err, &conflict) || !conflict.Overlap {
+			t.Errorf("%s: expected an overlap but got '%v'", tc.pattern, err)
+		}
+	}
+}
+
+func TestRouterExportRoutes(t *testing.T) {
+	r := router.New()
+	for _, route := range [][2]string{
+		{http.MethodPut, "/users/:id/"},
+		{http.MethodGet, "/users/:id"},
+		{http.MethodGet, "/"},
+		{http.MethodGet, "/files/*path"},
+	} {
+		if err := r.Handle(route[0], route[1], http.NotFoundHandler()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	h, _ := r.Host("*.example.com")
+	_ = h.Handle(http.MethodGet, "/status", http.NotFoundHandler())
+
+	var b strings.Builder
+	if err := r.ExportRoutes(&b, router.Text); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "GET /\nGET /files/*path\nGET /users/:id\nPUT /users/:id\nGET *.example.com/status\n"
+	if b.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	if err := r.ExportRoutes(&b, router.JSON); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var routes []map[string]string
+	if err := json.Unmarshal([]byte(b.String()), &routes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(routes) != 5 || routes[4]["host"] != "*.example.com" || routes[2]["pattern"] != "/users/:id" {
+		t.Errorf("unexpected routes %v", routes)
+	}
+
+	b.Reset()
+	if err := r.ExportRoutes(&b, router.OpenAPI); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Parameters []struct{ Name, In string }
+		}
+	}
+	if err := json.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(doc.Paths) != 3 || len(doc.Paths["/users/{id}"]) != 2 {
+		t.Errorf("unexpected paths %v", doc.Paths)
+	}
+	if p := doc.Paths["/files/{path}"]["get"].Parameters; len(p) != 1 || p[0].Name != "path" || p[0].In != "path" {
+		t.Errorf("unexpected parameters %v", p)
+	}
+
+	if err := r.ExportRoutes(&b, router.RouteFormat(-1)); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestRouterLookupAll(t *testing.T) {
+	r := router.New()
+	for _, route := range [][2]string{
+		{http.MethodGet, "/events/orders/created"},
+		{http.MethodPost, "/events/:topic/created"},
+		{http.MethodGet, "/events/:topic/:kind"},
+		{http.MethodGet, "/events/*rest"},
+		{http.MethodGet, "/events/orders/*rest"},
+		{http.MethodGet, "/other"},
+	} {
+		if err := r.Handle(route[0], route[1], http.NotFoundHandler()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var got []string
+	for _, m := range r.LookupAll("/events/orders/created") {
+		s := m.Method + " " + m.Pattern
+		for _, p := range m.Params {
+			s += " " + p.Name + "=" + p.Value
+		}
+		got = append(got, s)
+	}
+	expected := []string{
+		"GET /events/orders/created",
+		"GET /events/orders/*rest rest=created",
+		"POST /events/:topic/created topic=orders",
+		"GET /events/:topic/:kind topic=orders kind=created",
+		"GET /events/*rest rest=orders/created",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+
+	if m := r.LookupAll("/missing"); len(m) != 0 {
+		t.Errorf("expected no matches but got %v", m)
+	}
+}
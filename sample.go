@@ -0,0 +1,225 @@
+package trie
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// WithSubtreeCounts makes every node keep track of the number of values in its
+// subtree, so that Sample can draw paths without walking the whole trie. All
+// writes to the trie are serialized and Sample blocks them while it runs. It
+// does not apply to Slice.
+func WithSubtreeCounts() Option {
+	return func(o *options) {
+		o.subtreeCounts = true
+	}
+}
+
+// lockCounts acquires the counts lock, if any.
+func (t *tree[K, V]) lockCounts() {
+	if t.counts != nil {
+		t.counts.Lock()
+	}
+}
+
+// unlockCounts releases the counts lock, if any.
+func (t *tree[K, V]) unlockCounts() {
+	if t.counts != nil {
+		t.counts.Unlock()
+	}
+}
+
+// addCount adds delta to the count of the root and of every node along
+// segments whose run is part of segments. The counts lock must be held by the
+// caller.
+func (t *tree[K, V]) addCount(segments []K, delta int) {
+	n := t.root
+	n.lock.RLock()
+	n.count += delta
+	for len(segments) > 0 {
+		child, ok := n.children.get(segments[0])
+		if !ok || len(child.segments) > len(segments) || !slices.Equal(child.segments, segments[:len(child.segments)]) {
+			break
+		}
+		child.lock.RLock()
+		n.lock.RUnlock()
+		n = child
+		n.count += delta
+		segments = segments[len(child.segments):]
+	}
+	n.lock.RUnlock()
+}
+
+// recount sets the count of n and all of its descendants from scratch and
+// returns it. The counts lock must be held by the caller.
+func (t *tree[K, V]) recount(n *node[K, V]) int {
+	n.lock.RLock()
+	count := 0
+	if n.hasValue {
+		count++
+	}
+	var children []*node[K, V]
+	n.children.each(func(_ K, child *node[K, V]) {
+		children = append(children, child)
+	})
+	n.lock.RUnlock()
+
+	for _, child := range children {
+		count += t.recount(child)
+	}
+	n.count = count
+	return count
+}
+
+// Sample uses the subtree counts to pick the paths if WithSubtreeCounts is
+// used and walks the whole trie otherwise.
+func (t *stringTrie[V]) Sample(n int) []string {
+	if t.counts == nil {
+		return t.sampleWeighted(t.Walk, n, nil)
+	}
+	defer t.unpin(t.pin())
+	t.counts.Lock()
+	defer t.counts.Unlock()
+	return t.sampleCounted(view[string, V]{n: t.root}, n)
+}
+
+func (t *stringTrie[V]) SampleWeighted(n int, weight func(V) float64) []string {
+	return t.sampleWeighted(t.Walk, n, weight)
+}
+
+// sampleCounted draws n distinct ranks of the values at and below v in walk
+// order and descends the trie once to resolve them to paths, which are
+// relative to v. The counts lock must be held by the caller.
+func (t *stringTrie[V]) sampleCounted(v view[string, V], n int) []string {
+	total := v.n.count
+	if n <= 0 || total == 0 {
+		return nil
+	}
+
+	var ranks []int
+	if n >= total {
+		ranks = make([]int, total)
+		for i := range ranks {
+			ranks[i] = i
+		}
+	} else {
+		// Floyd's algorithm picks n distinct ranks with n random numbers.
+		picked := make(map[int]struct{}, n)
+		for j := total - n; j < total; j++ {
+			r := rand.Intn(j + 1)
+			if _, ok := picked[r]; ok {
+				r = j
+			}
+			picked[r] = struct{}{}
+			ranks = append(ranks, r)
+		}
+		sort.Ints(ranks)
+	}
+
+	paths := make([]string, 0, len(ranks))
+	t.resolveRanks(v, "", true, ranks, &paths)
+	return paths
+}
+
+// resolveRanks appends the paths of the values at the sorted ranks, counted
+// from v in walk order, to paths.
+func (t *stringTrie[V]) resolveRanks(v view[string, V], path string, root bool, ranks []int, paths *[]string) {
+	_, hasValue, keys, children := t.sortedSnapshot(v)
+	offset := 0
+	if hasValue {
+		if ranks[0] == 0 {
+			*paths = append(*paths, path)
+			ranks = ranks[1:]
+		}
+		offset++
+	}
+
+	for i, child := range children {
+		if len(ranks) == 0 {
+			return
+		}
+		// The count of an implicit node is the count of the node at the end
+		// of its run.
+		end := offset + child.n.count
+		j := sort.SearchInts(ranks, end)
+		if j > 0 {
+			shifted := make([]int, j)
+			for k, r := range ranks[:j] {
+				shifted[k] = r - offset
+			}
+			t.resolveRanks(child, t.childPath(path, keys[i], root), false, shifted, paths)
+		}
+		ranks = ranks[j:]
+		offset = end
+	}
+}
+
+// sampleWeighted draws n distinct paths from the values visited by walk using
+// weighted reservoir sampling. A nil weight gives every value the weight one.
+func (t *stringTrie[V]) sampleWeighted(walk func(func(string, V) bool), n int, weight func(V) float64) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	var (
+		h   sampleHeap
+		seq int
+	)
+	walk(func(path string, value V) bool {
+		w := 1.0
+		if weight != nil {
+			w = weight(value)
+		}
+		if !(w > 0) {
+			return true
+		}
+
+		// Each value gets the key u^(1/w) for a uniform u in (0, 1], the
+		// n values with the largest keys are a weighted sample without
+		// replacement. The logarithm is used to avoid underflows.
+		e := sampleEntry{path: path, key: math.Log(1-rand.Float64()) / w, seq: seq}
+		seq++
+		if len(h) < n {
+			heap.Push(&h, e)
+		} else if h[0].key < e.key {
+			h[0] = e
+			heap.Fix(&h, 0)
+		}
+		return true
+	})
+
+	sort.Slice(h, func(i, j int) bool { return h[i].seq < h[j].seq })
+	paths := make([]string, len(h))
+	for i, e := range h {
+		paths[i] = e.path
+	}
+	return paths
+}
+
+type sampleEntry struct {
+	path string
+	key  float64
+	// seq is the position of the path in the walk order, the sample is
+	// returned in that order.
+	seq int
+}
+
+// sampleHeap keeps the entry with the smallest key at the top so it can be
+// replaced by entries with larger keys.
+type sampleHeap []sampleEntry
+
+func (h sampleHeap) Len() int           { return len(h) }
+func (h sampleHeap) Less(i, j int) bool { return h[i].key < h[j].key }
+func (h sampleHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *sampleHeap) Push(x any) { *h = append(*h, x.(sampleEntry)) }
+
+func (h *sampleHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,43 @@
+package trie
+
+// Scheduler controls the interleaving of concurrent operations in tests, see
+// WithScheduler. trietest.Scheduler runs the goroutines accessing a trie one
+// at a time in an order determined by a seed, which makes races between
+// operations reproducible.
+type Scheduler interface {
+	// Yield is called by Put, Delete and lookups of single paths like Get
+	// before they acquire the lock of a node, and repeatedly while the lock
+	// is held by another goroutine instead of blocking on it. The locks of
+	// the nodes that have already been locked by the operation are held
+	// while Yield is called.
+	Yield()
+}
+
+// WithScheduler makes the trie call s at every point at which an operation
+// acquires the lock of a node. It is meant for tests only, locks are acquired
+// by spinning on s instead of blocking. Operations that visit large parts of
+// the trie, like Walk, GetBatch or Compact, and the release of pooled nodes
+// acquire their locks without calling s, so they must not run concurrently
+// while s is pausing other goroutines.
+func WithScheduler(s Scheduler) Option {
+	return func(o *options) {
+		o.sched = s
+	}
+}
+
+// schedLock acquires the write lock of n, giving s the chance to run other
+// goroutines first.
+func schedLock[K comparable, V any](s Scheduler, n *node[K, V]) {
+	s.Yield()
+	for !n.lock.TryLock() {
+		s.Yield()
+	}
+}
+
+// schedRLock is like schedLock but acquires the read lock.
+func schedRLock[K comparable, V any](s Scheduler, n *node[K, V]) {
+	s.Yield()
+	for !n.lock.TryRLock() {
+		s.Yield()
+	}
+}
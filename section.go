@@ -0,0 +1,180 @@
+package trie
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// Since version 2 the nodes of the binary format are split into sections
+// which are protected by a checksum each. The sections are terminated by an
+// empty section followed by a trailer:
+//
+//	section: uvarint length | bytes | uint32 CRC-32C of bytes (little endian)
+//	trailer: uvarint number of values | SHA-256 of all section bytes
+//
+// A truncated or corrupted input is therefore detected instead of being read
+// as a smaller trie.
+const sectionSize = 64 << 10
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// CorruptError is returned if the input is truncated or a checksum does not
+// match. It matches ErrInvalidFormat when checked using errors.Is.
+type CorruptError struct {
+	// Offset of the input at which the corruption has been detected.
+	Offset int64
+	Reason string
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("trie: corrupt input at offset %d: %s", e.Offset, e.Reason)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return ErrInvalidFormat
+}
+
+// sectionWriter splits everything written to it into sections.
+type sectionWriter struct {
+	w    *binaryWriter
+	buf  []byte
+	hash hash.Hash
+}
+
+func newSectionWriter(w *binaryWriter) *sectionWriter {
+	return &sectionWriter{w: w, hash: sha256.New()}
+}
+
+func (s *sectionWriter) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+	for len(s.buf) >= sectionSize {
+		err := s.emit(s.buf[:sectionSize])
+		if err != nil {
+			return 0, err
+		}
+		s.buf = s.buf[sectionSize:]
+	}
+	return len(p), nil
+}
+
+func (s *sectionWriter) emit(section []byte) error {
+	err := s.w.writeBytes(section)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(binary.LittleEndian.AppendUint32(nil, crc32.Checksum(section, crcTable)))
+	s.hash.Write(section)
+	return err
+}
+
+// close writes the remaining data, the terminating section and the trailer.
+func (s *sectionWriter) close(values int64) error {
+	if len(s.buf) > 0 {
+		err := s.emit(s.buf)
+		if err != nil {
+			return err
+		}
+	}
+	err := s.w.writeUvarint(0)
+	if err == nil {
+		err = s.w.writeUvarint(uint64(values))
+	}
+	if err == nil {
+		_, err = s.w.Write(s.hash.Sum(nil))
+	}
+	return err
+}
+
+// sectionReader reads the sections written by sectionWriter and verifies
+// their checksums. It returns io.EOF once the terminating section has been
+// read and never reads beyond it.
+type sectionReader struct {
+	r    *binaryReader
+	buf  []byte
+	hash hash.Hash
+	done bool
+}
+
+func newSectionReader(r *binaryReader) *sectionReader {
+	return &sectionReader{r: r, hash: sha256.New()}
+}
+
+func (s *sectionReader) corrupt(reason string, args ...any) error {
+	return &CorruptError{Offset: s.r.n, Reason: fmt.Sprintf(reason, args...)}
+}
+
+func (s *sectionReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+
+		l, err := s.r.readUvarint()
+		if err != nil {
+			return 0, s.corrupt("truncated section: %v", err)
+		}
+		if l == 0 {
+			s.done = true
+			continue
+		}
+		if l > sectionSize {
+			return 0, s.corrupt("section length %d exceeds limit", l)
+		}
+
+		data := make([]byte, l+4)
+		n, err := io.ReadFull(s.r.r, data)
+		s.r.n += int64(n)
+		if err != nil {
+			return 0, s.corrupt("truncated section: %v", unexpectedEOF(err))
+		}
+
+		data, sum := data[:l], binary.LittleEndian.Uint32(data[l:])
+		if crc32.Checksum(data, crcTable) != sum {
+			return 0, s.corrupt("section checksum mismatch")
+		}
+		s.hash.Write(data)
+		s.buf = data
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// finish verifies that all sections have been consumed by r and checks the
+// trailer against the number of values that have been read.
+func (s *sectionReader) finish(r *bufio.Reader, values int64) error {
+	_, err := r.ReadByte()
+	if err == nil {
+		return s.corrupt("unexpected data after the last node")
+	}
+	if err != io.EOF {
+		return err
+	}
+
+	count, err := s.r.readUvarint()
+	if err != nil {
+		return s.corrupt("truncated trailer: %v", err)
+	}
+	if count != uint64(values) {
+		return s.corrupt("expected %d values but got %d", count, values)
+	}
+
+	sum := make([]byte, sha256.Size)
+	n, err := io.ReadFull(s.r.r, sum)
+	s.r.n += int64(n)
+	if err != nil {
+		return s.corrupt("truncated trailer: %v", unexpectedEOF(err))
+	}
+	if !bytes.Equal(sum, s.hash.Sum(nil)) {
+		return s.corrupt("trailer hash mismatch")
+	}
+
+	return nil
+}
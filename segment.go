@@ -0,0 +1,623 @@
+package trie
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The segment format stores the paths of a trie that have a value set as a
+// sorted list of key-value pairs, similar to an SSTable. The entries are split
+// into blocks and an index containing the first key of every block allows to
+// find the only block that may contain a key:
+//
+//	header:  magic "TRIS" | uvarint version | string delimiter | byte flags
+//	block:   uvarint len(entries) | entry...
+//	entry:   [uvarint shared] | string key | string value
+//	index:   uvarint len(blocks) | (string first key | uvarint offset | uvarint length)...
+//	trailer: uint64 offset of the index (little endian)
+//	string:  uvarint length | bytes
+//
+// If flagPrefixCompression is set, each entry only stores the part of the key
+// that is not shared with the previous key in the same block.
+const (
+	segmentMagic   = "TRIS"
+	segmentVersion = 1
+
+	flagPrefixCompression byte = 1
+
+	defaultSegmentBlockSize = 4096
+)
+
+// SegmentOption configures how a segment is written.
+type SegmentOption func(*segmentConfig)
+
+type segmentConfig struct {
+	blockSize int
+	compress  bool
+}
+
+// SegmentBlockSize sets the size in bytes after which a new block is started.
+// The default is 4KiB.
+func SegmentBlockSize(n int) SegmentOption {
+	return func(c *segmentConfig) {
+		c.blockSize = n
+	}
+}
+
+// SegmentPrefixCompression enables prefix compression of the keys within a
+// block.
+func SegmentPrefixCompression() SegmentOption {
+	return func(c *segmentConfig) {
+		c.compress = true
+	}
+}
+
+// WriteSegment writes all paths of t that have a value set to w in the
+// segment format. The entries are collected and sorted before they are
+// written.
+func WriteSegment[V any](w io.Writer, t String[V], opts ...SegmentOption) (int64, error) {
+	var entries []segmentEntry
+	var err error
+	codec := t.Codec()
+	t.Walk(func(path string, value V) bool {
+		var b []byte
+		b, err = codec.Encode(value)
+		if err != nil {
+			err = fmt.Errorf("trie: encode value: %w", err)
+			return false
+		}
+		entries = append(entries, segmentEntry{path, b})
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	sw := newSegmentWriter(w, t.Delimiter(), opts)
+	for _, e := range entries {
+		err = sw.add(e)
+		if err != nil {
+			return sw.w.n, err
+		}
+	}
+	return sw.close()
+}
+
+// MergeSegments merges segments into a single segment written to w. If a key
+// is present in multiple segments, the value of the last segment wins. All
+// segments must use the same delimiter.
+func MergeSegments[V any](w io.Writer, segments []*Segment[V], opts ...SegmentOption) (int64, error) {
+	if len(segments) == 0 {
+		return 0, fmt.Errorf("trie: no segments to merge")
+	}
+
+	h := make(segmentHeap, 0, len(segments))
+	for i, s := range segments {
+		if s.delimiter != segments[0].delimiter {
+			return 0, fmt.Errorf("trie: delimiter mismatch: expected '%s' but got '%s'", segments[0].delimiter, s.delimiter)
+		}
+		it := &segmentIterator{blocks: s.blocks, compressed: s.compressed, priority: i}
+		ok, err := it.next()
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			h = append(h, it)
+		}
+	}
+	heap.Init(&h)
+
+	sw := newSegmentWriter(w, segments[0].delimiter, opts)
+	for len(h) > 0 {
+		// Entries with the same key are ordered by descending priority, the
+		// first one is written and all others are skipped.
+		e := h[0].entry
+		err := sw.add(e)
+		if err != nil {
+			return sw.w.n, err
+		}
+
+		for len(h) > 0 && h[0].entry.key == e.key {
+			it := h[0]
+			ok, err := it.next()
+			if err != nil {
+				return sw.w.n, err
+			}
+			if ok {
+				heap.Fix(&h, 0)
+			} else {
+				heap.Pop(&h)
+			}
+		}
+	}
+
+	return sw.close()
+}
+
+type segmentEntry struct {
+	key   string
+	value []byte
+}
+
+type segmentBlock struct {
+	first  string
+	offset uint64
+	length uint64
+	read   func(p []byte, off int64) (int, error)
+}
+
+func (b segmentBlock) load(compressed bool) ([]segmentEntry, error) {
+	data := make([]byte, b.length)
+	_, err := b.read(data, int64(b.offset))
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	br := &binaryReader{r: bufio.NewReader(bytes.NewReader(data))}
+	count, err := br.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	// Every entry takes at least one byte for the length of its key and its
+	// value and for the shared prefix if the keys are compressed.
+	size := uint64(2)
+	if compressed {
+		size++
+	}
+	if count > uint64(int64(len(data))-br.n)/size {
+		return nil, fmt.Errorf("%w: entry count out of range", ErrInvalidFormat)
+	}
+
+	entries := make([]segmentEntry, 0, min(count, 1024))
+	prev := ""
+	for i := uint64(0); i < count; i++ {
+		var shared uint64
+		if compressed {
+			shared, err = br.readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			if shared > uint64(len(prev)) {
+				return nil, fmt.Errorf("%w: invalid shared prefix", ErrInvalidFormat)
+			}
+		}
+		key, err := br.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		value, err := br.readBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		prev = prev[:shared] + string(key)
+		entries = append(entries, segmentEntry{prev, value})
+	}
+
+	return entries, nil
+}
+
+type segmentWriter struct {
+	w      *binaryWriter
+	config segmentConfig
+
+	index []segmentBlock
+	block *bytes.Buffer
+	bw    *binaryWriter
+	count uint64
+	first string
+	prev  string
+
+	err error
+}
+
+func newSegmentWriter(w io.Writer, delimiter string, opts []SegmentOption) *segmentWriter {
+	c := segmentConfig{blockSize: defaultSegmentBlockSize}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	sw := &segmentWriter{
+		w:      &binaryWriter{w: bufio.NewWriter(w)},
+		config: c,
+		block:  new(bytes.Buffer),
+	}
+	sw.bw = &binaryWriter{w: bufio.NewWriter(sw.block)}
+
+	var flags byte
+	if c.compress {
+		flags |= flagPrefixCompression
+	}
+
+	_, sw.err = sw.w.Write([]byte(segmentMagic))
+	if sw.err == nil {
+		sw.err = sw.w.writeUvarint(segmentVersion)
+	}
+	if sw.err == nil {
+		sw.err = sw.w.writeBytes([]byte(delimiter))
+	}
+	if sw.err == nil {
+		_, sw.err = sw.w.Write([]byte{flags})
+	}
+
+	return sw
+}
+
+// add appends an entry to the current block. Entries must be added in sorted
+// order.
+func (w *segmentWriter) add(e segmentEntry) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if w.count == 0 {
+		w.first = e.key
+		w.prev = ""
+	}
+
+	key := e.key
+	if w.config.compress {
+		shared := commonPrefixLen(w.prev, key)
+		_ = w.bw.writeUvarint(uint64(shared))
+		key = key[shared:]
+	}
+	_ = w.bw.writeBytes([]byte(key))
+	_ = w.bw.writeBytes(e.value)
+
+	w.count++
+	w.prev = e.key
+
+	if w.bw.n >= int64(w.config.blockSize) {
+		w.err = w.flush()
+	}
+	return w.err
+}
+
+// flush writes the current block and adds it to the index.
+func (w *segmentWriter) flush() error {
+	if w.count == 0 {
+		return nil
+	}
+
+	err := w.bw.w.Flush()
+	if err != nil {
+		return err
+	}
+
+	offset := w.w.n
+	err = w.w.writeUvarint(w.count)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(w.block.Bytes())
+	if err != nil {
+		return err
+	}
+
+	w.index = append(w.index, segmentBlock{
+		first:  w.first,
+		offset: uint64(offset),
+		length: uint64(w.w.n - offset),
+	})
+
+	w.block.Reset()
+	w.bw.n = 0
+	w.count = 0
+
+	return nil
+}
+
+// close flushes the last block and writes the index and trailer.
+func (w *segmentWriter) close() (int64, error) {
+	if w.err != nil {
+		return w.w.n, w.err
+	}
+
+	err := w.flush()
+	if err != nil {
+		return w.w.n, err
+	}
+
+	offset := w.w.n
+	err = w.w.writeUvarint(uint64(len(w.index)))
+	for _, b := range w.index {
+		if err == nil {
+			err = w.w.writeBytes([]byte(b.first))
+		}
+		if err == nil {
+			err = w.w.writeUvarint(b.offset)
+		}
+		if err == nil {
+			err = w.w.writeUvarint(b.length)
+		}
+	}
+	if err == nil {
+		var trailer [8]byte
+		binary.LittleEndian.PutUint64(trailer[:], uint64(offset))
+		_, err = w.w.Write(trailer[:])
+	}
+	if err == nil {
+		err = w.w.w.Flush()
+	}
+
+	return w.w.n, err
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// Segment is a read-only, sorted set of paths and values stored in the segment
+// format. Only the block index is held in memory, each lookup reads a single
+// block.
+//
+// Unlike String, a Segment only contains paths that have a value set, Get does
+// not find intermediate nodes.
+type Segment[V any] struct {
+	codec      ValueCodec[V]
+	delimiter  string
+	compressed bool
+	blocks     []segmentBlock
+	closer     io.Closer
+}
+
+// OpenSegment opens a file written by WriteSegment or MergeSegments.
+func OpenSegment[V any](path string) (*Segment[V], error) {
+	return OpenSegmentCodec[V](path, nil)
+}
+
+// OpenSegmentCodec is like OpenSegment but uses codec to decode the values.
+func OpenSegmentCodec[V any](path string, codec ValueCodec[V]) (*Segment[V], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	s, err := NewSegmentCodec[V](f, info.Size(), codec)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	s.closer = f
+
+	return s, nil
+}
+
+// NewSegment reads the index of a segment of the given size from r.
+func NewSegment[V any](r io.ReaderAt, size int64) (*Segment[V], error) {
+	return NewSegmentCodec[V](r, size, nil)
+}
+
+// NewSegmentCodec is like NewSegment but uses codec to decode the values.
+func NewSegmentCodec[V any](r io.ReaderAt, size int64, codec ValueCodec[V]) (*Segment[V], error) {
+	if size < int64(len(segmentMagic))+8 {
+		return nil, ErrInvalidFormat
+	}
+
+	br := &binaryReader{r: bufio.NewReader(io.NewSectionReader(r, 0, size))}
+	magic := make([]byte, len(segmentMagic))
+	_, err := io.ReadFull(br.r, magic)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if string(magic) != segmentMagic {
+		return nil, ErrInvalidFormat
+	}
+	version, err := br.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if version != segmentVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, version)
+	}
+	delimiter, err := br.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	flags, err := br.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	var trailer [8]byte
+	_, err = r.ReadAt(trailer[:], size-8)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	offset := int64(binary.LittleEndian.Uint64(trailer[:]))
+	if offset < 0 || offset > size-8 {
+		return nil, fmt.Errorf("%w: index offset out of range", ErrInvalidFormat)
+	}
+
+	br = &binaryReader{r: bufio.NewReader(io.NewSectionReader(r, offset, size-8-offset))}
+	count, err := br.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]segmentBlock, 0, min(count, 1024))
+	for i := uint64(0); i < count; i++ {
+		first, err := br.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		off, err := br.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		length, err := br.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if off+length > uint64(offset) || length > maxBinaryLength {
+			return nil, fmt.Errorf("%w: block out of range", ErrInvalidFormat)
+		}
+		blocks = append(blocks, segmentBlock{string(first), off, length, r.ReadAt})
+	}
+
+	return &Segment[V]{
+		codec:      codecOrDefault(codec),
+		delimiter:  string(delimiter),
+		compressed: flags&flagPrefixCompression != 0,
+		blocks:     blocks,
+	}, nil
+}
+
+// Close closes the underlying file if the segment has been opened by
+// OpenSegment.
+func (s *Segment[V]) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// Delimiter that has been used by the trie the segment was written from.
+func (s *Segment[V]) Delimiter() string {
+	return s.delimiter
+}
+
+// Get the value of path. `found` is only true if a value has been set at
+// path.
+func (s *Segment[V]) Get(path string) (value V, found bool, err error) {
+	// Find the last block whose first key is not greater than path.
+	i := sort.Search(len(s.blocks), func(i int) bool {
+		return s.blocks[i].first > path
+	}) - 1
+	if i < 0 {
+		return value, false, nil
+	}
+
+	entries, err := s.blocks[i].load(s.compressed)
+	if err != nil {
+		return value, false, err
+	}
+
+	j := sort.Search(len(entries), func(j int) bool {
+		return entries[j].key >= path
+	})
+	if j == len(entries) || entries[j].key != path {
+		return value, false, nil
+	}
+
+	value, err = s.codec.Decode(entries[j].value)
+	if err != nil {
+		return value, true, fmt.Errorf("trie: decode value: %w", err)
+	}
+	return value, true, nil
+}
+
+// PutE always fails with an error wrapping ErrReadOnly, segments are immutable
+// once they have been written. Use MergeSegments to combine them.
+func (s *Segment[V]) PutE(path string, _ V) error {
+	return fmt.Errorf("%w: %q", ErrReadOnly, path)
+}
+
+// DeleteE always fails with an error wrapping ErrReadOnly, see PutE.
+func (s *Segment[V]) DeleteE(path string) error {
+	return fmt.Errorf("%w: %q", ErrReadOnly, path)
+}
+
+// Walk calls fn for every entry with a path starting with prefix in sorted
+// order. Walking stops if fn returns false.
+func (s *Segment[V]) Walk(prefix string, fn func(path string, value V) bool) error {
+	start := sort.Search(len(s.blocks), func(i int) bool {
+		return s.blocks[i].first > prefix
+	}) - 1
+
+	it := &segmentIterator{blocks: s.blocks[max(start, 0):], compressed: s.compressed}
+	for {
+		ok, err := it.next()
+		if err != nil || !ok {
+			return err
+		}
+		if it.entry.key < prefix {
+			continue
+		}
+		if !strings.HasPrefix(it.entry.key, prefix) {
+			return nil
+		}
+
+		value, err := s.codec.Decode(it.entry.value)
+		if err != nil {
+			return fmt.Errorf("trie: decode value: %w", err)
+		}
+		if !fn(it.entry.key, value) {
+			return nil
+		}
+	}
+}
+
+// segmentIterator iterates over the entries of a list of blocks in order.
+type segmentIterator struct {
+	blocks     []segmentBlock
+	compressed bool
+	priority   int
+
+	entries []segmentEntry
+	entry   segmentEntry
+}
+
+func (it *segmentIterator) next() (bool, error) {
+	for len(it.entries) == 0 {
+		if len(it.blocks) == 0 {
+			return false, nil
+		}
+		entries, err := it.blocks[0].load(it.compressed)
+		if err != nil {
+			return false, err
+		}
+		it.entries = entries
+		it.blocks = it.blocks[1:]
+	}
+
+	it.entry = it.entries[0]
+	it.entries = it.entries[1:]
+	return true, nil
+}
+
+// segmentHeap orders iterators by their current key and by descending
+// priority for equal keys.
+type segmentHeap []*segmentIterator
+
+func (h segmentHeap) Len() int { return len(h) }
+
+func (h segmentHeap) Less(i, j int) bool {
+	if h[i].entry.key == h[j].entry.key {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].entry.key < h[j].entry.key
+}
+
+func (h segmentHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *segmentHeap) Push(x any) { *h = append(*h, x.(*segmentIterator)) }
+
+func (h *segmentHeap) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,242 @@
+// Package server exposes a String trie over a small HTTP API, so that it
+// can be queried by other processes without reimplementing the trie:
+//
+//	GET    /v1/keys/{path}   value at path, 404 if it has none
+//	PUT    /v1/keys/{path}   set the value at path to the request body
+//	DELETE /v1/keys/{path}   delete path and everything below it
+//	GET    /v1/list          list paths, see below
+//	GET    /v1/watch         stream changes as server-sent events
+//
+// Values are transferred in the encoding of the codec of the trie. The list
+// endpoint accepts the query parameters prefix, delimiter, max_keys and
+// start_after with the meaning of the parameters of String.List and responds
+// with a JSON object containing keys, commonPrefixes and truncated. The watch
+// endpoint accepts a prefix and sends an event for every change made through
+// the server to a path starting with it, including deletes of its parents.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"moehl.dev/trie"
+)
+
+// maxValueSize limits the size of the request body of PUT.
+const maxValueSize = 1 << 20
+
+// Event describes a change, it is sent as the data of server-sent events.
+type Event struct {
+	// Op is either "put" or "delete".
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	// Value is the encoded value for puts.
+	Value []byte `json:"value,omitempty"`
+}
+
+// Server is an http.Handler serving t. Changes to t that are not made through
+// the server, including Put and Delete, are not reported to watchers.
+type Server[V any] struct {
+	t trie.String[V]
+
+	// write is held across a change and its notification, so that watchers
+	// receive the events in the order the changes have been applied.
+	write    sync.Mutex
+	lock     sync.Mutex
+	watchers map[*watcher]bool
+}
+
+type watcher struct {
+	prefix string
+	events chan Event
+}
+
+// New creates a Server for t.
+func New[V any](t trie.String[V]) *Server[V] {
+	return &Server[V]{t: t, watchers: make(map[*watcher]bool)}
+}
+
+// Put sets the value at path and notifies watchers.
+func (s *Server[V]) Put(path string, value V) error {
+	b, err := s.t.Codec().Encode(value)
+	if err != nil {
+		return err
+	}
+	s.write.Lock()
+	defer s.write.Unlock()
+	err = s.t.PutE(path, value)
+	if err != nil {
+		return err
+	}
+	s.notify(Event{Op: "put", Path: path, Value: b})
+	return nil
+}
+
+// Delete deletes path and notifies watchers.
+func (s *Server[V]) Delete(path string) {
+	s.write.Lock()
+	defer s.write.Unlock()
+	s.t.Delete(path)
+	s.notify(Event{Op: "delete", Path: path})
+}
+
+// notify passes e to all watchers of a prefix of its path and, for deletes,
+// to the watchers of paths below it. Watchers that do not keep up lose events
+// rather than blocking changes.
+func (s *Server[V]) notify(e Event) {
+	delimiter := s.t.Delimiter()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for w := range s.watchers {
+		below := e.Op == "delete" && (e.Path == "" || strings.HasPrefix(w.prefix, e.Path+delimiter))
+		if !strings.HasPrefix(e.Path, w.prefix) && !below {
+			continue
+		}
+		select {
+		case w.events <- e:
+		default:
+		}
+	}
+}
+
+func (s *Server[V]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if path, ok := strings.CutPrefix(r.URL.Path, "/v1/keys/"); ok {
+		s.serveKey(w, r, path)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/list":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.serveList(w, r)
+	case "/v1/watch":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.serveWatch(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server[V]) serveKey(w http.ResponseWriter, r *http.Request, path string) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		value, state := s.t.Lookup(path)
+		if state != trie.Exists {
+			http.NotFound(w, r)
+			return
+		}
+		b, err := s.t.Codec().Encode(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		_, _ = w.Write(b)
+	case http.MethodPut:
+		b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValueSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		value, err := s.t.Codec().Decode(b)
+		if err == nil {
+			err = s.Put(path, value)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.Delete(path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete)
+	}
+}
+
+func (s *Server[V]) serveList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	maxKeys := 1000
+	if v := q.Get("max_keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("invalid max_keys %q", v), http.StatusBadRequest)
+			return
+		}
+		maxKeys = n
+	}
+
+	var resp struct {
+		Keys           []string `json:"keys"`
+		CommonPrefixes []string `json:"commonPrefixes"`
+		Truncated      bool     `json:"truncated"`
+	}
+	resp.Keys, resp.CommonPrefixes, resp.Truncated = s.t.List(q.Get("prefix"), q.Get("delimiter"), maxKeys, q.Get("start_after"))
+	if resp.Keys == nil {
+		resp.Keys = []string{}
+	}
+	if resp.CommonPrefixes == nil {
+		resp.CommonPrefixes = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server[V]) serveWatch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	wt := &watcher{prefix: r.URL.Query().Get("prefix"), events: make(chan Event, 64)}
+	s.lock.Lock()
+	s.watchers[wt] = true
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		delete(s.watchers, wt)
+		s.lock.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-wt.events:
+			b, err := json.Marshal(e)
+			if err != nil {
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Op, b)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,161 @@
+package server_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"moehl.dev/trie"
+	"moehl.dev/trie/server"
+)
+
+func TestServer(t *testing.T) {
+	tr := trie.New[string]("/")
+	tr.Put("users/1/name", "alice")
+	s := httptest.NewServer(server.New(tr))
+	defer s.Close()
+
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	if status, body := do("GET", "/v1/keys/users/1/name", ""); status != 200 || body != `"alice"` {
+		t.Errorf("expected alice but got %d %q", status, body)
+	}
+	if status, _ := do("GET", "/v1/keys/users/1", ""); status != 404 {
+		t.Errorf("expected 404 for a node without a value but got %d", status)
+	}
+	if status, _ := do("PUT", "/v1/keys/users/2/name", `"bob"`); status != 204 {
+		t.Errorf("expected 204 but got %d", status)
+	}
+	if v, _ := tr.Get("users/2/name"); v != "bob" {
+		t.Errorf("expected bob but got %q", v)
+	}
+	if status, _ := do("PUT", "/v1/keys/users/3", `not json`); status != 400 {
+		t.Errorf("expected 400 for an invalid value but got %d", status)
+	}
+
+	status, body := do("GET", "/v1/list?prefix=users/&delimiter=/", "")
+	var list struct {
+		Keys           []string
+		CommonPrefixes []string
+		Truncated      bool
+	}
+	if err := json.Unmarshal([]byte(body), &list); status != 200 || err != nil {
+		t.Fatalf("unexpected response %d %q: %v", status, body, err)
+	}
+	if len(list.Keys) != 0 || !reflect.DeepEqual(list.CommonPrefixes, []string{"users/1/", "users/2/"}) {
+		t.Errorf("unexpected list %+v", list)
+	}
+
+	if status, _ := do("DELETE", "/v1/keys/users/1", ""); status != 204 {
+		t.Errorf("expected 204 but got %d", status)
+	}
+	if _, found := tr.Get("users/1/name"); found {
+		t.Errorf("expected users/1 to be deleted")
+	}
+	if status, _ := do("POST", "/v1/list", ""); status != 405 {
+		t.Errorf("expected 405 but got %d", status)
+	}
+}
+
+func TestServerWatch(t *testing.T) {
+	tr := trie.New[int]("/")
+	srv := server.New(tr)
+	s := httptest.NewServer(srv)
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "/v1/watch?prefix=a/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The headers are flushed once the watcher is registered.
+	_ = srv.Put("b/1", 1)
+	_ = srv.Put("a/1", 2)
+	srv.Delete("a")
+	srv.Delete("a/1")
+
+	r := bufio.NewReader(resp.Body)
+	var events []server.Event
+	for len(events) < 3 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var e server.Event
+			if err := json.Unmarshal([]byte(data), &e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			events = append(events, e)
+		}
+	}
+	expected := []server.Event{{Op: "put", Path: "a/1", Value: []byte("2")}, {Op: "delete", Path: "a"}, {Op: "delete", Path: "a/1"}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %+v but got %+v", expected, events)
+	}
+}
+
+func TestServerWatchOrder(t *testing.T) {
+	tr := trie.New[int]("/")
+	srv := server.New(tr)
+	s := httptest.NewServer(srv)
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "/v1/watch?prefix=a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The last event must carry the value that has been put last.
+	const n = 32
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = srv.Put("a", i)
+		}(i)
+	}
+	wg.Wait()
+
+	r := bufio.NewReader(resp.Body)
+	var last server.Event
+	for i := 0; i < n; {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if err := json.Unmarshal([]byte(data), &last); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			i++
+		}
+	}
+	value, _ := tr.Get("a")
+	if expected := strconv.Itoa(value); string(last.Value) != expected {
+		t.Errorf("expected last event to have value %s but got %s", expected, last.Value)
+	}
+}
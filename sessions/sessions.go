@@ -0,0 +1,240 @@
+// Package sessions implements an expiring session store for web
+// applications. Sessions are stored in a String trie under the user they
+// belong to, so all sessions of a user are removed at once by deleting a
+// single node.
+package sessions
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"moehl.dev/trie"
+)
+
+// delimiter and escape are used for the keys of the trie, user and session
+// ids may contain the delimiter since they are escaped.
+const (
+	delimiter = "/"
+	escape    = '\\'
+)
+
+// ErrEmptyID is returned by Create if the user or session id is empty.
+var ErrEmptyID = errors.New("sessions: empty user or session id")
+
+// Reason why a session has been removed.
+type Reason uint8
+
+const (
+	// Expired sessions have not been used for longer than the TTL.
+	Expired Reason = iota
+	// Deleted sessions have been removed by Delete or DeleteUser.
+	Deleted
+	// Replaced sessions have been overwritten by Create.
+	Replaced
+)
+
+// Store contains the sessions of all users. A Store is safe for concurrent
+// use.
+type Store[V any] struct {
+	ttl   time.Duration
+	clock func() time.Time
+
+	lock     sync.Mutex
+	sessions trie.String[*session[V]]
+	// expiry orders all sessions by their expiry.
+	expiry expiryHeap[V]
+	// reason is passed to onEvict for the sessions removed from the trie
+	// by the current operation.
+	reason  Reason
+	onEvict func(user, id string, value V, reason Reason)
+}
+
+type session[V any] struct {
+	user, id string
+	value    V
+	expires  time.Time
+	// index is the position of the session in the expiry heap.
+	index int
+}
+
+// New creates an empty Store whose sessions expire if they are not used for
+// ttl. If clock is nil, time.Now is used.
+func New[V any](ttl time.Duration, clock func() time.Time) *Store[V] {
+	if clock == nil {
+		clock = time.Now
+	}
+	s := &Store[V]{ttl: ttl, clock: clock}
+	s.sessions = trie.New[*session[V]](delimiter, trie.WithEscape(escape), trie.WithEvict(func(_ string, sn *session[V]) {
+		heap.Remove(&s.expiry, sn.index)
+		if s.onEvict != nil {
+			s.onEvict(sn.user, sn.id, sn.value, s.reason)
+		}
+	}))
+	return s
+}
+
+// OnEvict sets a function that is called for every session that is removed,
+// e.g. to release resources held by it. It is called while the store is
+// locked and must not access the store.
+func (s *Store[V]) OnEvict(fn func(user, id string, value V, reason Reason)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onEvict = fn
+}
+
+func key(user, id string) string {
+	return trie.EscapeSegment(user, delimiter, escape) + delimiter + trie.EscapeSegment(id, delimiter, escape)
+}
+
+// Create stores a new session of user, replacing an existing one with the
+// same id. Neither user nor id may be empty.
+func (s *Store[V]) Create(user, id string, value V) error {
+	if user == "" || id == "" {
+		return ErrEmptyID
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sn := &session[V]{user: user, id: id, value: value, expires: s.clock().Add(s.ttl)}
+	s.reason = Replaced
+	s.sessions.Put(key(user, id), sn)
+	heap.Push(&s.expiry, sn)
+	return nil
+}
+
+// Get returns the value of the session and extends its lifetime. Expired
+// sessions are removed and not found.
+func (s *Store[V]) Get(user, id string) (value V, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	k := key(user, id)
+	sn, _ := s.sessions.Get(k)
+	if sn == nil {
+		return value, false
+	}
+	now := s.clock()
+	if !now.Before(sn.expires) {
+		s.reason = Expired
+		s.remove(user, id)
+		return value, false
+	}
+	sn.expires = now.Add(s.ttl)
+	heap.Fix(&s.expiry, sn.index)
+	return sn.value, true
+}
+
+// Delete removes the session.
+func (s *Store[V]) Delete(user, id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.reason = Deleted
+	s.remove(user, id)
+}
+
+// remove deletes the session and the node of its user if it was the last
+// session of the user, so that the trie does not keep a node for every user
+// that has ever had a session.
+func (s *Store[V]) remove(user, id string) {
+	s.sessions.Delete(key(user, id))
+	u := trie.EscapeSegment(user, delimiter, escape)
+	if children, _ := s.sessions.Children(u); len(children) == 0 {
+		s.sessions.Delete(u)
+	}
+}
+
+// DeleteUser removes all sessions of user and returns how many there were.
+func (s *Store[V]) DeleteUser(user string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if user == "" {
+		return 0
+	}
+	before := s.expiry.Len()
+	s.reason = Deleted
+	s.sessions.Delete(trie.EscapeSegment(user, delimiter, escape))
+	return before - s.expiry.Len()
+}
+
+// Sessions returns the ids of the sessions of user, including expired ones
+// that have not been removed yet.
+func (s *Store[V]) Sessions(user string) []string {
+	if user == "" {
+		return nil
+	}
+	var ids []string
+	trie.Namespace(s.sessions, trie.EscapeSegment(user, delimiter, escape)).Walk(func(_ string, sn *session[V]) bool {
+		ids = append(ids, sn.id)
+		return true
+	})
+	return ids
+}
+
+// Len returns the number of sessions.
+func (s *Store[V]) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.expiry.Len()
+}
+
+// Expire removes all expired sessions and returns how many there were.
+func (s *Store[V]) Expire() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock()
+	s.reason = Expired
+	n := 0
+	for s.expiry.Len() > 0 && !now.Before(s.expiry[0].expires) {
+		sn := s.expiry[0]
+		s.remove(sn.user, sn.id)
+		n++
+	}
+	return n
+}
+
+// Run calls Expire every interval until ctx is done.
+func (s *Store[V]) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Expire()
+		}
+	}
+}
+
+// expiryHeap is a min-heap of sessions ordered by their expiry.
+type expiryHeap[V any] []*session[V]
+
+func (h expiryHeap[V]) Len() int           { return len(h) }
+func (h expiryHeap[V]) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+
+func (h expiryHeap[V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[V]) Push(x any) {
+	sn := x.(*session[V])
+	sn.index = len(*h)
+	*h = append(*h, sn)
+}
+
+func (h *expiryHeap[V]) Pop() any {
+	old := *h
+	sn := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return sn
+}
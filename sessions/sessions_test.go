@@ -0,0 +1,68 @@
+package sessions_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"moehl.dev/trie/sessions"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := sessions.New[string](time.Minute, func() time.Time { return now })
+	var evicted []string
+	s.OnEvict(func(user, id, value string, reason sessions.Reason) {
+		evicted = append(evicted, fmt.Sprintf("%s/%s=%s:%d", user, id, value, reason))
+	})
+
+	_ = s.Create("alice", "a1", "x")
+	_ = s.Create("alice", "a2", "y")
+	_ = s.Create("alice/admin", "b1", "z")
+	_ = s.Create("bob", "b1", "w")
+	_ = s.Create("bob", "b1", "v")
+	if err := s.Create("", "c1", ""); !errors.Is(err, sessions.ErrEmptyID) {
+		t.Errorf("expected ErrEmptyID but got %v", err)
+	}
+
+	ids := s.Sessions("alice")
+	sort.Strings(ids)
+	if !reflect.DeepEqual(ids, []string{"a1", "a2"}) {
+		t.Errorf("expected [a1 a2] but got %v", ids)
+	}
+
+	// a1 is used after 30 seconds, so it expires after the others.
+	now = now.Add(30 * time.Second)
+	if v, ok := s.Get("alice", "a1"); !ok || v != "x" {
+		t.Errorf("expected x but got %q, %v", v, ok)
+	}
+	now = now.Add(45 * time.Second)
+	if _, ok := s.Get("alice", "a2"); ok {
+		t.Errorf("expected a2 to be expired")
+	}
+	if n := s.Expire(); n != 2 {
+		t.Errorf("expected 2 expired sessions but got %d", n)
+	}
+	if n := s.DeleteUser("alice"); n != 1 {
+		t.Errorf("expected 1 session of alice but got %d", n)
+	}
+	if s.Len() != 0 {
+		t.Errorf("expected no sessions left but got %d", s.Len())
+	}
+
+	// Sessions expiring at the same time are removed in no particular order.
+	sort.Strings(evicted[2:4])
+	expected := []string{
+		"bob/b1=w:2",
+		"alice/a2=y:0",
+		"alice/admin/b1=z:0",
+		"bob/b1=v:0",
+		"alice/a1=x:1",
+	}
+	if !reflect.DeepEqual(evicted, expected) {
+		t.Errorf("expected %v but got %v", expected, evicted)
+	}
+}
@@ -0,0 +1,61 @@
+package trie
+
+// Union returns a trie containing the paths of a and b. If a path is present
+// in both, its value is the result of merge. If merge is nil, the value of b
+// is used.
+//
+// Union, Intersect and Subtract treat a trie as the set of paths that have a
+// value set by Put, nodes that have only been created as part of a longer path
+// are ignored. The result is a new trie with the delimiter of a which is
+// created with opts. The options should match those of a and b since paths
+// are passed between the tries as strings, e.g. the same escape rune has to be
+// used. Neither a nor b is modified, concurrent writes to them may or may not
+// be part of the result.
+func Union[V any](a, b String[V], merge func(path string, a, b V) V, opts ...Option) String[V] {
+	t := New[V](a.Delimiter(), opts...)
+	a.Walk(func(path string, value V) bool {
+		t.Put(path, value)
+		return true
+	})
+	b.Walk(func(path string, value V) bool {
+		if merge != nil {
+			if old, state := t.Lookup(path); state == Exists {
+				value = merge(path, old, value)
+			}
+		}
+		t.Put(path, value)
+		return true
+	})
+	return t
+}
+
+// Intersect returns a trie containing the paths of a that are also present in
+// b, together with their values from a. See Union for details.
+func Intersect[V any](a, b String[V], opts ...Option) String[V] {
+	return filter(a, opts, func(path string) bool {
+		_, state := b.Lookup(path)
+		return state == Exists
+	})
+}
+
+// Subtract returns a trie containing the paths of a that are not present in
+// b, together with their values from a. See Union for details.
+func Subtract[V any](a, b String[V], opts ...Option) String[V] {
+	return filter(a, opts, func(path string) bool {
+		_, state := b.Lookup(path)
+		return state != Exists
+	})
+}
+
+// filter returns a new trie containing the values of a whose path is accepted
+// by keep.
+func filter[V any](a String[V], opts []Option, keep func(path string) bool) String[V] {
+	t := New[V](a.Delimiter(), opts...)
+	a.Walk(func(path string, value V) bool {
+		if keep(path) {
+			t.Put(path, value)
+		}
+		return true
+	})
+	return t
+}
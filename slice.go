@@ -1,7 +1,7 @@
 package trie
 
 import (
-	"sync"
+	"fmt"
 )
 
 type Slice[K comparable, V any] interface {
@@ -11,86 +11,97 @@ type Slice[K comparable, V any] interface {
 	// the trie which does not necessarily mean that the value is meaningful.
 	// If you access a node that was created as part of a longer path the value
 	// might be the default value of type V as it was not explicitly set.
+	// The root is only found once a value has been set for it with Put(nil).
+	// Get does not allocate.
 	Get(path []K) (value V, found bool)
+	// LongestPrefix returns the value of the longest prefix of path, which
+	// may be path itself, that has a value set by Put. n is the length of
+	// that prefix. LongestPrefix does not allocate.
+	LongestPrefix(path []K) (n int, value V, found bool)
 	// Delete the node at the given path (including all of its children). If
-	// the node does not exist, delete does not modify the trie. Delete does
-	// not check if the intermediate nodes can be garbage collected as it
-	// cannot reliably determine if a value has been set or not.
-	// TODO: Would it be desirable to track which nodes have values assigned
-	//  and which haven't to be able to garbage collect?
+	// the node does not exist, delete does not modify the trie. The empty path
+	// refers to the root, deleting it only removes the value of the root and
+	// keeps its children. Delete does not remove intermediate nodes that are
+	// no longer needed, use Compact for that.
 	Delete(path []K)
+	// Compact reclaims memory after large deletions. Nodes that neither
+	// have a value set by Put nor children with a value are removed, so Get
+	// no longer finds them afterwards. Concurrent operations are blocked on
+	// the parts of the trie that are being compacted.
+	Compact()
+
+	// Stringer returns a summary of the trie.
+	fmt.Stringer
 }
 
+// sliceTrie stores the paths in a path-compressed tree, see node for details.
 type sliceTrie[K comparable, V any] struct {
-	lock     *sync.RWMutex
-	children map[K]*sliceTrie[K, V]
-
-	value V
+	tree[K, V]
 }
 
-func NewSlice[K comparable, V any]() Slice[K, V] {
-	return newSliceTrie[K, V]()
+// NewSlice creates a new trie for paths of type []K. Of the options only
+// WithPooling, WithArena and WithInstrumentation apply.
+func NewSlice[K comparable, V any](opts ...Option) Slice[K, V] {
+	t := newSliceTrie[K, V]()
+	applyTree(&t.tree, newOptions(opts))
+	return t
 }
 
 func newSliceTrie[K comparable, V any]() *sliceTrie[K, V] {
 	return &sliceTrie[K, V]{
-		lock:     new(sync.RWMutex),
-		children: make(map[K]*sliceTrie[K, V]),
+		tree: newTree[K, V](),
 	}
 }
 
 func (t *sliceTrie[K, V]) Put(path []K, value V) {
-	if len(path) == 0 {
-		t.value = value
-		return
-	}
-
-	t.lock.Lock()
-	child, ok := t.children[path[0]]
-	if !ok {
-		child = newSliceTrie[K, V]()
-		t.children[path[0]] = child
-	}
-	t.lock.Unlock()
-
-	child.Put(path[1:], value)
+	t.put(path, value, true)
 }
 
 func (t *sliceTrie[K, V]) Get(path []K) (value V, found bool) {
-	if len(path) == 0 {
-		return t.value, true
+	c := t.cursor()
+	for _, key := range path {
+		if !c.next(key) {
+			t.reportGet(&c, false)
+			return value, false
+		}
 	}
 
-	t.lock.RLock()
-	child, ok := t.children[path[0]]
-	t.lock.RUnlock()
-	if !ok {
-		return value, false
-	}
+	value, hasValue := c.value()
+	c.close()
+	found = hasValue || len(path) > 0
+	t.reportGet(&c, found)
+	return value, found
+}
 
-	return child.Get(path[1:])
+func (t *sliceTrie[K, V]) LongestPrefix(path []K) (n int, value V, found bool) {
+	c := t.cursor()
+	for i := 0; ; i++ {
+		if v, hasValue := c.value(); hasValue {
+			n, value, found = i, v, true
+		}
+		if i == len(path) {
+			c.close()
+			break
+		}
+		if !c.next(path[i]) {
+			break
+		}
+	}
+	t.reportGet(&c, found)
+	return n, value, found
 }
 
 func (t *sliceTrie[K, V]) Delete(path []K) {
 	if len(path) == 0 {
-		panic("trie: cannot delete self")
-	}
-
-	if len(path) == 1 {
-		t.lock.Lock()
-		delete(t.children, path[0])
-		t.lock.Unlock()
-
+		t.clearRoot()
 		return
 	}
 
-	t.lock.RLock()
-	child, ok := t.children[path[0]]
-	t.lock.RUnlock()
-
-	if !ok {
-		return
+	if n, removed := t.remove(path); n != nil {
+		t.discard(removed, n)
 	}
+}
 
-	child.Delete(path[1:])
+func (t *sliceTrie[K, V]) Compact() {
+	t.compact()
 }
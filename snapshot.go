@@ -0,0 +1,87 @@
+package trie
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+)
+
+// SaveSnapshot writes t in the binary format to the file at path. The snapshot
+// is written to a temporary file in the same directory first which then
+// replaces path atomically, so path always contains a complete snapshot.
+func SaveSnapshot[V any](t String[V], path string) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	_, err = t.WriteTo(f)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir makes the rename durable on systems that support syncing
+// directories. Errors are ignored as not all systems support it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot and returns a new trie
+// using the delimiter stored in the snapshot.
+func LoadSnapshot[V any](path string) (String[V], error) {
+	return LoadSnapshotCodec[V](path, nil)
+}
+
+// LoadSnapshotCodec is like LoadSnapshot but uses codec to decode the values.
+// The returned trie uses codec as well.
+func LoadSnapshotCodec[V any](path string, codec ValueCodec[V]) (String[V], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := &binaryReader{r: bufio.NewReader(f)}
+
+	delimiter, version, err := br.readHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	t := newStringTrie[V](delimiter)
+	t.codec = codecOrDefault(codec)
+	_, err = t.readBody(br, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
@@ -1,53 +1,303 @@
 package trie
 
 import (
+	"fmt"
+	"hash/maphash"
+	"io"
+	"log/slog"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"golang.org/x/text/collate"
 )
 
 // String is a trie based on string paths delimited by a given delimiter. It is
 // safe for concurrent reads and writes, although it does not guarantee that
-// they are executed in a deterministic order. This can result in lost writes
-// if a path is concurrently written and deleted.
+// they are executed in a deterministic order. All operations descend the trie
+// using lock coupling, i.e. the lock of a child is acquired before the lock of
+// its parent is released. A Put that races with a Delete of one of its
+// prefixes therefore either happens before the Delete and is removed by it or
+// happens after it, it is never written into a subtree that has already been
+// detached. Operations on disjoint subtrees only contend on the locks of their
+// common ancestors.
 type String[V any] interface {
-	// Put a new key into the trie. The path is split at the delimiter.
+	// Put a new key into the trie. The path is split at the delimiter. The
+	// empty path refers to the root of the trie. Paths rejected by the key
+	// validator or exceeding the limits are ignored, see WithKeyValidator,
+	// WithMaxDepth and WithMaxKeyLen.
 	Put(path string, value V)
+	// PutE is like Put but returns an error wrapping ErrInvalidKey and, if
+	// any, the error of the key validator if the path is rejected.
+	PutE(path string, value V) error
+	// CheckPath returns the error for the first segment of path that is
+	// rejected or nil if the path can be used with Put.
+	CheckPath(path string) error
 	// Get the value at a path. `found` indicates whether the node exists in
 	// the trie which does not necessarily mean that the value is meaningful.
 	// If you access a node that was created as part of a longer path the value
 	// might be the default value of type V as it was not explicitly set.
+	// The root is only found once a value has been set for it with Put("").
+	// Get does not allocate unless WithAccessStats is used.
 	Get(path string) (value V, found bool)
+	// GetE is like Get but returns an error wrapping ErrNotFound instead of
+	// `found`.
+	GetE(path string) (V, error)
+	// Lookup is like Get but distinguishes nodes with a value set by Put from
+	// nodes that only exist as part of a longer path. The root is reported as
+	// ImplicitNode as long as no value has been set for it.
+	Lookup(path string) (V, LookupState)
+	// PutSegments is like Put but takes the path already split into segments.
+	// The segments are used as they are, so they may contain the delimiter,
+	// but such nodes are only reachable through the other methods if escaping
+	// is enabled, see WithEscape. No segments refer to the root.
+	PutSegments(value V, segments ...string)
+	// GetSegments is like Get but takes the path already split into segments,
+	// see PutSegments.
+	GetSegments(segments ...string) (value V, found bool)
+	// GetBatch looks up all paths at once and stores their values in out,
+	// which must have at least len(paths) elements. The returned slice
+	// indicates for each path whether the node exists, just like `found` of
+	// Get. Paths that share a prefix descend it only once and the lock of each
+	// node is acquired at most once per batch.
+	GetBatch(paths []string, out []V) []bool
 	// Delete the node at the given path (including all of its children). If
-	// the node does not exist, delete does not modify the trie. Delete does
-	// not check if the intermediate nodes can be garbage collected as it
-	// cannot reliably determine if a value has been set or not.
-	// TODO: Would it be desirable to track which nodes have values assigned
-	//  and which haven't to be able to garbage collect?
+	// the node does not exist, delete does not modify the trie. The empty path
+	// refers to the root, deleting it only removes the value of the root and
+	// keeps its children. Delete does not remove intermediate nodes that are
+	// no longer needed, use Compact for that.
 	Delete(path string)
+	// DeleteE is like Delete but returns an error wrapping ErrNotFound if the
+	// node does not exist, or for the root, if it has no value.
+	DeleteE(path string) error
+	// Clear removes all nodes and the value of the root node.
+	Clear()
+	// Compact reclaims memory after large deletions. Nodes that neither
+	// have a value set by Put nor children with a value are removed, so Get
+	// no longer finds them afterwards. Concurrent operations are blocked on
+	// the parts of the trie that are being compacted.
+	Compact()
+	// Rekey moves every value to the path returned by fn for its current
+	// path, values for which fn returns false are removed. The new trie is
+	// built aside and replaces the contents of the trie at once, concurrent
+	// operations wait until it is done. If a new path is rejected or two
+	// values are moved to the same path, the trie is left unchanged and an
+	// error is returned. Values are reported to WithEvict and the onDelete
+	// hook only if they are removed. Nodes without a value are not kept. fn
+	// must not access the trie.
+	Rekey(fn func(oldPath string) (newPath string, keep bool)) error
 	// Delimiter that has been specified on creation of the trie.
 	Delimiter() string
+	// Codec used to encode and decode values when serializing the trie.
+	Codec() ValueCodec[V]
+	// Walk calls fn for every path that has a value set by Put, in sorted
+	// order, see WithCollation. Walking stops if fn returns false.
+	Walk(fn func(path string, value V) bool)
+	// WalkFrom is like Walk but starts at the first path that does not sort
+	// before from, so that an interrupted walk can be resumed without
+	// visiting the paths before it again.
+	WalkFrom(from string, fn func(path string, value V) bool)
+	// Iterator returns an iterator over all paths that have a value set by
+	// Put in the order of Walk. The iterator can be repositioned by Seek
+	// like WalkFrom.
+	Iterator() *Iterator[V]
+	// WalkPath calls fn for every node along path, starting at the root and
+	// ending at the node at path, that has a value set by Put. The path
+	// passed to fn is the prefix of path leading to the node. The values are
+	// collected before fn is called, so fn may access the trie. Walking
+	// stops if fn returns false.
+	WalkPath(path string, fn func(path string, value V) bool)
+	// GetShortestPrefix returns the value of the first node along path,
+	// starting at the root, that has a value set by Put, and the prefix of
+	// path leading to it. It stops at the first hit, unlike WalkPath which
+	// visits every node along path.
+	GetShortestPrefix(path string) (value V, prefix string, found bool)
+	// ContainsPrefixOf reports whether a node along path, including the
+	// root and the node at path, has a value set by Put. HasKeysWithPrefix
+	// reports whether the node at path or any node below it has a value set
+	// by Put. Both stop at the first hit.
+	ContainsPrefixOf(path string) bool
+	HasKeysWithPrefix(path string) bool
+	// ShortestKeys returns up to k paths with a value set by Put that have
+	// the fewest segments, LongestKeys the ones that have the most. Paths
+	// are ordered by their number of segments, ascending for ShortestKeys
+	// and descending for LongestKeys, and paths with the same number of
+	// segments in the order of Walk.
+	ShortestKeys(k int) []string
+	LongestKeys(k int) []string
+	// Meta returns when the value at path has been set by Put for the first
+	// time and when it has been set most recently. ok is false if path has
+	// no value or WithTimestamps is not used. Meta may be called by the
+	// function passed to Walk, e.g. to find values that have not been
+	// updated for some time.
+	Meta(path string) (createdAt, updatedAt time.Time, ok bool)
+	// HotKeys returns up to k paths that have been read most often within
+	// the window of WithAccessStats, ordered by descending number of reads
+	// and then by path. Paths that have been deleted are reported until
+	// their reads leave the window. It returns nil if WithAccessStats is not
+	// used.
+	HotKeys(k int) []HotKey
+	// Sample returns up to n distinct paths with a value set by Put, drawn
+	// uniformly at random, in the order of Walk. It walks the whole trie
+	// unless WithSubtreeCounts is used. SampleWeighted is like Sample but
+	// draws each path with a probability proportional to the weight of its
+	// value, paths whose weight is not positive are never drawn. It always
+	// walks the whole trie.
+	Sample(n int) []string
+	SampleWeighted(n int, weight func(V) float64) []string
+	// GetLongestSuffix returns the value of the path with the most segments
+	// that has a value set by Put and whose segments are the last segments
+	// of path, along with that path. SuffixKeys returns all paths with a
+	// value whose last segments are the segments of suffix, in byte order.
+	// Both walk the whole trie unless WithSuffixIndex is used.
+	GetLongestSuffix(path string) (value V, suffix string, found bool)
+	SuffixKeys(suffix string) []string
+	// List returns the paths with a value set by Put that start with prefix,
+	// like the ListObjectsV2 operation of S3. The prefix does not have to end
+	// at a delimiter. If delimiter is not empty, paths containing it after
+	// the prefix are rolled up into common prefixes up to and including its
+	// first occurrence, which may differ from the delimiter of the trie. Only
+	// paths and common prefixes that sort after startAfter are returned, in
+	// byte order, up to maxKeys of them in total if maxKeys is greater than
+	// zero. truncated indicates whether there are more results, they can be
+	// listed by passing the last path or common prefix as startAfter. The
+	// root is never listed.
+	List(prefix, delimiter string, maxKeys int, startAfter string) (keys, commonPrefixes []string, truncated bool)
+	// Children returns the sorted segments of the direct children of the node
+	// at path. `found` indicates whether the node exists.
+	Children(path string) (segments []string, found bool)
+	// ToMap returns all paths that have a value set by Put and their values.
+	ToMap() map[string]V
+	// Root returns a handle to the root of the trie which can be used to
+	// navigate the trie one segment at a time.
+	Root() Node[V]
+
+	// WriterTo writes the trie in a compact binary format.
+	io.WriterTo
+	// ReaderFrom reads a trie in the binary format written by WriteTo and
+	// merges it into the trie.
+	io.ReaderFrom
+
+	// WriteDOT renders the structure of the trie as a DOT graph.
+	WriteDOT(w io.Writer, opts ...DotOption) error
+	// Dump writes a tree(1)-style rendering of the trie.
+	Dump(w io.Writer) error
+	// DumpCanonical returns a rendering of all nodes in a format that
+	// never changes, e.g. for golden files in tests.
+	DumpCanonical() string
+
+	// Validate walks the trie and checks its internal invariants, e.g. in
+	// tests or after reading untrusted input. It returns an *InvariantError
+	// for the first violation.
+	Validate() error
+
+	// Stringer returns a summary of the trie, formatting the trie with %+v
+	// writes the rendering of Dump.
+	fmt.Stringer
 }
 
 // stringTrie is the underlying implementation of a simple string-based trie.
-//
-// The locks are only acquired while the children map is being read or written.
+// The paths are split into segments at the delimiter which are stored in a
+// path-compressed tree, see node for details on the locking.
 type stringTrie[V any] struct {
-	lock     *sync.RWMutex
-	children map[string]*stringTrie[V]
+	tree[string, V]
 
 	delimiter string
-	value     V
+	// escape is used to escape delimiters within segments, 0 disables
+	// escaping.
+	escape rune
+	// trim enables the removal of leading and trailing delimiters.
+	trim bool
+	// transform is applied to every segment if it is not nil, e.g. to
+	// normalize it.
+	transform func(string) string
+	// collator defines the order of segments for Walk and Children if it is
+	// not nil.
+	collator *collator
+	codec    ValueCodec[V]
+	// validator checks all segments passed to Put if it is not nil and those
+	// passed to Get if validateGet is true.
+	validator   func(segment string) error
+	validateGet bool
+	// maxDepth and maxKeyLen limit the number of segments of a path and the
+	// length of each segment when they are greater than zero.
+	maxDepth  int
+	maxKeyLen int
+	// onPut is called by insert after a value has been set if it is not nil.
+	// The hooks for removed values are called by tree.removed.
+	onPut func(path string, old, new V, existed bool)
+	// evict is called for every value that is removed or replaced if it is
+	// not nil.
+	evict func(path string, value V)
+	// tracer starts spans for long running operations if it is not nil.
+	tracer Tracer
+	// logger receives debug messages about structural changes if it is not
+	// nil.
+	logger *slog.Logger
+	// bloom contains the paths of all nodes if it is not nil, it is used to
+	// skip the descent for paths that are not in the trie.
+	bloom *bloomFilter
+	// cache contains the results of recent lookups if it is not nil.
+	cache *lookupCache[V]
+	// suffix contains the reversed paths of all values if it is not nil.
+	suffix *suffixIndex
+	// access counts the reads of each path if it is not nil.
+	access *accessStats
+}
+
+// New creates a new trie that splits paths at delimiter. Its behaviour can be
+// adjusted by opts.
+func New[V any](delimiter string, opts ...Option) String[V] {
+	t := newStringTrie[V](delimiter)
+	applyString(t, newOptions(opts))
+	return t
+}
+
+// NewWithCodec is equivalent to New with WithCodec.
+func NewWithCodec[V any](delimiter string, codec ValueCodec[V]) String[V] {
+	return New[V](delimiter, WithCodec(codec))
+}
+
+// FromMap creates a new trie containing all entries of m.
+func FromMap[V any](m map[string]V, delimiter string, opts ...Option) String[V] {
+	t := New[V](delimiter, opts...)
+	for path, value := range m {
+		t.Put(path, value)
+	}
+	return t
+}
+
+// NewPooled is equivalent to New with WithPooling.
+func NewPooled[V any](delimiter string) String[V] {
+	return New[V](delimiter, WithPooling())
+}
+
+// NewArena is equivalent to New with WithArena.
+func NewArena[V any](delimiter string) String[V] {
+	return New[V](delimiter, WithArena())
+}
+
+// NewWithBloomFilter is equivalent to New with WithBloomFilter.
+func NewWithBloomFilter[V any](delimiter string, nodes int, falsePositiveRate float64) String[V] {
+	return New[V](delimiter, WithBloomFilter(nodes, falsePositiveRate))
+}
+
+// NewWithCache is equivalent to New with WithCache.
+func NewWithCache[V any](delimiter string, size int) String[V] {
+	return New[V](delimiter, WithCache(size))
 }
 
-func New[V any](delimiter string) String[V] {
-	return newStringTrie[V](delimiter)
+// NewInstrumented is equivalent to New with WithInstrumentation.
+func NewInstrumented[V any](delimiter string, inst Instrumentation) String[V] {
+	return New[V](delimiter, WithInstrumentation(inst))
 }
 
 func newStringTrie[V any](delimiter string) *stringTrie[V] {
 	return &stringTrie[V]{
-		lock:      new(sync.RWMutex),
-		children:  make(map[string]*stringTrie[V]),
+		tree:      newTree[string, V](),
 		delimiter: delimiter,
+		codec:     DefaultCodec[V]{},
 	}
 }
 
@@ -55,60 +305,757 @@ func (t *stringTrie[V]) Delimiter() string {
 	return t.delimiter
 }
 
+func (t *stringTrie[V]) Codec() ValueCodec[V] {
+	return t.codec
+}
+
+// cut returns the first segment of path and the rest of the path.
+func (t *stringTrie[V]) cut(path string) (key, rest string) {
+	if t.escape != 0 {
+		key, rest = cutEscaped(path, t.delimiter, t.escape)
+	} else {
+		key, rest, _ = strings.Cut(path, t.delimiter)
+	}
+	if t.transform != nil {
+		key = t.transform(key)
+	}
+	return key, rest
+}
+
+// escapeSegments returns segments with all segments escaped if escaping is
+// enabled.
+func (t *stringTrie[V]) escapeSegments(segments []string) []string {
+	if t.escape == 0 {
+		return segments
+	}
+
+	escaped := make([]string, len(segments))
+	for i, key := range segments {
+		escaped[i] = EscapeSegment(key, t.delimiter, t.escape)
+	}
+	return escaped
+}
+
+// normalize removes all leading and trailing delimiters from path if trimming
+// is enabled. Escaped trailing delimiters are kept.
+func (t *stringTrie[V]) normalize(path string) string {
+	if !t.trim {
+		return path
+	}
+
+	for strings.HasPrefix(path, t.delimiter) {
+		path = path[len(t.delimiter):]
+	}
+	for strings.HasSuffix(path, t.delimiter) {
+		trimmed := path[:len(path)-len(t.delimiter)]
+		if t.escape != 0 && escapedSuffix(trimmed, t.escape) {
+			break
+		}
+		path = trimmed
+	}
+	return path
+}
+
+// segments splits path at the delimiter. The empty path has no segments.
+func (t *stringTrie[V]) segments(path string) []string {
+	var segments []string
+	for path != "" {
+		var key string
+		key, path = t.cut(path)
+		segments = append(segments, key)
+	}
+	return segments
+}
+
 func (t *stringTrie[V]) Put(path string, value V) {
-	if path == "" {
-		t.value = value
+	_ = t.PutE(path, value)
+}
+
+func (t *stringTrie[V]) PutE(path string, value V) error {
+	segments := t.segments(t.normalize(path))
+	err := t.checkSegments(segments)
+	if err != nil {
+		return err
+	}
+	t.insert(segments, value, true)
+	return nil
+}
+
+func (t *stringTrie[V]) PutSegments(value V, segments ...string) {
+	if t.transform != nil {
+		transformed := make([]string, len(segments))
+		for i, key := range segments {
+			transformed[i] = t.transform(key)
+		}
+		segments = transformed
+	}
+	if t.checkSegments(segments) != nil {
 		return
 	}
+	t.insert(segments, value, true)
+}
 
-	key, path, _ := strings.Cut(path, t.delimiter)
+func (t *stringTrie[V]) CheckPath(path string) error {
+	return t.checkSegments(t.segments(t.normalize(path)))
+}
 
-	t.lock.Lock()
-	child, ok := t.children[key]
-	if !ok {
-		child = newStringTrie[V](t.delimiter)
-		t.children[key] = child
+// checkSegments checks the depth of segments and passes all segments to
+// checkSegment.
+func (t *stringTrie[V]) checkSegments(segments []string) error {
+	err := t.checkDepth(len(segments))
+	if err != nil {
+		return err
 	}
-	t.lock.Unlock()
+	for _, key := range segments {
+		err = t.checkSegment(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	child.Put(path, value)
+func (t *stringTrie[V]) checkDepth(depth int) error {
+	if t.maxDepth > 0 && depth > t.maxDepth {
+		return fmt.Errorf("%w: depth %d exceeds limit of %d", ErrInvalidKey, depth, t.maxDepth)
+	}
+	return nil
 }
 
-func (t *stringTrie[V]) Get(path string) (value V, found bool) {
-	if path == "" {
-		return t.value, true
+// checkSegment checks the length of key and passes it to the key validator,
+// if any.
+func (t *stringTrie[V]) checkSegment(key string) error {
+	if t.maxKeyLen > 0 && len(key) > t.maxKeyLen {
+		return fmt.Errorf("%w: segment of length %d exceeds limit of %d", ErrInvalidKey, len(key), t.maxKeyLen)
+	}
+	if t.validator == nil {
+		return nil
+	}
+	err := t.validator(key)
+	if err != nil {
+		return fmt.Errorf("%w: segment %q: %w", ErrInvalidKey, key, err)
+	}
+	return nil
+}
+
+// insert puts the node at segments into the tree and adds it to the Bloom
+// filter, if any. All insertions into a stringTrie must go through insert.
+func (t *stringTrie[V]) insert(segments []string, value V, set bool) {
+	var (
+		old     V
+		existed bool
+	)
+	index := t.suffix != nil && set
+	if index {
+		t.suffix.lock.Lock()
+	}
+	t.lockCounts()
+	old, existed = t.put(segments, value, set)
+	// The bits are only set once the node is part of the tree. Clear and
+	// Rekey reset the filter before they remove the nodes, so they can not
+	// reset the bits of a node that remains in the tree.
+	t.addToBloom(segments)
+	if t.counts != nil && set && !existed {
+		t.addCount(segments, 1)
+	}
+	assertCounts(&t.tree, segments)
+	t.unlockCounts()
+	if index {
+		t.suffix.keys.put(reversed(segments), struct{}{}, true)
+		t.suffix.lock.Unlock()
+	}
+	if t.cache != nil {
+		t.cache.invalidate(t.escapeSegments(segments), t.delimiter)
+	}
+	if !set || t.onPut == nil && (t.evict == nil || !existed) {
+		return
+	}
+	path := t.joinSegments(segments)
+	if t.onPut != nil {
+		t.onPut(path, old, value, existed)
+	}
+	if t.evict != nil && existed {
+		t.evict(path, old)
 	}
+}
 
-	key, path, _ := strings.Cut(path, t.delimiter)
+// canonical returns path in the form it is passed to Walk, i.e. normalized and
+// with all segments escaped and joined by the delimiter.
+func (t *stringTrie[V]) canonical(path string) string {
+	return t.joinSegments(t.segments(t.normalize(path)))
+}
 
-	t.lock.RLock()
-	child, ok := t.children[key]
-	t.lock.RUnlock()
-	if !ok {
+// joinSegments returns the path of segments as it is passed to Walk.
+func (t *stringTrie[V]) joinSegments(segments []string) string {
+	return strings.Join(t.escapeSegments(segments), t.delimiter)
+}
+
+// addToBloom adds the node at segments and all of its parents to the Bloom
+// filter, if any.
+func (t *stringTrie[V]) addToBloom(segments []string) {
+	if t.bloom == nil {
+		return
+	}
+
+	var h maphash.Hash
+	h.SetSeed(t.bloom.seed)
+	for i, key := range segments {
+		if i > 0 {
+			h.WriteString(t.delimiter)
+		}
+		h.WriteString(key)
+		t.bloom.add(h.Sum64())
+	}
+}
+
+// mayContain returns false if the node at path is definitely not part of the
+// trie. The path is hashed segment by segment in the same way as by insert so
+// that paths that are split into the same segments have the same hash.
+func (t *stringTrie[V]) mayContain(path string) bool {
+	if t.bloom == nil || path == "" {
+		return true
+	}
+
+	var h maphash.Hash
+	h.SetSeed(t.bloom.seed)
+	for i := 0; path != ""; i++ {
+		var key string
+		key, path = t.cut(path)
+		if i > 0 {
+			h.WriteString(t.delimiter)
+		}
+		h.WriteString(key)
+	}
+	return t.bloom.mayContain(h.Sum64())
+}
+
+func (t *stringTrie[V]) Get(path string) (value V, found bool) {
+	path = t.normalize(path)
+	value, found = t.getNormalized(path)
+	if found && t.access != nil {
+		t.recordAccess(path)
+	}
+	return value, found
+}
+
+// getNormalized implements Get for a normalized path.
+func (t *stringTrie[V]) getNormalized(path string) (value V, found bool) {
+	if !t.mayContain(path) {
+		if t.inst != nil {
+			t.inst.Get(0, false, 0)
+		}
 		return value, false
 	}
 
-	return child.Get(path)
+	if t.cache == nil {
+		return t.get(path)
+	}
+	key := t.cacheKey(path)
+	if key == "" {
+		return t.get(path)
+	}
+
+	value, found, ok, gen := t.cache.get(key)
+	if ok {
+		if t.inst != nil {
+			t.inst.Get(0, found, 0)
+		}
+		return value, found
+	}
+	value, found = t.get(path)
+	t.cache.store(key, value, found, gen)
+	return value, found
+}
+
+// cacheKey returns the escaped segments of path joined by the delimiter.
+// Without escaping and transformation only a final empty segment is dropped
+// by segments, so this is path without a trailing delimiter. The root and the
+// child with the empty key both have the empty key and are never cached.
+func (t *stringTrie[V]) cacheKey(path string) string {
+	if t.escape != 0 || t.transform != nil {
+		return strings.Join(t.escapeSegments(t.segments(path)), t.delimiter)
+	}
+	return strings.TrimSuffix(path, t.delimiter)
+}
+
+func (t *stringTrie[V]) get(path string) (value V, found bool) {
+	root := path == ""
+	c := t.cursor()
+	for path != "" {
+		var key string
+		key, path = t.cut(path)
+		if !t.step(&c, key) {
+			return value, false
+		}
+	}
+	return t.result(&c, root)
+}
+
+func (t *stringTrie[V]) Lookup(path string) (value V, state LookupState) {
+	path = t.normalize(path)
+	value, state = t.lookup(path)
+	if state != Missing && t.access != nil {
+		t.recordAccess(path)
+	}
+	return value, state
+}
+
+// lookup implements Lookup for a normalized path.
+func (t *stringTrie[V]) lookup(path string) (value V, state LookupState) {
+	if !t.mayContain(path) {
+		if t.inst != nil {
+			t.inst.Get(0, false, 0)
+		}
+		return value, Missing
+	}
+
+	c := t.cursor()
+	for path != "" {
+		var key string
+		key, path = t.cut(path)
+		if !t.step(&c, key) {
+			return value, Missing
+		}
+	}
+
+	value, hasValue := c.value()
+	c.close()
+	t.reportGet(&c, true)
+	if !hasValue {
+		return value, ImplicitNode
+	}
+	return value, Exists
+}
+
+func (t *stringTrie[V]) GetSegments(segments ...string) (value V, found bool) {
+	value, found = t.getSegments(segments)
+	if found && t.access != nil {
+		t.recordSegments(segments)
+	}
+	return value, found
+}
+
+func (t *stringTrie[V]) getSegments(segments []string) (value V, found bool) {
+	c := t.cursor()
+	for _, key := range segments {
+		if t.transform != nil {
+			key = t.transform(key)
+		}
+		if !t.step(&c, key) {
+			return value, false
+		}
+	}
+	return t.result(&c, len(segments) == 0)
+}
+
+// step moves c to the child with the given key. If the key is rejected or the
+// child does not exist, the cursor is closed and the miss is reported.
+func (t *stringTrie[V]) step(c *cursor[string, V], key string) bool {
+	if t.validateGet && t.validator(key) != nil {
+		c.close()
+		t.reportGet(c, false)
+		return false
+	}
+	if !c.next(key) {
+		t.reportGet(c, false)
+		return false
+	}
+	return true
+}
+
+// result closes c and returns the value of the node it points to.
+func (t *stringTrie[V]) result(c *cursor[string, V], root bool) (value V, found bool) {
+	value, hasValue := c.value()
+	c.close()
+	found = hasValue || !root
+	t.reportGet(c, found)
+	return value, found
 }
 
 func (t *stringTrie[V]) Delete(path string) {
-	key, path, _ := strings.Cut(path, t.delimiter)
+	t.delete(path)
+}
 
-	if path == "" {
-		t.lock.Lock()
-		delete(t.children, key)
-		t.lock.Unlock()
+func (t *stringTrie[V]) DeleteE(path string) error {
+	if !t.delete(path) {
+		return fmt.Errorf("%w: %q", ErrNotFound, path)
+	}
+	return nil
+}
 
-		return
+// delete removes the node at path and returns whether it existed. For the
+// root it returns whether it had a value.
+func (t *stringTrie[V]) delete(path string) bool {
+	path = t.normalize(path)
+	segments := t.segments(path)
+	if len(segments) == 0 {
+		if t.suffix == nil && t.counts == nil {
+			return t.clearRoot()
+		}
+		if t.suffix != nil {
+			t.suffix.lock.Lock()
+		}
+		t.lockCounts()
+		old, hadValue := t.takeRoot()
+		if t.counts != nil && hadValue {
+			t.root.count--
+		}
+		assertCounts(&t.tree, nil)
+		t.unlockCounts()
+		if t.suffix != nil {
+			t.suffix.keys.unset(nil)
+			t.suffix.lock.Unlock()
+		}
+		if hadValue && t.removed != nil {
+			t.removed(nil, old)
+		}
+		return hadValue
+	}
+
+	n, removed := t.detach(segments)
+	if n == nil {
+		return false
+	}
+	if t.debugEnabled() {
+		s := viewStats(view[string, V]{n: n})
+		t.logger.Debug("trie: deleted subtree", "path", t.joinSegments(segments), "values", s.Values, "nodes", s.Nodes)
+	}
+	t.discard(removed, n)
+	return true
+}
+
+// detach removes the node reached by following segments from the tree and
+// updates the suffix index, the counts and the cache accordingly. segments
+// must not be empty. It returns the removed subtree and its path like remove.
+func (t *stringTrie[V]) detach(segments []string) (n *node[string, V], removed []string) {
+	if t.suffix != nil {
+		t.suffix.lock.Lock()
+	}
+	t.lockCounts()
+	n, removed = t.remove(segments)
+	if t.counts != nil && n != nil {
+		t.addCount(segments, -n.count)
+	}
+	assertCounts(&t.tree, segments)
+	t.unlockCounts()
+	if t.suffix != nil {
+		if n != nil {
+			t.unindex(view[string, V]{n: n}, removed)
+		}
+		t.suffix.lock.Unlock()
 	}
+	if t.cache != nil {
+		t.cache.invalidateTree(t.escapeSegments(segments), t.delimiter)
+	}
+	return n, removed
+}
+
+func (t *stringTrie[V]) GetE(path string) (V, error) {
+	value, found := t.Get(path)
+	if !found {
+		return value, fmt.Errorf("%w: %q", ErrNotFound, path)
+	}
+	return value, nil
+}
+
+func (t *stringTrie[V]) Clear() {
+	// The filter must be reset before the nodes are removed, see insert.
+	if t.bloom != nil {
+		t.bloom.reset()
+	}
+	if t.suffix != nil {
+		t.suffix.lock.Lock()
+	}
+	t.lockCounts()
+	old := t.clear()
+	t.root.count = 0
+	assertCounts(&t.tree, nil)
+	t.unlockCounts()
+	if t.suffix != nil {
+		t.suffix.keys.clear()
+		t.suffix.lock.Unlock()
+	}
+	if t.cache != nil {
+		t.cache.purge()
+	}
+	if t.debugEnabled() {
+		s := viewStats(view[string, V]{n: old})
+		t.logger.Debug("trie: cleared", "values", s.Values, "nodes", s.Nodes)
+	}
+	t.discard(nil, old)
+}
+
+func (t *stringTrie[V]) Compact() {
+	defer t.startSpan("trie.Compact").End()
 
-	t.lock.RLock()
-	child, ok := t.children[key]
-	t.lock.RUnlock()
+	var before Stats
+	debug := t.debugEnabled()
+	if debug {
+		before = t.stats()
+	}
+	// Compaction does not change the counts but must not release nodes
+	// whose counts are being updated.
+	t.lockCounts()
+	t.compact()
+	assertCounts(&t.tree, nil)
+	t.unlockCounts()
+	if t.suffix != nil {
+		t.suffix.keys.compact()
+	}
+	if t.cache != nil {
+		t.cache.purge()
+	}
+	if debug {
+		t.logger.Debug("trie: compacted", "nodesBefore", before.Nodes, "nodesAfter", t.stats().Nodes)
+	}
+}
+
+func (t *stringTrie[V]) Walk(fn func(path string, value V) bool) {
+	defer t.unpin(t.pin())
+	fn, end := t.traceWalk("", fn)
+	defer end()
+	t.walk(view[string, V]{n: t.root}, "", true, fn)
+}
 
+func (t *stringTrie[V]) WalkFrom(from string, fn func(path string, value V) bool) {
+	defer t.unpin(t.pin())
+	fn, end := t.traceWalk("", fn)
+	defer end()
+	t.walkFrom(view[string, V]{n: t.root}, "", true, t.segments(t.normalize(from)), fn)
+}
+
+// walkFrom is like walk but skips all paths below v that sort before the
+// relative path from.
+func (t *stringTrie[V]) walkFrom(v view[string, V], path string, root bool, from []string, fn func(string, V) bool) bool {
+	if len(from) == 0 {
+		return t.walk(v, path, root, fn)
+	}
+
+	// The value of v itself sorts before all of its children.
+	_, _, keys, children := t.sortedSnapshot(v)
+	for i, child := range children {
+		c := t.compareKeys(keys[i], from[0])
+		if c < 0 {
+			continue
+		}
+		childPath := t.childPath(path, keys[i], root)
+		if c == 0 && !t.walkFrom(child, childPath, false, from[1:], fn) {
+			return false
+		}
+		if c > 0 && !t.walk(child, childPath, false, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *stringTrie[V]) WalkPath(path string, fn func(path string, value V) bool) {
+	type entry struct {
+		end   int
+		value V
+	}
+	var entries []entry
+
+	path = t.normalize(path)
+	rest := path
+	c := t.cursor()
+	for {
+		if value, hasValue := c.value(); hasValue {
+			end := len(path) - len(rest)
+			if rest != "" && end > 0 {
+				end -= len(t.delimiter)
+			}
+			entries = append(entries, entry{end, value})
+		}
+		if rest == "" {
+			c.close()
+			break
+		}
+		var key string
+		key, rest = t.cut(rest)
+		if !c.next(key) {
+			break
+		}
+	}
+
+	for _, e := range entries {
+		if !fn(path[:e.end], e.value) {
+			return
+		}
+	}
+}
+
+func (t *stringTrie[V]) GetShortestPrefix(path string) (value V, prefix string, found bool) {
+	path = t.normalize(path)
+	rest := path
+	c := t.cursor()
+	for {
+		if v, hasValue := c.value(); hasValue {
+			c.close()
+			end := len(path) - len(rest)
+			if rest != "" && end > 0 {
+				end -= len(t.delimiter)
+			}
+			return v, path[:end], true
+		}
+		if rest == "" {
+			c.close()
+			return value, "", false
+		}
+		var key string
+		key, rest = t.cut(rest)
+		if !c.next(key) {
+			return value, "", false
+		}
+	}
+}
+
+func (t *stringTrie[V]) ContainsPrefixOf(path string) bool {
+	path = t.normalize(path)
+	c := t.cursor()
+	for {
+		if _, hasValue := c.value(); hasValue {
+			c.close()
+			return true
+		}
+		if path == "" {
+			c.close()
+			return false
+		}
+		var key string
+		key, path = t.cut(path)
+		if !c.next(key) {
+			return false
+		}
+	}
+}
+
+func (t *stringTrie[V]) HasKeysWithPrefix(path string) bool {
+	defer t.unpin(t.pin())
+	v, ok := t.find(path)
+	return ok && hasValues(v)
+}
+
+// hasValues reports whether v or any of its descendants has a value.
+func hasValues[K comparable, V any](v view[K, V]) bool {
+	_, hasValue, _, children := v.snapshot()
+	if hasValue {
+		return true
+	}
+	for _, child := range children {
+		if hasValues(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *stringTrie[V]) Children(path string) (segments []string, found bool) {
+	defer t.unpin(t.pin())
+	v, ok := t.find(path)
 	if !ok {
-		return
+		return nil, false
+	}
+
+	_, _, keys, _ := t.sortedSnapshot(v)
+	return keys, true
+}
+
+// find returns a view of the node at path.
+func (t *stringTrie[V]) find(path string) (view[string, V], bool) {
+	path = t.normalize(path)
+	c := t.cursor()
+	for path != "" {
+		var key string
+		key, path = t.cut(path)
+		if !c.next(key) {
+			return view[string, V]{}, false
+		}
 	}
 
-	child.Delete(path)
+	v := c.view()
+	c.close()
+	return v, true
+}
+
+func (t *stringTrie[V]) ToMap() map[string]V {
+	m := make(map[string]V)
+	t.Walk(func(path string, value V) bool {
+		m[path] = value
+		return true
+	})
+	return m
+}
+
+// walk calls fn for v and all of its children. path is the full path of v,
+// root indicates whether v is the root node in which case path is not joined
+// with the keys of the children. It returns false if walking has been stopped
+// by fn.
+func (t *stringTrie[V]) walk(v view[string, V], path string, root bool, fn func(string, V) bool) bool {
+	value, hasValue, keys, children := t.sortedSnapshot(v)
+
+	if hasValue && !fn(path, value) {
+		return false
+	}
+
+	for i, child := range children {
+		if !t.walk(child, t.childPath(path, keys[i], root), false, fn) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// collator sorts segments according to the rules of a language. The
+// underlying collate.Collator is not safe for concurrent use.
+type collator struct {
+	lock sync.Mutex
+	c    *collate.Collator
+}
+
+// compareKeys compares two segments in the order of Walk.
+func (t *stringTrie[V]) compareKeys(a, b string) int {
+	if t.collator == nil {
+		return strings.Compare(a, b)
+	}
+	t.collator.lock.Lock()
+	defer t.collator.lock.Unlock()
+	return t.collator.c.CompareString(a, b)
+}
+
+// sortedSnapshot is like the function sortedSnapshot but sorts the children
+// using the collator of the trie, if any.
+func (t *stringTrie[V]) sortedSnapshot(v view[string, V]) (value V, hasValue bool, keys []string, children []view[string, V]) {
+	if t.collator == nil {
+		return sortedSnapshot(v)
+	}
+
+	value, hasValue, keys, children = v.snapshot()
+	t.collator.lock.Lock()
+	t.collator.c.Sort(byKey[V]{keys, children})
+	t.collator.lock.Unlock()
+	return value, hasValue, keys, children
+}
+
+// sortedSnapshot is like view.snapshot but sorts the children by key.
+func sortedSnapshot[V any](v view[string, V]) (value V, hasValue bool, keys []string, children []view[string, V]) {
+	value, hasValue, keys, children = v.snapshot()
+	sort.Sort(byKey[V]{keys, children})
+	return value, hasValue, keys, children
+}
+
+type byKey[V any] struct {
+	keys     []string
+	children []view[string, V]
+}
+
+func (b byKey[V]) Len() int           { return len(b.keys) }
+func (b byKey[V]) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+
+func (b byKey[V]) Bytes(i int) []byte { return []byte(b.keys[i]) }
+
+func (b byKey[V]) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.children[i], b.children[j] = b.children[j], b.children[i]
 }
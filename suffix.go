@@ -0,0 +1,156 @@
+package trie
+
+import (
+	"slices"
+	"sort"
+	"sync"
+)
+
+// suffixIndex contains the paths of all values of a trie with their segments
+// in reverse order, see WithSuffixIndex.
+type suffixIndex struct {
+	// lock serializes all mutations of the trie and the index, so that both
+	// see them in the same order. Lookups do not acquire it, they check the
+	// paths found in the index against the trie instead.
+	lock sync.Mutex
+	keys tree[string, struct{}]
+}
+
+// WithSuffixIndex makes the trie maintain an index of the reversed paths of
+// all values, so that GetLongestSuffix and SuffixKeys do not have to walk the
+// whole trie. The index roughly doubles the memory used for the paths and
+// all writes to the trie are serialized. It does not apply to Slice.
+func WithSuffixIndex() Option {
+	return func(o *options) {
+		o.suffixIndex = true
+	}
+}
+
+// unlockSuffix releases the lock of the suffix index, if any.
+func (t *stringTrie[V]) unlockSuffix() {
+	if t.suffix != nil {
+		t.suffix.lock.Unlock()
+	}
+}
+
+func reversed(segments []string) []string {
+	r := make([]string, len(segments))
+	for i, key := range segments {
+		r[len(segments)-1-i] = key
+	}
+	return r
+}
+
+// unindex removes the paths of all values of the detached subtree v at path
+// from the index. The lock of the index must be held by the caller.
+func (t *stringTrie[V]) unindex(v view[string, V], path []string) {
+	_, hasValue, keys, children := v.snapshot()
+	if hasValue {
+		t.suffix.keys.unset(reversed(path))
+	}
+	for i, child := range children {
+		t.unindex(child, append(path[:len(path):len(path)], keys[i]))
+	}
+}
+
+func (t *stringTrie[V]) GetLongestSuffix(path string) (value V, suffix string, found bool) {
+	if t.suffix == nil {
+		return t.scanLongestSuffix(t.Walk, path)
+	}
+
+	segments := t.segments(t.normalize(path))
+
+	// Collect the lengths of all suffixes in the index and check them
+	// against the trie starting with the longest one, the index may be
+	// ahead of or behind the trie for paths that are being modified.
+	var lengths []int
+	c := t.suffix.keys.cursor()
+	for i := len(segments); ; i-- {
+		if _, hasValue := c.value(); hasValue {
+			lengths = append(lengths, len(segments)-i)
+		}
+		if i == 0 {
+			c.close()
+			break
+		}
+		if !c.next(segments[i-1]) {
+			break
+		}
+	}
+
+	for i := len(lengths) - 1; i >= 0; i-- {
+		key := segments[len(segments)-lengths[i]:]
+		if v, ok := t.valueAt(key); ok {
+			return v, t.joinSegments(key), true
+		}
+	}
+	return value, "", false
+}
+
+func (t *stringTrie[V]) SuffixKeys(suffix string) []string {
+	if t.suffix == nil {
+		return t.scanSuffixKeys(t.Walk, suffix)
+	}
+
+	segments := t.segments(t.normalize(suffix))
+	var keys []string
+	c := t.suffix.keys.cursor()
+	for i := len(segments) - 1; i >= 0; i-- {
+		if !c.next(segments[i]) {
+			return nil
+		}
+	}
+	v := c.view()
+	c.close()
+
+	var visit func(v view[string, struct{}], path []string)
+	visit = func(v view[string, struct{}], path []string) {
+		_, hasValue, rkeys, children := v.snapshot()
+		if hasValue {
+			key := reversed(path)
+			if _, ok := t.valueAt(key); ok {
+				keys = append(keys, t.joinSegments(key))
+			}
+		}
+		for i, child := range children {
+			visit(child, append(path[:len(path):len(path)], rkeys[i]))
+		}
+	}
+	visit(v, reversed(segments))
+	sort.Strings(keys)
+	return keys
+}
+
+// scanLongestSuffix implements GetLongestSuffix by calling walk, which walks
+// the paths and values of t or a namespace of t.
+func (t *stringTrie[V]) scanLongestSuffix(walk func(func(string, V) bool), path string) (value V, suffix string, found bool) {
+	segments := t.segments(t.normalize(path))
+	var longest []string
+	walk(func(p string, v V) bool {
+		key := t.segments(p)
+		if hasSuffix(segments, key) && (!found || len(key) > len(longest)) {
+			value, longest, found = v, key, true
+		}
+		return true
+	})
+	return value, t.joinSegments(longest), found
+}
+
+// scanSuffixKeys implements SuffixKeys like scanLongestSuffix.
+func (t *stringTrie[V]) scanSuffixKeys(walk func(func(string, V) bool), suffix string) []string {
+	segments := t.segments(t.normalize(suffix))
+	var keys []string
+	walk(func(p string, _ V) bool {
+		if hasSuffix(t.segments(p), segments) {
+			keys = append(keys, p)
+		}
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// hasSuffix returns whether the last segments of path are suffix.
+func hasSuffix(path, suffix []string) bool {
+	return len(suffix) <= len(path) && slices.Equal(path[len(path)-len(suffix):], suffix)
+}
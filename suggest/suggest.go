@@ -0,0 +1,239 @@
+// Package suggest implements typeahead suggestions. Recorded queries are
+// stored in a String trie with one segment per rune, so the completions of a
+// prefix that ends within a word are the subtree of a single node. Each query
+// carries a score that is increased every time it is recorded and decays
+// exponentially over time, so recent queries rank above ones that were
+// popular a long time ago.
+package suggest
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"moehl.dev/trie"
+)
+
+// separator delimits the runes of a query in the trie. Queries containing it
+// are not recorded.
+const separator = "\x00"
+
+// Suggestion is a completion returned by Top.
+type Suggestion struct {
+	Query string
+	// Score is the decayed number of times the query has been recorded.
+	Score float64
+	// Count is the number of times the query has been recorded.
+	Count uint64
+}
+
+// Suggester records queries and suggests completions. It is safe for
+// concurrent use.
+type Suggester struct {
+	// decay is the factor the score decreases by per second.
+	decay float64
+	clock func() time.Time
+
+	// lock serializes Record, the values in queries are never modified.
+	lock    sync.Mutex
+	queries trie.String[stat]
+}
+
+type stat struct {
+	score   float64
+	count   uint64
+	updated time.Time
+}
+
+// New creates an empty Suggester whose scores halve every halfLife. A
+// halfLife of 0 disables the decay. If clock is nil, time.Now is used.
+func New(halfLife time.Duration, clock func() time.Time) *Suggester {
+	s := &Suggester{clock: clock, queries: trie.New[stat](separator)}
+	if s.clock == nil {
+		s.clock = time.Now
+	}
+	if halfLife > 0 {
+		s.decay = math.Ln2 / halfLife.Seconds()
+	}
+	return s
+}
+
+// normalize returns the query in lower case without surrounding white space.
+func normalize(query string) string {
+	return strings.ToLower(strings.TrimSpace(query))
+}
+
+// key returns the path of query in the trie.
+func key(query string) string {
+	return strings.Join(strings.Split(query, ""), separator)
+}
+
+// score returns the score of st at now.
+func (s *Suggester) score(st stat, now time.Time) float64 {
+	if s.decay == 0 {
+		return st.score
+	}
+	return st.score * math.Exp(-s.decay*now.Sub(st.updated).Seconds())
+}
+
+// Record an occurrence of the query.
+func (s *Suggester) Record(query string) {
+	query = normalize(query)
+	if query == "" || strings.Contains(query, separator) {
+		return
+	}
+	k := key(query)
+	now := s.clock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	st, _ := s.queries.Get(k)
+	s.queries.Put(k, stat{
+		score:   s.score(st, now) + 1,
+		count:   st.count + 1,
+		updated: now,
+	})
+}
+
+// Forget removes the query and all queries starting with it, e.g. to remove
+// offensive queries.
+func (s *Suggester) Forget(prefix string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.queries.Delete(key(normalize(prefix)))
+}
+
+// Top returns up to k recorded queries starting with prefix, ordered by their
+// score. Queries with the same score are ordered alphabetically.
+func (s *Suggester) Top(prefix string, k int) []Suggestion {
+	if k <= 0 {
+		return nil
+	}
+	prefix = normalize(prefix)
+	now := s.clock()
+
+	h := &suggestionHeap{}
+	trie.Namespace(s.queries, key(prefix)).Walk(func(path string, st stat) bool {
+		sg := Suggestion{
+			Query: prefix + strings.ReplaceAll(path, separator, ""),
+			Score: s.score(st, now),
+			Count: st.count,
+		}
+		if h.Len() < k {
+			heap.Push(h, sg)
+		} else if h.less(h.s[0], sg) {
+			h.s[0] = sg
+			heap.Fix(h, 0)
+		}
+		return true
+	})
+
+	top := make([]Suggestion, h.Len())
+	for i := len(top) - 1; i >= 0; i-- {
+		top[i] = heap.Pop(h).(Suggestion)
+	}
+	return top
+}
+
+// suggestionHeap is a min-heap containing the best suggestions seen so far,
+// the worst of them at the top.
+type suggestionHeap struct {
+	s []Suggestion
+}
+
+// less reports whether a ranks below b.
+func (h *suggestionHeap) less(a, b Suggestion) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return a.Query > b.Query
+}
+
+func (h *suggestionHeap) Len() int           { return len(h.s) }
+func (h *suggestionHeap) Less(i, j int) bool { return h.less(h.s[i], h.s[j]) }
+func (h *suggestionHeap) Swap(i, j int)      { h.s[i], h.s[j] = h.s[j], h.s[i] }
+func (h *suggestionHeap) Push(x any)         { h.s = append(h.s, x.(Suggestion)) }
+
+func (h *suggestionHeap) Pop() any {
+	x := h.s[len(h.s)-1]
+	h.s = h.s[:len(h.s)-1]
+	return x
+}
+
+// Corrections returns up to limit recorded queries within an edit distance of
+// maxDist of word, e.g. for "did you mean" messages. The edit distance is the
+// number of runes that have to be inserted, deleted or substituted. The
+// queries are ordered by their distance and then by the number of times they
+// have been recorded. Subtrees are skipped as soon as none of their queries
+// can be within maxDist.
+func (s *Suggester) Corrections(word string, maxDist, limit int) []string {
+	if limit <= 0 {
+		return nil
+	}
+	target := []rune(normalize(word))
+	row := make([]int, len(target)+1)
+	for i := range row {
+		row[i] = i
+	}
+
+	type candidate struct {
+		query string
+		dist  int
+		count uint64
+	}
+	var candidates []candidate
+
+	var visit func(n trie.Node[stat], query []rune, prev []int)
+	visit = func(n trie.Node[stat], query []rune, prev []int) {
+		children, _ := s.queries.Children(n.Path())
+		for _, segment := range children {
+			child, ok := n.Child(segment)
+			if !ok {
+				continue
+			}
+			r := []rune(segment)[0]
+
+			row := make([]int, len(prev))
+			row[0] = prev[0] + 1
+			best := row[0]
+			for i := 1; i < len(row); i++ {
+				cost := 1
+				if target[i-1] == r {
+					cost = 0
+				}
+				row[i] = min(prev[i]+1, row[i-1]+1, prev[i-1]+cost)
+				best = min(best, row[i])
+			}
+
+			q := append(query[:len(query):len(query)], r)
+			if st, ok := child.Value(); ok && row[len(row)-1] <= maxDist {
+				candidates = append(candidates, candidate{string(q), row[len(row)-1], st.count})
+			}
+			if best <= maxDist {
+				visit(child, q, row)
+			}
+		}
+	}
+	visit(s.queries.Root(), nil, row)
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.dist != b.dist {
+			return a.dist < b.dist
+		}
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		return a.query < b.query
+	})
+
+	corrections := make([]string, 0, min(limit, len(candidates)))
+	for _, c := range candidates[:min(limit, len(candidates))] {
+		corrections = append(corrections, c.query)
+	}
+	return corrections
+}
@@ -0,0 +1,85 @@
+package suggest_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"moehl.dev/trie/suggest"
+)
+
+func TestSuggesterTop(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := suggest.New(time.Hour, func() time.Time { return now })
+
+	for i := 0; i < 4; i++ {
+		s.Record("golang")
+	}
+	s.Record("Go Modules ")
+	s.Record("gopher")
+	s.Record("rust")
+
+	// Two half-lives later golang has a score of 1, the same as go
+	// modules recorded afterwards.
+	now = now.Add(2 * time.Hour)
+	s.Record("go modules")
+	s.Record("gopls")
+	s.Record("gopls")
+
+	got := s.Top("Go", 3)
+	expected := []string{"gopls", "go modules", "golang"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v but got %+v", expected, got)
+	}
+	for i, sg := range got {
+		if sg.Query != expected[i] {
+			t.Errorf("%d: expected %q but got %q", i, expected[i], sg.Query)
+		}
+	}
+	if got[1].Count != 2 || math.Abs(got[1].Score-1.25) > 1e-9 {
+		t.Errorf("expected go modules to have count 2 and score 1.25 but got %+v", got[1])
+	}
+
+	if got := s.Top("gop", 10); len(got) != 2 || got[0].Query != "gopls" || got[1].Query != "gopher" {
+		t.Errorf("expected gopls and gopher but got %+v", got)
+	}
+	if got := s.Top("", 1); len(got) != 1 || got[0].Query != "gopls" {
+		t.Errorf("expected gopls but got %+v", got)
+	}
+
+	s.Forget("gop")
+	if got := s.Top("gop", 10); len(got) != 0 {
+		t.Errorf("expected no suggestions after forgetting gop but got %+v", got)
+	}
+}
+
+func TestSuggesterCorrections(t *testing.T) {
+	s := suggest.New(0, nil)
+	for _, cmd := range []string{"build", "build", "bench", "clean", "test", "vet", "version", "vet"} {
+		s.Record(cmd)
+	}
+
+	for _, tc := range []struct {
+		word     string
+		maxDist  int
+		limit    int
+		expected []string
+	}{
+		{"biuld", 2, 3, []string{"build"}},
+		{"vat", 1, 3, []string{"vet"}},
+		{"bulid", 1, 3, nil},
+		{"versoin", 2, 2, []string{"version"}},
+		// vet is recorded more often than test.
+		{"tset", 2, 3, []string{"vet", "test"}},
+		{"best", 1, 3, []string{"test"}},
+		{"best", 2, 3, []string{"test", "vet"}},
+		{"best", 3, 2, []string{"test", "vet"}},
+		{"xyz", 1, 3, nil},
+	} {
+		got := s.Corrections(tc.word, tc.maxDist, tc.limit)
+		if !reflect.DeepEqual(got, tc.expected) && (len(got) != 0 || len(tc.expected) != 0) {
+			t.Errorf("%s: expected %v but got %v", tc.word, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package trie
+
+import (
+	"slices"
+	"time"
+)
+
+// nodeMeta contains the timestamps of the value of a node.
+type nodeMeta struct {
+	created time.Time
+	updated time.Time
+}
+
+// WithTimestamps makes the trie record when the value of each path has been
+// set for the first time and when it has been set most recently, see Meta.
+// Timestamps are not serialized, values read by ReadFrom are stamped with the
+// time they are read. It does not apply to Slice.
+func WithTimestamps() Option {
+	return func(o *options) {
+		o.timestamps = true
+	}
+}
+
+// stamp records a write to the value of n if timestamps are enabled. existed
+// indicates whether n already had a value. The caller must hold the lock of n.
+func (t *tree[K, V]) stamp(n *node[K, V], existed bool) {
+	if t.now == nil {
+		return
+	}
+	now := t.now()
+	if existed && n.meta != nil {
+		n.meta.updated = now
+		return
+	}
+	n.meta = &nodeMeta{created: now, updated: now}
+}
+
+// meta returns the timestamps of the value at segments.
+func (t *tree[K, V]) meta(segments []K) (nodeMeta, bool) {
+	c := t.cursor()
+	for _, key := range segments {
+		if !c.next(key) {
+			return nodeMeta{}, false
+		}
+	}
+	defer c.close()
+	if len(c.run) > 0 || !c.n.hasValue || c.n.meta == nil {
+		return nodeMeta{}, false
+	}
+	return *c.n.meta, true
+}
+
+// setMeta replaces the timestamps of the value at segments, if it has one.
+func (t *tree[K, V]) setMeta(segments []K, meta nodeMeta) {
+	n := t.root
+	n.lock.Lock()
+	for len(segments) > 0 {
+		child, ok := n.children.get(segments[0])
+		if !ok || len(child.segments) > len(segments) || !slices.Equal(child.segments, segments[:len(child.segments)]) {
+			n.lock.Unlock()
+			return
+		}
+		child.lock.Lock()
+		n.lock.Unlock()
+		n = child
+		segments = segments[len(child.segments):]
+	}
+
+	if n.hasValue {
+		n.meta = &meta
+	}
+	n.lock.Unlock()
+}
+
+// Meta returns false if timestamps are not recorded.
+func (t *stringTrie[V]) Meta(path string) (createdAt, updatedAt time.Time, ok bool) {
+	if t.now == nil {
+		return createdAt, updatedAt, false
+	}
+	meta, ok := t.meta(t.segments(t.normalize(path)))
+	return meta.created, meta.updated, ok
+}
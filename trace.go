@@ -0,0 +1,63 @@
+package trie
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Tracer starts spans for operations that visit large parts of a trie, i.e.
+// Walk, GetBatch, Rekey, Compact and the encoding and decoding of the binary
+// format, see WithTracer. It is deliberately minimal so that it can be
+// implemented on top of any tracing library, e.g. OpenTelemetry.
+type Tracer interface {
+	// Start starts a span for the operation with the given name, e.g.
+	// "trie.Walk".
+	Start(name string) Span
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetAttribute records an attribute of the operation, value is either a
+	// string or an int.
+	SetAttribute(key string, value any)
+	// End is called once the operation is done.
+	End()
+}
+
+// noopSpan is used if no tracer has been configured.
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+func (noopSpan) End()                     {}
+
+// startSpan starts a span with the tracer of the trie, if any.
+func (t *stringTrie[V]) startSpan(name string) Span {
+	if t.tracer == nil {
+		return noopSpan{}
+	}
+	return t.tracer.Start(name)
+}
+
+// traceWalk starts the span of a walk of the subtree at prefix. It returns fn
+// wrapped to count the visited values and a function that ends the span.
+func (t *stringTrie[V]) traceWalk(prefix string, fn func(string, V) bool) (func(string, V) bool, func()) {
+	if t.tracer == nil {
+		return fn, func() {}
+	}
+
+	span := t.tracer.Start("trie.Walk")
+	span.SetAttribute("trie.prefix", prefix)
+	var values int
+	return func(path string, value V) bool {
+			values++
+			return fn(path, value)
+		}, func() {
+			span.SetAttribute("trie.values", values)
+			span.End()
+		}
+}
+
+// debugEnabled returns whether structural changes are logged, see WithLogger.
+func (t *stringTrie[V]) debugEnabled() bool {
+	return t.logger != nil && t.logger.Enabled(context.Background(), slog.LevelDebug)
+}
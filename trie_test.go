@@ -1,9 +1,26 @@
 package trie_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
 	"moehl.dev/trie"
+	"moehl.dev/trie/trietest"
 )
 
 func TestStringSimple(t *testing.T) {
@@ -112,3 +129,2965 @@ func BenchmarkSlicePutLongKey(b *testing.B) {
 		tr.Put(key, value)
 	}
 }
+
+func TestStringBinaryRoundTrip(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar", 1)
+	tr.Put("foo/baz", 2)
+	tr.Put("qux", 3)
+
+	var buf bytes.Buffer
+	n, err := tr.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected %d bytes to be written but got %d", buf.Len(), n)
+	}
+
+	got := trie.New[int]("/")
+	_, err = got.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for key, value := range map[string]int{"foo/bar": 1, "foo/baz": 2, "qux": 3} {
+		gotValue, ok := got.Get(key)
+		if !ok {
+			t.Errorf("expected value to be '%v' but got no value at all", value)
+		}
+		if value != gotValue {
+			t.Errorf("expected value to be '%v' but got '%v'", value, gotValue)
+		}
+	}
+}
+
+func TestStringBinaryInvalid(t *testing.T) {
+	_, err := trie.New[int]("/").ReadFrom(strings.NewReader("NOPE"))
+	if !errors.Is(err, trie.ErrInvalidFormat) {
+		t.Errorf("expected '%v' but got '%v'", trie.ErrInvalidFormat, err)
+	}
+}
+
+func TestStringBinaryDeep(t *testing.T) {
+	const depth = 100000
+
+	// Version 1 of the format has no sections, which makes it easy to
+	// generate a chain of nodes.
+	var buf bytes.Buffer
+	buf.WriteString("TRIE\x01\x01/")
+	for i := 0; i < depth; i++ {
+		buf.WriteString("\x00\x01\x01a")
+	}
+	buf.WriteString("\x00\x00")
+
+	tr := trie.New[int]("/")
+	_, err := tr.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := strings.TrimSuffix(strings.Repeat("a/", depth), "/")
+	if _, ok := tr.Get(path); !ok {
+		t.Errorf("expected the deepest node to exist")
+	}
+}
+
+func TestStringBinaryProgress(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar", 1)
+	tr.Put("foo/baz", 2)
+
+	var buf bytes.Buffer
+	var encoded int64
+	_, err := trie.Encode(&buf, tr, func(values, bytes int64) { encoded = values })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded int64
+	_, err = trie.Decode(&buf, trie.New[int]("/"), func(values, bytes int64) { decoded = values })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if encoded != 2 || decoded != 2 {
+		t.Errorf("expected progress to report 2 values but got %d and %d", encoded, decoded)
+	}
+}
+
+func TestLoadLines(t *testing.T) {
+	tr, err := trie.LoadLines(strings.NewReader("foo/bar\r\n\nfoo/baz\n"), "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, key := range []string{"foo/bar", "foo/baz"} {
+		if _, ok := tr.Get(key); !ok {
+			t.Errorf("expected '%v' to exist", key)
+		}
+	}
+}
+
+func TestStringWriteDOT(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar", 1)
+
+	var buf bytes.Buffer
+	err := tr.WriteDOT(&buf, trie.DotValues(), trie.DotValueMarkers())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `digraph trie {
+	n0 [label="/"];
+	n1 [label="foo", shape=circle];
+	n0 -> n1;
+	n2 [label="bar\n1", shape=doublecircle];
+	n1 -> n2;
+}
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, buf.String())
+	}
+}
+
+func TestStringDump(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar", 1)
+	tr.Put("foo/baz", 2)
+	tr.Put("qux", 3)
+
+	var buf bytes.Buffer
+	err := tr.Dump(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `/
+├── foo
+│   ├── bar: 1
+│   └── baz: 2
+└── qux: 3
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, buf.String())
+	}
+}
+
+func TestMapped(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar", 1)
+	tr.Put("foo/baz", 2)
+	tr.Put("qux", 3)
+
+	path := filepath.Join(t.TempDir(), "trie")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = trie.WriteMapped(f, tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+
+	m, err := trie.OpenMapped[int](path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Close()
+
+	gotValue, ok, err := m.Get("foo/baz")
+	if err != nil || !ok || gotValue != 2 {
+		t.Errorf("expected value to be '2' but got '%v' (%v, %v)", gotValue, ok, err)
+	}
+	_, ok, err = m.Get("foo/nope")
+	if err != nil || ok {
+		t.Errorf("expected no value but got (%v, %v)", ok, err)
+	}
+
+	var keys []string
+	err = m.WalkPrefix("foo", func(path string, value int) bool {
+		keys = append(keys, path)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(keys, ",") != "foo/bar,foo/baz" {
+		t.Errorf("expected keys 'foo/bar,foo/baz' but got '%v'", keys)
+	}
+
+	if err := m.PutE("foo/bar", 4); !errors.Is(err, trie.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly but got '%v'", err)
+	}
+	if err := m.DeleteE("foo/bar"); !errors.Is(err, trie.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly but got '%v'", err)
+	}
+}
+
+func TestMappedCycle(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("a", 1)
+
+	var buf bytes.Buffer
+	_, err := trie.WriteMapped(&buf, tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Let the only child of the root, whose offset is the last byte before
+	// the trailer, point back to the root.
+	data := buf.Bytes()
+	data[len(data)-9] = data[len(data)-8]
+
+	path := filepath.Join(t.TempDir(), "trie")
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := trie.OpenMapped[int](path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Close()
+
+	err = m.WalkPrefix("", func(string, int) bool { return true })
+	if !errors.Is(err, trie.ErrInvalidFormat) {
+		t.Errorf("expected ErrInvalidFormat but got '%v'", err)
+	}
+}
+
+func TestWALReplay(t *testing.T) {
+	var log bytes.Buffer
+	wal := trie.NewWAL(trie.New[int]("/"), &log)
+	wal.Put("foo/bar", 1)
+	wal.Put("foo/baz", 2)
+	wal.Delete("foo/bar")
+
+	var checkpoint bytes.Buffer
+	err := wal.Checkpoint(&checkpoint)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wal.Put("qux", 3)
+
+	if err := wal.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, l := range []*bytes.Buffer{&log, &checkpoint} {
+		replayed := trie.NewWAL(trie.New[int]("/"), nil)
+		err = replayed.Replay(bytes.NewReader(l.Bytes()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, ok := replayed.Get("foo/bar"); ok {
+			t.Errorf("expected 'foo/bar' to be deleted")
+		}
+		if gotValue, _ := replayed.Get("foo/baz"); gotValue != 2 {
+			t.Errorf("expected value to be '2' but got '%v'", gotValue)
+		}
+	}
+
+	replayed := trie.NewWAL(trie.New[int]("/"), nil)
+	_ = replayed.Replay(&checkpoint)
+	if gotValue, _ := replayed.Get("qux"); gotValue != 3 {
+		t.Errorf("expected value to be '3' but got '%v'", gotValue)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	tr := trie.New[int](".")
+	tr.Put("foo.bar", 1)
+
+	path := filepath.Join(t.TempDir(), "snapshot")
+	err := trie.SaveSnapshot(tr, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := trie.LoadSnapshot[int](path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Delimiter() != "." {
+		t.Errorf("expected delimiter to be '.' but got '%v'", got.Delimiter())
+	}
+	if gotValue, _ := got.Get("foo.bar"); gotValue != 1 {
+		t.Errorf("expected value to be '1' but got '%v'", gotValue)
+	}
+}
+
+func TestStringWalk(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar", 1)
+	tr.Put("/foo", 2)
+	tr.Put("qux", 3)
+
+	var keys []string
+	tr.Walk(func(path string, value int) bool {
+		keys = append(keys, path)
+		return true
+	})
+
+	if strings.Join(keys, ",") != "/foo,foo/bar,qux" {
+		t.Errorf("expected keys '/foo,foo/bar,qux' but got '%v'", keys)
+	}
+}
+
+func TestExport(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("a", 1)
+	tr.Put("a/b", -2)
+
+	tests := []struct {
+		name     string
+		encoder  trie.Encoder
+		expected []byte
+	}{
+		{"CBOR", trie.CBOR, []byte{0xa2, 0x61, 'a', 0x01, 0x63, 'a', '/', 'b', 0x21}},
+		{"MessagePack", trie.MessagePack, []byte{0x82, 0xa1, 'a', 0x01, 0xa3, 'a', '/', 'b', 0xfe}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := trie.Export(&buf, tr, tt.encoder)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.expected) {
+				t.Errorf("expected '%x' but got '%x'", tt.expected, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestStringMap(t *testing.T) {
+	m := map[string]int{"foo/bar": 1, "foo/baz": 2, "qux": 3}
+
+	got := trie.FromMap(m, "/").ToMap()
+	if !reflect.DeepEqual(m, got) {
+		t.Errorf("expected '%v' but got '%v'", m, got)
+	}
+}
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"foo/bar.txt": &fstest.MapFile{Data: []byte("bar")},
+		"baz.txt":     &fstest.MapFile{Data: []byte("baz")},
+	}
+
+	tr, err := trie.FromFS(fsys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, ok := tr.Get("foo/bar.txt")
+	if !ok || info.Size() != 3 {
+		t.Errorf("expected 'foo/bar.txt' with size 3 but got '%v'", info)
+	}
+	info, ok = tr.Get("foo")
+	if !ok || !info.IsDir() {
+		t.Errorf("expected 'foo' to be a directory but got '%v'", info)
+	}
+}
+
+func TestAsFS(t *testing.T) {
+	tr := trie.New[[]byte]("/")
+	tr.Put("foo/bar.txt", []byte("bar"))
+	tr.Put("foo/baz/qux.txt", []byte("qux"))
+	tr.Put("quux.txt", []byte("quux"))
+
+	err := fstest.TestFS(trie.AsFS(tr), "foo/bar.txt", "foo/baz/qux.txt", "quux.txt")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSegment(t *testing.T) {
+	tr := trie.New[int]("/")
+	for i := 0; i < 100; i++ {
+		tr.Put(fmt.Sprintf("foo/%03d", i), i)
+	}
+
+	for _, opts := range [][]trie.SegmentOption{
+		{trie.SegmentBlockSize(64)},
+		{trie.SegmentBlockSize(64), trie.SegmentPrefixCompression()},
+	} {
+		var buf bytes.Buffer
+		_, err := trie.WriteSegment(&buf, tr, opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		s, err := trie.NewSegment[int](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for i := 0; i < 100; i++ {
+			gotValue, ok, err := s.Get(fmt.Sprintf("foo/%03d", i))
+			if err != nil || !ok || gotValue != i {
+				t.Errorf("expected value to be '%v' but got '%v' (%v, %v)", i, gotValue, ok, err)
+			}
+		}
+		if _, ok, _ := s.Get("foo"); ok {
+			t.Errorf("expected intermediate node not to be found")
+		}
+		if err := s.PutE("foo/100", 100); !errors.Is(err, trie.ErrReadOnly) {
+			t.Errorf("expected ErrReadOnly but got '%v'", err)
+		}
+		if err := s.DeleteE("foo/000"); !errors.Is(err, trie.ErrReadOnly) {
+			t.Errorf("expected ErrReadOnly but got '%v'", err)
+		}
+	}
+}
+
+func TestSegmentEntryCount(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("a", 1)
+
+	var buf bytes.Buffer
+	_, err := trie.WriteSegment(&buf, tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The only block follows the header, claim that it contains more
+	// entries than fit into it.
+	data := buf.Bytes()
+	data[len("TRIS")+3+1] = 0x7f
+
+	s, err := trie.NewSegment[int](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err = s.Get("a"); !errors.Is(err, trie.ErrInvalidFormat) {
+		t.Errorf("expected ErrInvalidFormat but got '%v'", err)
+	}
+}
+
+func TestMergeSegments(t *testing.T) {
+	var segments []*trie.Segment[int]
+	for _, m := range []map[string]int{{"a": 1, "b": 1}, {"b": 2, "c": 2}} {
+		var buf bytes.Buffer
+		_, err := trie.WriteSegment(&buf, trie.FromMap(m, "/"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s, err := trie.NewSegment[int](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		segments = append(segments, s)
+	}
+
+	var buf bytes.Buffer
+	_, err := trie.MergeSegments(&buf, segments)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	merged, err := trie.NewSegment[int](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[string]int)
+	err = merged.Walk("", func(path string, value int) bool {
+		got[path] = value
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]int{"a": 1, "b": 2, "c": 2}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestJournalDelta(t *testing.T) {
+	j := trie.NewJournal(trie.New[int]("/"))
+	j.Put("foo/bar", 1)
+	j.Put("foo/baz", 2)
+
+	replica := trie.New[int]("/")
+	var buf bytes.Buffer
+	err := j.ExportSince(&buf, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rev, err := trie.ApplyDelta(replica, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rev != 2 {
+		t.Errorf("expected revision to be '2' but got '%v'", rev)
+	}
+
+	j.Put("foo/bar/qux", 3)
+	j.Delete("foo")
+	j.Put("foo/baz", 4)
+
+	buf.Reset()
+	err = j.ExportSince(&buf, rev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = trie.ApplyDelta(replica, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]int{"foo/baz": 4}
+	if got := replica.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestStringBinaryCorrupt(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar", 1)
+	tr.Put("foo/baz", 2)
+
+	var buf bytes.Buffer
+	_, err := tr.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	flipped := bytes.Clone(buf.Bytes())
+	flipped[len(flipped)/2] ^= 0xff
+
+	for name, data := range map[string][]byte{
+		"truncated": buf.Bytes()[:buf.Len()-1],
+		"flipped":   flipped,
+	} {
+		_, err := trie.New[int]("/").ReadFrom(bytes.NewReader(data))
+		var corrupt *trie.CorruptError
+		if !errors.As(err, &corrupt) {
+			t.Errorf("%s: expected a corrupt error but got '%v'", name, err)
+		}
+	}
+}
+
+type upperCodec struct{}
+
+func (upperCodec) Encode(v string) ([]byte, error) { return []byte(strings.ToUpper(v)), nil }
+func (upperCodec) Decode(b []byte) (string, error) { return string(b), nil }
+
+func TestStringCodec(t *testing.T) {
+	tr := trie.NewWithCodec[string]("/", upperCodec{})
+	tr.Put("foo", "bar")
+
+	path := filepath.Join(t.TempDir(), "snapshot")
+	err := trie.SaveSnapshot(tr, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := trie.LoadSnapshotCodec[string](path, upperCodec{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotValue, _ := got.Get("foo"); gotValue != "BAR" {
+		t.Errorf("expected value to be 'BAR' but got '%v'", gotValue)
+	}
+}
+
+func BenchmarkStringGetLongKey(b *testing.B) {
+	b.ReportAllocs()
+
+	tr := trie.New[string]("/")
+
+	n := 100
+	segments := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		segments = append(segments, strconv.Itoa(i))
+	}
+	key := strings.Join(segments, "/")
+	tr.Put(key, "foobar")
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tr.Get(key)
+	}
+}
+
+func TestStringPooled(t *testing.T) {
+	tr := trie.NewPooled[int]("/")
+
+	for i := 0; i < 3; i++ {
+		tr.Put("foo/bar", 1)
+		tr.Put("foo/baz", 2)
+		tr.Delete("foo/bar")
+
+		expected := map[string]int{"foo/baz": 2}
+		if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected '%v' but got '%v'", expected, got)
+		}
+
+		tr.Clear()
+		if got := tr.ToMap(); len(got) != 0 {
+			t.Errorf("expected trie to be empty but got '%v'", got)
+		}
+	}
+}
+
+func TestStringPooledConcurrent(t *testing.T) {
+	tr := trie.NewPooled[int]("/")
+
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				path := fmt.Sprintf("a/%d/b/%d", w, i%10)
+				tr.Put(path, i)
+				if i%3 == 0 {
+					tr.Delete(fmt.Sprintf("a/%d", w))
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				tr.Walk(func(path string, value int) bool {
+					return true
+				})
+				tr.Children("a")
+				trie.StatsOf[int](tr)
+				if n, ok := tr.Root().Child("a"); ok {
+					n.Value()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := tr.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDetach(t *testing.T) {
+	tr := trie.NewPooled[int]("/")
+	tr.Put("a/b/c", 1)
+	tr.Put("a/b/d", 2)
+	tr.Put("p/q/r", 3)
+	tr.Put("x", 4)
+
+	for path, expected := range map[string]map[string]int{
+		"a/b": {"c": 1, "d": 2},
+		// p/q is an implicit node within the run of p/q/r.
+		"p/q": {"r": 3},
+	} {
+		d, found, err := trie.Detach[int](tr, path)
+		if err != nil || !found {
+			t.Fatalf("expected to detach '%s' but got %v, %v", path, found, err)
+		}
+		got := make(map[string]int)
+		d.Walk(func(path string, value int) bool {
+			got[path] = value
+			return true
+		})
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected '%v' but got '%v'", expected, got)
+		}
+
+		d.Release()
+		d.Walk(func(path string, value int) bool {
+			t.Errorf("unexpected path '%s' after release", path)
+			return true
+		})
+		d.Release()
+	}
+
+	expected := map[string]int{"x": 4}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+	if _, found, _ := trie.Detach[int](tr, "a/b"); found {
+		t.Errorf("expected 'a/b' to not exist")
+	}
+	if _, _, err := trie.Detach[int](tr, ""); err == nil {
+		t.Errorf("expected an error when detaching the root")
+	}
+	if _, _, err := trie.Detach[int](trie.Namespace[int](tr, "x"), "y"); err == nil {
+		t.Errorf("expected an error when detaching from a namespace")
+	}
+}
+
+func TestStringCompression(t *testing.T) {
+	tr := trie.New[int]("/")
+
+	tr.Put("a/b/c/d", 1)
+	tr.Put("a/b/x", 2)
+
+	for _, path := range []string{"a", "a/b", "a/b/c"} {
+		if _, ok := tr.Get(path); !ok {
+			t.Errorf("expected intermediate node '%s' to exist", path)
+		}
+	}
+	if _, ok := tr.Get("a/c"); ok {
+		t.Errorf("expected 'a/c' to not exist")
+	}
+	if children, _ := tr.Children("a/b"); !reflect.DeepEqual(children, []string{"c", "x"}) {
+		t.Errorf("expected children 'c' and 'x' but got '%v'", children)
+	}
+	if children, _ := tr.Children("a/b/c"); !reflect.DeepEqual(children, []string{"d"}) {
+		t.Errorf("expected child 'd' but got '%v'", children)
+	}
+
+	tr.Delete("a/b/c")
+	if _, ok := tr.Get("a/b/c/d"); ok {
+		t.Errorf("expected 'a/b/c/d' to be deleted")
+	}
+
+	tr.Put("a/b", 3)
+	expected := map[string]int{"a/b": 3, "a/b/x": 2}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tr.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tr2 := trie.New[int]("/")
+	if _, err := tr2.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr2.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestStringFanout(t *testing.T) {
+	tr := trie.New[int]("/")
+
+	expected := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		path := "foo/" + strconv.Itoa(i)
+		tr.Put(path, i)
+		expected[path] = i
+	}
+	for i := 0; i < 20; i += 3 {
+		path := "foo/" + strconv.Itoa(i)
+		tr.Delete(path)
+		delete(expected, path)
+	}
+
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+	for path, value := range expected {
+		if got, _ := tr.Get(path); got != value {
+			t.Errorf("expected '%v' at '%s' but got '%v'", value, path, got)
+		}
+	}
+}
+
+func TestStringArena(t *testing.T) {
+	tr := trie.NewArena[int]("/")
+
+	for i := 0; i < 3; i++ {
+		expected := make(map[string]int)
+		for j := 0; j < 100; j++ {
+			path := fmt.Sprintf("foo/%d/bar", j)
+			tr.Put(path, j)
+			expected[path] = j
+		}
+		tr.Delete("foo/0")
+		delete(expected, "foo/0/bar")
+
+		if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected '%v' but got '%v'", expected, got)
+		}
+
+		tr.Clear()
+		if got := tr.ToMap(); len(got) != 0 {
+			t.Errorf("expected trie to be empty but got '%v'", got)
+		}
+	}
+}
+
+func TestGetAllocs(t *testing.T) {
+	str := trie.New[string]("/")
+	str.Put("foo/bar/baz", "value")
+	str.Put("foo/qux", "value")
+
+	slice := trie.NewSlice[int, string]()
+	slice.Put([]int{1, 2, 3}, "value")
+	slice.Put([]int{1, 4}, "value")
+	key := []int{1, 2, 3}
+
+	bloom := trie.NewWithBloomFilter[string]("/", 100, 0.01)
+	bloom.Put("foo/bar/baz", "value")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		str.Get("foo/bar/baz")
+		str.Get("foo/bar")
+		str.Get("foo/missing")
+		bloom.Get("foo/bar/baz")
+		bloom.Get("foo/missing")
+	})
+	if allocs != 0 {
+		t.Errorf("expected String.Get to not allocate but got %v allocations", allocs)
+	}
+
+	allocs = testing.AllocsPerRun(100, func() {
+		slice.Get(key)
+		slice.Get(key[:2])
+	})
+	if allocs != 0 {
+		t.Errorf("expected Slice.Get to not allocate but got %v allocations", allocs)
+	}
+}
+
+func TestStringCompact(t *testing.T) {
+	tr := trie.New[int]("/")
+
+	expected := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		path := "foo/" + strconv.Itoa(i) + "/bar/baz"
+		tr.Put(path, i)
+		if i%4 == 0 {
+			expected[path] = i
+		} else {
+			tr.Delete(path)
+		}
+	}
+	tr.Put("a/b/c", 1)
+	tr.Delete("a/b/c")
+
+	tr.Compact()
+
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+	if _, ok := tr.Get("a"); ok {
+		t.Errorf("expected 'a' to be removed")
+	}
+	if _, ok := tr.Get("foo/1"); ok {
+		t.Errorf("expected 'foo/1' to be removed")
+	}
+	if _, ok := tr.Get("foo/4/bar"); !ok {
+		t.Errorf("expected 'foo/4/bar' to exist")
+	}
+	if children, _ := tr.Children("foo/4"); !reflect.DeepEqual(children, []string{"bar"}) {
+		t.Errorf("expected child 'bar' but got '%v'", children)
+	}
+}
+
+func TestStringBloomFilter(t *testing.T) {
+	tr := trie.NewWithBloomFilter[int]("/", 1000, 0.01)
+
+	tr.Put("foo/bar/baz", 1)
+	tr.Put("qux/", 2)
+
+	for _, path := range []string{"foo", "foo/bar", "foo/bar/baz", "qux", "qux/"} {
+		if _, ok := tr.Get(path); !ok {
+			t.Errorf("expected '%s' to be found", path)
+		}
+	}
+
+	misses := 0
+	for i := 0; i < 1000; i++ {
+		if _, ok := tr.Get("foo/" + strconv.Itoa(i)); ok {
+			misses++
+		}
+	}
+	if misses > 0 {
+		t.Errorf("expected no false positives to be reported by Get but got %d", misses)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tr.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tr2 := trie.NewWithBloomFilter[int]("/", 1000, 0.01)
+	if _, err := tr2.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := tr2.Get("foo/bar/baz"); !ok || got != 1 {
+		t.Errorf("expected '1' but got '%v'", got)
+	}
+
+	tr.Clear()
+	if _, ok := tr.Get("foo"); ok {
+		t.Errorf("expected 'foo' to be removed")
+	}
+}
+
+func TestStringBloomFilterConcurrentClear(t *testing.T) {
+	tr := trie.NewWithBloomFilter[int]("/", 1000, 0.01)
+
+	// Every round ends with Puts that overlap a Clear, none of the values
+	// that remain may be hidden by the filter.
+	for round := 0; round < 200; round++ {
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 20; i++ {
+					tr.Put(fmt.Sprintf("%d/%d/%d", round, w, i), i)
+				}
+			}(w)
+		}
+		for i := 0; i < 5; i++ {
+			if i%2 == 0 {
+				tr.Clear()
+			} else {
+				_ = tr.Rekey(func(path string) (string, bool) { return path, true })
+			}
+		}
+		wg.Wait()
+
+		for path := range tr.ToMap() {
+			if _, ok := tr.Get(path); !ok {
+				t.Fatalf("round %d: expected '%s' to be found", round, path)
+			}
+		}
+	}
+}
+
+// interruptingScheduler calls interrupt on the first Yield, which Put calls
+// before it locks the root.
+type interruptingScheduler struct {
+	interrupt func()
+}
+
+func (s *interruptingScheduler) Yield() {
+	if fn := s.interrupt; fn != nil {
+		s.interrupt = nil
+		fn()
+	}
+}
+
+func TestStringBloomFilterInterruptedPut(t *testing.T) {
+	for name, reset := range map[string]func(trie.String[int]){
+		"clear": func(tr trie.String[int]) { tr.Clear() },
+		"rekey": func(tr trie.String[int]) {
+			_ = tr.Rekey(func(path string) (string, bool) { return path, true })
+		},
+	} {
+		s := &interruptingScheduler{}
+		tr := trie.New[int]("/", trie.WithBloomFilter(1000, 0.01), trie.WithScheduler(s))
+		s.interrupt = func() { reset(tr) }
+		tr.Put("a/b", 1)
+
+		if _, ok := tr.Get("a/b"); !ok {
+			t.Errorf("%s: expected 'a/b' to be found", name)
+		}
+	}
+}
+
+func TestStringCache(t *testing.T) {
+	tr := trie.NewWithCache[int]("/", 2)
+
+	if _, ok := tr.Get("foo/bar"); ok {
+		t.Errorf("expected 'foo/bar' to not exist")
+	}
+	if _, ok := tr.Get("foo"); ok {
+		t.Errorf("expected 'foo' to not exist")
+	}
+
+	tr.Put("foo/bar/baz", 1)
+	if _, ok := tr.Get("foo/bar"); !ok {
+		t.Errorf("expected 'foo/bar' to exist")
+	}
+	if got, _ := tr.Get("foo/bar/baz/"); got != 1 {
+		t.Errorf("expected '1' but got '%v'", got)
+	}
+
+	tr.Put("foo/bar/baz", 2)
+	if got, _ := tr.Get("foo/bar/baz"); got != 2 {
+		t.Errorf("expected '2' but got '%v'", got)
+	}
+
+	tr.Delete("foo/bar")
+	if _, ok := tr.Get("foo/bar/baz"); ok {
+		t.Errorf("expected 'foo/bar/baz' to be deleted")
+	}
+	if _, ok := tr.Get("foo"); !ok {
+		t.Errorf("expected 'foo' to exist")
+	}
+
+	tr.Clear()
+	if _, ok := tr.Get("foo"); ok {
+		t.Errorf("expected 'foo' to be removed")
+	}
+}
+
+func TestStringConcurrentPutDelete(t *testing.T) {
+	tr := trie.New[int]("/")
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				tr.Put(fmt.Sprintf("a/%d/b/%d", w, i%10), i)
+				tr.Get(fmt.Sprintf("a/%d/b", w))
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			tr.Delete("a/" + strconv.Itoa(i%4))
+			tr.Delete("a")
+		}
+	}()
+	wg.Wait()
+
+	// Every remaining path must be reachable from the root.
+	tr.Walk(func(path string, value int) bool {
+		if got, ok := tr.Get(path); !ok || got != value {
+			t.Errorf("expected '%v' at '%s' but got '%v'", value, path, got)
+		}
+		return true
+	})
+
+	tr.Put("a/0/b/0", -1)
+	if got, _ := tr.Get("a/0/b/0"); got != -1 {
+		t.Errorf("expected '-1' but got '%v'", got)
+	}
+}
+
+func TestStringGetBatch(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar/baz", 1)
+	tr.Put("foo/bar-qux", 2)
+	tr.Put("foo", 3)
+	tr.Put("", 4)
+
+	paths := []string{"foo/bar/baz", "missing", "foo/bar", "", "foo/bar-qux", "foo/", "foo/bar/baz/qux", "foo/bar/x"}
+	out := make([]int, len(paths))
+	found := tr.GetBatch(paths, out)
+
+	for i, path := range paths {
+		value, ok := tr.Get(path)
+		if found[i] != ok || out[i] != value {
+			t.Errorf("expected '%v', %v at '%s' but got '%v', %v", value, ok, path, out[i], found[i])
+		}
+	}
+}
+
+func TestIndexed(t *testing.T) {
+	tr := trie.NewIndexed[int]("/")
+
+	expected := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		path := fmt.Sprintf("foo/%d/bar", i)
+		tr.Put(path, i)
+		expected[path] = i
+	}
+	tr.Put("", -1)
+	expected[""] = -1
+	for i := 0; i < 100; i += 2 {
+		tr.Delete("foo/" + strconv.Itoa(i))
+		delete(expected, fmt.Sprintf("foo/%d/bar", i))
+	}
+	tr.Put("foo/0/baz", 0)
+	expected["foo/0/baz"] = 0
+
+	got := make(map[string]int)
+	var paths []string
+	tr.Walk(func(path string, value int) bool {
+		got[path] = value
+		paths = append(paths, path)
+		return true
+	})
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+	if !sort.StringsAreSorted(paths) {
+		t.Errorf("expected paths to be sorted but got '%v'", paths)
+	}
+
+	if _, ok := tr.Get("foo/1"); !ok {
+		t.Errorf("expected 'foo/1' to exist")
+	}
+	if _, ok := tr.Get("foo/2"); ok {
+		t.Errorf("expected 'foo/2' to be deleted")
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		tr.Get("foo/99/bar")
+	})
+	if allocs != 0 {
+		t.Errorf("expected Get to not allocate but got %v allocations", allocs)
+	}
+}
+
+func TestIndexedChurn(t *testing.T) {
+	tr := trie.NewIndexed[int]("/")
+
+	// Deleting most of the paths drops their segments, the remaining ones
+	// must be unaffected.
+	expected := make(map[string]int)
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 100; i++ {
+			path := fmt.Sprintf("%s-%d/%d", strings.Repeat("x", 20), round, i)
+			tr.Put(path, i)
+			expected[path] = i
+		}
+		for i := 0; i < 100; i++ {
+			if i%10 != 0 {
+				path := fmt.Sprintf("%s-%d/%d", strings.Repeat("x", 20), round, i)
+				tr.Delete(path)
+				delete(expected, path)
+			}
+		}
+	}
+
+	got := make(map[string]int)
+	tr.Walk(func(path string, value int) bool {
+		got[path] = value
+		return true
+	})
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+type countingInstrumentation struct {
+	puts, gets, misses, deletes, depth int
+}
+
+func (c *countingInstrumentation) Put(depth int, _ time.Duration) {
+	c.puts++
+	c.depth += depth
+}
+
+func (c *countingInstrumentation) Get(depth int, found bool, _ time.Duration) {
+	c.gets++
+	if !found {
+		c.misses++
+	}
+	c.depth += depth
+}
+
+func (c *countingInstrumentation) Delete(depth int, _ bool, _ time.Duration) {
+	c.deletes++
+	c.depth += depth
+}
+
+func TestStringInstrumented(t *testing.T) {
+	inst := &countingInstrumentation{}
+	tr := trie.NewInstrumented[int]("/", inst)
+
+	tr.Put("foo/bar/baz", 1)
+	tr.Get("foo/bar/baz")
+	tr.Get("foo/qux")
+	tr.Delete("foo/bar")
+
+	expected := countingInstrumentation{puts: 1, gets: 2, misses: 1, deletes: 1, depth: 3 + 3 + 1 + 2}
+	if *inst != expected {
+		t.Errorf("expected '%+v' but got '%+v'", expected, *inst)
+	}
+}
+
+func TestStringOptions(t *testing.T) {
+	inst := &countingInstrumentation{}
+	tr := trie.New[string]("/",
+		trie.WithCodec[string](upperCodec{}),
+		trie.WithArena(),
+		trie.WithBloomFilter(100, 0.01),
+		trie.WithCache(10),
+		trie.WithInstrumentation(inst),
+	)
+
+	tr.Put("foo/bar", "baz")
+	if got, _ := tr.Get("foo/bar"); got != "baz" {
+		t.Errorf("expected 'baz' but got '%v'", got)
+	}
+	if inst.puts != 1 || inst.gets != 1 {
+		t.Errorf("expected one put and one get but got '%+v'", *inst)
+	}
+	if b, _ := tr.Codec().Encode("baz"); string(b) != "BAZ" {
+		t.Errorf("expected codec to be used but got '%s'", b)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected New to panic for a codec of the wrong type")
+		}
+	}()
+	trie.New[int]("/", trie.WithCodec[string](upperCodec{}))
+}
+
+func TestSliceDeleteRoot(t *testing.T) {
+	tr := trie.NewSlice[int, string]()
+	tr.Put(nil, "root")
+	tr.Put([]int{1}, "child")
+
+	tr.Delete(nil)
+
+	if got, _ := tr.Get(nil); got != "" {
+		t.Errorf("expected the root value to be removed but got '%v'", got)
+	}
+	if got, _ := tr.Get([]int{1}); got != "child" {
+		t.Errorf("expected 'child' but got '%v'", got)
+	}
+}
+
+func TestSliceLongestPrefix(t *testing.T) {
+	tr := trie.NewSlice[int, string]()
+	tr.Put([]int{1}, "1")
+	tr.Put([]int{1, 2, 3}, "123")
+	tr.Put([]int{1, 2, 3, 4, 5}, "12345")
+
+	for _, tc := range []struct {
+		path  []int
+		n     int
+		value string
+		found bool
+	}{
+		{nil, 0, "", false},
+		{[]int{2}, 0, "", false},
+		{[]int{1}, 1, "1", true},
+		{[]int{1, 2}, 1, "1", true},
+		{[]int{1, 2, 3}, 3, "123", true},
+		{[]int{1, 2, 3, 4}, 3, "123", true},
+		{[]int{1, 2, 3, 4, 5, 6}, 5, "12345", true},
+	} {
+		n, value, found := tr.LongestPrefix(tc.path)
+		if n != tc.n || value != tc.value || found != tc.found {
+			t.Errorf("%v: expected (%d, '%v', %v) but got (%d, '%v', %v)", tc.path, tc.n, tc.value, tc.found, n, value, found)
+		}
+	}
+
+	tr.Put(nil, "root")
+	if n, value, _ := tr.LongestPrefix([]int{2}); n != 0 || value != "root" {
+		t.Errorf("expected the root but got (%d, '%v')", n, value)
+	}
+}
+
+func TestStringRoot(t *testing.T) {
+	tr := trie.New[string]("/")
+	tr.Put("foo", "bar")
+
+	if _, ok := tr.Get(""); ok {
+		t.Errorf("expected the root to not be found without a value")
+	}
+
+	tr.Put("", "root")
+	if got, ok := tr.Get(""); !ok || got != "root" {
+		t.Errorf("expected 'root' but got '%v'", got)
+	}
+
+	tr.Delete("")
+	if _, ok := tr.Get(""); ok {
+		t.Errorf("expected the root value to be removed")
+	}
+	if got, _ := tr.Get("foo"); got != "bar" {
+		t.Errorf("expected 'bar' but got '%v'", got)
+	}
+}
+
+func TestStringEscape(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithEscape('\\'), trie.WithCache(10))
+
+	tr.Put(`a\/b/c`, 1)
+	tr.Put(`a/b\\/d`, 2)
+
+	if got, _ := tr.Get(`a\/b/c`); got != 1 {
+		t.Errorf("expected '1' but got '%v'", got)
+	}
+	if _, ok := tr.Get("a/b/c"); ok {
+		t.Errorf("expected 'a/b/c' to not exist")
+	}
+	if children, _ := tr.Children(""); !reflect.DeepEqual(children, []string{"a", "a/b"}) {
+		t.Errorf("expected children 'a' and 'a/b' but got '%v'", children)
+	}
+	if children, _ := tr.Children("a"); !reflect.DeepEqual(children, []string{`b\`}) {
+		t.Errorf(`expected child 'b\' but got '%v'`, children)
+	}
+
+	expected := map[string]int{`a\/b/c`: 1, `a/b\\/d`: 2}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+
+	tr.Delete(`a\/b`)
+	if _, ok := tr.Get(`a\/b/c`); ok {
+		t.Errorf("expected 'a\\/b/c' to be deleted")
+	}
+
+	if got := trie.EscapeSegment(`a/b\c`, "/", '\\'); got != `a\/b\\c` {
+		t.Errorf(`expected 'a\/b\\c' but got '%s'`, got)
+	}
+}
+
+func TestStringTrimmedDelimiters(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithTrimmedDelimiters())
+
+	tr.Put("/foo/bar/", 1)
+	for _, path := range []string{"foo/bar", "/foo/bar", "foo/bar/", "//foo/bar//"} {
+		if got, _ := tr.Get(path); got != 1 {
+			t.Errorf("expected '1' at '%s' but got '%v'", path, got)
+		}
+	}
+	if children, _ := tr.Children("/"); !reflect.DeepEqual(children, []string{"foo"}) {
+		t.Errorf("expected child 'foo' but got '%v'", children)
+	}
+
+	tr.Delete("foo/bar/")
+	if _, ok := tr.Get("foo/bar"); ok {
+		t.Errorf("expected 'foo/bar' to be deleted")
+	}
+}
+
+func TestStringKeyValidator(t *testing.T) {
+	errControl := errors.New("control character")
+	validator := func(segment string) error {
+		if strings.ContainsFunc(segment, func(r rune) bool { return r < ' ' }) {
+			return errControl
+		}
+		return nil
+	}
+	tr := trie.New[int]("/", trie.WithKeyValidator(validator), trie.WithValidatedGet())
+
+	if err := tr.PutE("foo/b\x00r", 1); !errors.Is(err, errControl) {
+		t.Errorf("expected validation error but got '%v'", err)
+	}
+	tr.Put("foo/b\nr", 1)
+	if err := tr.PutE("foo/bar", 2); err != nil {
+		t.Errorf("expected no error but got '%v'", err)
+	}
+
+	expected := map[string]int{"foo/bar": 2}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+	if _, ok := tr.Get("foo/b\x00r"); ok {
+		t.Errorf("expected invalid path to not be found")
+	}
+
+	var buf bytes.Buffer
+	unchecked := trie.New[int]("/")
+	unchecked.Put("foo/b\x00r", 1)
+	if _, err := unchecked.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.ReadFrom(&buf); !errors.Is(err, errControl) {
+		t.Errorf("expected validation error but got '%v'", err)
+	}
+
+	var log bytes.Buffer
+	wal := trie.NewWAL(tr, &log)
+	if err := wal.PutE("b\x00r", 1); !errors.Is(err, errControl) {
+		t.Errorf("expected validation error but got '%v'", err)
+	}
+	if log.Len() != 0 {
+		t.Errorf("expected rejected path to not be logged")
+	}
+}
+
+func TestStringFormat(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar", 1)
+	tr.Put("foo/baz/qux", 2)
+
+	expected := `trie.String{delimiter: "/", values: 2, depth: 3}`
+	if got := fmt.Sprint(tr); got != expected {
+		t.Errorf("expected '%s' but got '%s'", expected, got)
+	}
+
+	var buf bytes.Buffer
+	if err := tr.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%+v", tr); got != buf.String() {
+		t.Errorf("expected '%s' but got '%s'", buf.String(), got)
+	}
+}
+
+func TestStringNode(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("foo/bar/baz", 1)
+
+	n := tr.Root()
+	for _, segment := range []string{"foo", "bar"} {
+		var ok bool
+		n, ok = n.Child(segment)
+		if !ok {
+			t.Fatalf("expected child '%s' to exist", segment)
+		}
+	}
+	if _, ok := n.Child("qux"); ok {
+		t.Errorf("expected child 'qux' to not exist")
+	}
+	if got := n.Path(); got != "foo/bar" {
+		t.Errorf("expected 'foo/bar' but got '%s'", got)
+	}
+	if _, ok := n.Value(); ok {
+		t.Errorf("expected 'foo/bar' to not have a value")
+	}
+
+	n.Set(2)
+	if got, ok := n.Value(); !ok || got != 2 {
+		t.Errorf("expected '2' but got '%v'", got)
+	}
+	if got, _ := tr.Get("foo/bar"); got != 2 {
+		t.Errorf("expected '2' but got '%v'", got)
+	}
+
+	baz, _ := n.Child("baz")
+	if got, _ := baz.Value(); got != 1 {
+		t.Errorf("expected '1' but got '%v'", got)
+	}
+}
+
+func TestWrapperSegmentsEscaped(t *testing.T) {
+	tests := map[string]func(trie.String[int]) func(int, ...string){
+		"wal": func(tr trie.String[int]) func(int, ...string) {
+			return trie.NewWAL(tr, io.Discard).PutSegments
+		},
+		"journal": func(tr trie.String[int]) func(int, ...string) {
+			return trie.NewJournal(tr).PutSegments
+		},
+		"persistent": func(tr trie.String[int]) func(int, ...string) {
+			p, err := trie.NewPersistent(tr, mapBackend{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return p.PutSegments
+		},
+		"recorder": func(tr trie.String[int]) func(int, ...string) {
+			return trie.NewRecorder(tr, io.Discard).PutSegments
+		},
+	}
+	for name, wrap := range tests {
+		t.Run(name, func(t *testing.T) {
+			tr := trie.New[int]("/", trie.WithEscape('\\'))
+			wrap(tr)(1, "a", "b/c")
+			if got, ok := tr.GetSegments("a", "b/c"); !ok || got != 1 {
+				t.Errorf("expected '1' but got '%v'", got)
+			}
+			if _, ok := tr.GetSegments("a", "b", "c"); ok {
+				t.Errorf("expected a/b/c to not be found")
+			}
+		})
+	}
+}
+
+func TestWALNode(t *testing.T) {
+	var log bytes.Buffer
+	tr := trie.New[int]("/")
+	wal := trie.NewWAL(trie.Namespace[int](tr, "ns"), &log)
+	wal.Put("foo/bar", 1)
+
+	n, _ := wal.Root().Child("foo")
+	n.Set(2)
+	n, _ = n.Child("bar")
+	n.Set(3)
+	if err := wal.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	replayed := trie.NewWAL(trie.New[int]("/"), nil)
+	err := replayed.Replay(&log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]int{"foo": 2, "foo/bar": 3}
+	if got := replayed.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+	expected = map[string]int{"ns/foo": 2, "ns/foo/bar": 3}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithKeyValidator(func(segment string) error {
+		if segment == "" {
+			return errors.New("empty segment")
+		}
+		return nil
+	}))
+	tr.Put("foo/bar", 1)
+
+	if got, err := tr.GetE("foo/bar"); err != nil || got != 1 {
+		t.Errorf("expected '1' but got '%v', '%v'", got, err)
+	}
+	if _, err := tr.GetE("foo/baz"); !errors.Is(err, trie.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got '%v'", err)
+	}
+	if err := tr.DeleteE("foo/baz"); !errors.Is(err, trie.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got '%v'", err)
+	}
+	if err := tr.DeleteE("foo/bar"); err != nil {
+		t.Errorf("expected no error but got '%v'", err)
+	}
+	if err := tr.DeleteE(""); !errors.Is(err, trie.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got '%v'", err)
+	}
+	if err := tr.PutE("/foo", 1); !errors.Is(err, trie.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey but got '%v'", err)
+	}
+
+	var log bytes.Buffer
+	wal := trie.NewWAL(tr, &log)
+	if err := wal.DeleteE("missing"); !errors.Is(err, trie.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got '%v'", err)
+	}
+}
+
+func TestStringUnicodeNormalization(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithUnicodeNormalization(norm.NFC))
+
+	tr.Put("cafe\u0301/menu", 1)
+	if got, ok := tr.Get("café/menu"); !ok || got != 1 {
+		t.Errorf("expected '1' but got '%v'", got)
+	}
+
+	tr.Put("café/menu", 2)
+	expected := map[string]int{"café/menu": 2}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tr.Get("café/menu")
+	})
+	if allocs != 0 {
+		t.Errorf("expected Get of a normalized path to not allocate but got %v allocations", allocs)
+	}
+}
+
+func TestStringCaseFolding(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithCaseFolding())
+
+	tr.Put("Straße/MENU", 1)
+	for _, path := range []string{"strasse/menu", "STRASSE/Menu", "straße/menu"} {
+		if got, ok := tr.Get(path); !ok || got != 1 {
+			t.Errorf("%s: expected '1' but got '%v'", path, got)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tr.Get("strasse/menu")
+	})
+	if allocs != 0 {
+		t.Errorf("expected Get of a folded path to not allocate but got %v allocations", allocs)
+	}
+}
+
+func TestStringCollation(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithCollation(language.German))
+	for i, path := range []string{"z", "ä", "a", "b", "Ä"} {
+		tr.Put(path, i)
+	}
+
+	expected := []string{"a", "ä", "Ä", "b", "z"}
+	got, _ := tr.Children("")
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+
+	got = nil
+	tr.Walk(func(path string, _ int) bool {
+		got = append(got, path)
+		return true
+	})
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestStringSegments(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithEscape('\\'))
+
+	tr.PutSegments(1, "a", "b/c")
+	tr.PutSegments(2)
+	if got, ok := tr.GetSegments("a", "b/c"); !ok || got != 1 {
+		t.Errorf("expected '1' but got '%v'", got)
+	}
+	if got, ok := tr.Get(`a/b\/c`); !ok || got != 1 {
+		t.Errorf("expected '1' but got '%v'", got)
+	}
+	if got, ok := tr.GetSegments(); !ok || got != 2 {
+		t.Errorf("expected '2' but got '%v'", got)
+	}
+	if _, ok := tr.GetSegments("a", "b", "c"); ok {
+		t.Errorf("expected a/b/c to not be found")
+	}
+
+	segments := []string{"a", "b/c"}
+	allocs := testing.AllocsPerRun(100, func() {
+		tr.GetSegments(segments...)
+	})
+	if allocs != 0 {
+		t.Errorf("expected GetSegments to not allocate but got %v allocations", allocs)
+	}
+}
+
+func TestStringLimits(t *testing.T) {
+	tr := trie.New[int]("/", trie.WithMaxDepth(2), trie.WithMaxKeyLen(3))
+
+	for path, ok := range map[string]bool{
+		"a/b":     true,
+		"abc":     true,
+		"a/b/c":   false,
+		"abcd":    false,
+		"a/abcd":  false,
+		"":        true,
+		"a//":     true,
+		"a/b/c/d": false,
+	} {
+		err := tr.PutE(path, 1)
+		if ok && err != nil {
+			t.Errorf("%s: unexpected error: %v", path, err)
+		}
+		if !ok && !errors.Is(err, trie.ErrInvalidKey) {
+			t.Errorf("%s: expected ErrInvalidKey but got '%v'", path, err)
+		}
+	}
+
+	if _, ok := tr.Get("a/b/c"); ok {
+		t.Errorf("expected a/b/c to not be found")
+	}
+
+	deep := trie.New[int]("/")
+	deep.Put("a/b/c", 1)
+	var buf bytes.Buffer
+	_, err := deep.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tr.ReadFrom(&buf)
+	if !errors.Is(err, trie.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey but got '%v'", err)
+	}
+}
+
+func TestStringHooks(t *testing.T) {
+	var events []string
+	tr := trie.New[int]("/", trie.WithHooks(
+		func(path string, old, new int, existed bool) {
+			events = append(events, fmt.Sprintf("put %s %d %d %t", path, old, new, existed))
+		},
+		func(path string, value int) {
+			events = append(events, fmt.Sprintf("delete %s %d", path, value))
+		},
+	))
+
+	tr.Put("a/b", 1)
+	tr.Put("a/b", 2)
+	tr.Put("a/b/c/d", 3)
+	tr.Put("", 4)
+	tr.Delete("a/b/c")
+	tr.Delete("x")
+	tr.Delete("")
+	tr.Put("x", 5)
+	tr.Clear()
+
+	expected := []string{
+		"put a/b 0 1 false",
+		"put a/b 1 2 true",
+		"put a/b/c/d 0 3 false",
+		"put  0 4 false",
+		"delete a/b/c/d 3",
+		"delete  4",
+		"put x 0 5 false",
+		"delete a/b 2",
+		"delete x 5",
+	}
+	sort.Strings(events[len(events)-2:])
+	if !reflect.DeepEqual(expected, events) {
+		t.Errorf("expected '%v' but got '%v'", expected, events)
+	}
+}
+
+func TestStringEvict(t *testing.T) {
+	evicted := map[string]int{}
+	tr := trie.New[int]("/", trie.WithPooling(), trie.WithEvict(func(path string, value int) {
+		evicted[path] += value
+	}))
+
+	tr.Put("a", 1)
+	tr.Put("a", 2)
+	tr.Put("a/b", 3)
+	tr.Put("a/b/c", 4)
+	tr.Put("d", 5)
+	tr.Delete("a/b")
+	expected := map[string]int{"a": 1, "a/b": 3, "a/b/c": 4}
+	if !reflect.DeepEqual(expected, evicted) {
+		t.Errorf("expected '%v' but got '%v'", expected, evicted)
+	}
+
+	tr.Clear()
+	expected = map[string]int{"a": 3, "a/b": 3, "a/b/c": 4, "d": 5}
+	if !reflect.DeepEqual(expected, evicted) {
+		t.Errorf("expected '%v' but got '%v'", expected, evicted)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("other/a", 1)
+	ns := trie.Namespace(tr, "lib/one")
+
+	if _, ok := ns.Get(""); ok {
+		t.Errorf("expected the root of the namespace to not be found")
+	}
+	ns.Put("a", 2)
+	ns.Put("a/b", 3)
+	ns.PutSegments(4, "c")
+	if got, ok := tr.Get("lib/one/a/b"); !ok || got != 3 {
+		t.Errorf("expected '3' but got '%v'", got)
+	}
+	if got, ok := ns.GetSegments("c"); !ok || got != 4 {
+		t.Errorf("expected '4' but got '%v'", got)
+	}
+
+	expected := map[string]int{"a": 2, "a/b": 3, "c": 4}
+	if got := ns.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+	if got, _ := ns.Children(""); !reflect.DeepEqual([]string{"a", "c"}, got) {
+		t.Errorf("expected '[a c]' but got '%v'", got)
+	}
+	if err := ns.DeleteE("x"); !errors.Is(err, trie.ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got '%v'", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := ns.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	copied := trie.Namespace(tr, "lib/two")
+	if _, err := copied.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := copied.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+
+	ns.Clear()
+	expected = map[string]int{"other/a": 1, "lib/two/a": 2, "lib/two/a/b": 3, "lib/two/c": 4}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+
+	root := ns.Root()
+	root.Set(5)
+	if got, ok := ns.Get(""); !ok || got != 5 {
+		t.Errorf("expected '5' but got '%v'", got)
+	}
+}
+
+func TestStringRekey(t *testing.T) {
+	var evicted []string
+	tr := trie.New[int]("/", trie.WithBloomFilter(100, 0.01), trie.WithEvict(func(path string, _ int) {
+		evicted = append(evicted, path)
+	}))
+	tr.Put("app/name", 1)
+	tr.Put("app/port", 2)
+	tr.Put("legacy/x", 3)
+
+	err := tr.Rekey(func(path string) (string, bool) {
+		if strings.HasPrefix(path, "legacy/") {
+			return "", false
+		}
+		return "service/" + strings.TrimPrefix(path, "app/"), true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"service/name": 1, "service/port": 2}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+	if _, ok := tr.Get("app"); ok {
+		t.Errorf("expected app to not be found")
+	}
+	if got, ok := tr.Get("service/port"); !ok || got != 2 {
+		t.Errorf("expected '2' but got '%v'", got)
+	}
+	if !reflect.DeepEqual([]string{"legacy/x"}, evicted) {
+		t.Errorf("expected '[legacy/x]' but got '%v'", evicted)
+	}
+
+	err = tr.Rekey(func(string) (string, bool) {
+		return "same", true
+	})
+	if err == nil {
+		t.Errorf("expected an error for colliding paths")
+	}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+
+	j := trie.NewJournal(tr)
+	err = j.Rekey(func(path string) (string, bool) {
+		return strings.ToUpper(path), true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]int{"SERVICE/NAME": 1, "SERVICE/PORT": 2}
+	if got := tr.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestStringLookup(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("a/b/c", 0)
+	tr.Put("a", 1)
+
+	for path, expected := range map[string]trie.LookupState{
+		"":      trie.ImplicitNode,
+		"a":     trie.Exists,
+		"a/b":   trie.ImplicitNode,
+		"a/b/c": trie.Exists,
+		"a/x":   trie.Missing,
+	} {
+		if _, got := tr.Lookup(path); got != expected {
+			t.Errorf("%s: expected '%v' but got '%v'", path, expected, got)
+		}
+	}
+
+	if got, state := tr.Lookup("a"); got != 1 || state != trie.Exists {
+		t.Errorf("expected '1' but got '%v'", got)
+	}
+	tr.Put("", 2)
+	if _, state := tr.Lookup(""); state != trie.Exists {
+		t.Errorf("expected '%v' but got '%v'", trie.Exists, state)
+	}
+}
+
+func TestStringWalkPath(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("", 0)
+	tr.Put("a", 1)
+	tr.Put("a/b/c", 3)
+	tr.Put("a/b/c/d/e", 5)
+
+	collect := func(tr trie.String[int], path string, limit int) []string {
+		var got []string
+		tr.WalkPath(path, func(p string, value int) bool {
+			got = append(got, fmt.Sprintf("%s=%d", p, value))
+			return len(got) < limit
+		})
+		return got
+	}
+
+	for _, tc := range []struct {
+		path     string
+		limit    int
+		expected []string
+	}{
+		{"a/b/c/d", 10, []string{"=0", "a=1", "a/b/c=3"}},
+		{"a/b/c/d/e/f", 10, []string{"=0", "a=1", "a/b/c=3", "a/b/c/d/e=5"}},
+		{"x/y", 10, []string{"=0"}},
+		{"a/b/c/d/e", 2, []string{"=0", "a=1"}},
+	} {
+		if got := collect(tr, tc.path, tc.limit); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v but got %v", tc.path, tc.expected, got)
+		}
+	}
+
+	ns := trie.Namespace(tr, "a/b")
+	expected := []string{"c=3", "c/d/e=5"}
+	if got := collect(ns, "c/d/e", 10); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
+
+func TestStringList(t *testing.T) {
+	tr := trie.New[int]("/")
+	for _, path := range []string{
+		"photos/2023/a.jpg",
+		"photos/2023/b.jpg",
+		"photos/2024/c.jpg",
+		"photos/index.html",
+		"photos-old/x.jpg",
+		"readme.md",
+	} {
+		tr.Put(path, 1)
+	}
+	// A node without a value is not listed.
+	tr.Put("videos/a.mp4", 1)
+	tr.Delete("videos/a.mp4")
+
+	for _, tc := range []struct {
+		prefix, delimiter string
+		maxKeys           int
+		startAfter        string
+		keys, prefixes    []string
+		truncated         bool
+	}{
+		{"", "/", 0, "", []string{"readme.md"}, []string{"photos-old/", "photos/"}, false},
+		{"photos/", "/", 0, "", []string{"photos/index.html"}, []string{"photos/2023/", "photos/2024/"}, false},
+		{"photos", "", 0, "", []string{"photos-old/x.jpg", "photos/2023/a.jpg", "photos/2023/b.jpg", "photos/2024/c.jpg", "photos/index.html"}, nil, false},
+		{"photos/20", "", 2, "", []string{"photos/2023/a.jpg", "photos/2023/b.jpg"}, nil, true},
+		{"photos/20", "", 2, "photos/2023/b.jpg", []string{"photos/2024/c.jpg"}, nil, false},
+		{"photos/", "/", 2, "photos/2023/", []string{"photos/index.html"}, []string{"photos/2024/"}, false},
+		{"", ".", 0, "", nil, []string{"photos-old/x.", "photos/2023/a.", "photos/2023/b.", "photos/2024/c.", "photos/index.", "readme."}, false},
+		{"x", "/", 0, "", nil, nil, false},
+	} {
+		keys, prefixes, truncated := tr.List(tc.prefix, tc.delimiter, tc.maxKeys, tc.startAfter)
+		if !reflect.DeepEqual(keys, tc.keys) || !reflect.DeepEqual(prefixes, tc.prefixes) || truncated != tc.truncated {
+			t.Errorf("%q %q %d %q: expected %v, %v, %v but got %v, %v, %v", tc.prefix, tc.delimiter, tc.maxKeys, tc.startAfter,
+				tc.keys, tc.prefixes, tc.truncated, keys, prefixes, truncated)
+		}
+	}
+
+	keys, prefixes, _ := trie.Namespace(tr, "photos").List("", "/", 0, "2023/")
+	if !reflect.DeepEqual(keys, []string{"index.html"}) || !reflect.DeepEqual(prefixes, []string{"2024/"}) {
+		t.Errorf("expected [index.html] and [2024/] but got %v and %v", keys, prefixes)
+	}
+}
+
+func TestStatsOf(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("a/b/c", 1)
+	tr.Put("a/b/d", 2)
+	tr.Put("e", 3)
+
+	// The root, a/b, a/b/c, a/b/d and e.
+	expected := trie.Stats{Values: 3, Nodes: 5, Depth: 3}
+	if got := trie.StatsOf(tr); got != expected {
+		t.Errorf("expected '%+v' but got '%+v'", expected, got)
+	}
+	expected = trie.Stats{Values: 2, Nodes: 3, Depth: 1}
+	if got := trie.StatsOf(trie.Namespace(tr, "a/b")); got != expected {
+		t.Errorf("expected '%+v' but got '%+v'", expected, got)
+	}
+}
+
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	name  string
+	attrs map[string]any
+	ended bool
+}
+
+func (r *recordingTracer) Start(name string) trie.Span {
+	s := &recordingSpan{name: name, attrs: map[string]any{}}
+	r.spans = append(r.spans, s)
+	return s
+}
+
+func (s *recordingSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *recordingSpan) End()                               { s.ended = true }
+
+func TestStringTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	tr := trie.New[int]("/", trie.WithTracer(tracer))
+	tr.Put("a/b", 1)
+	tr.Put("a/c", 2)
+	tr.Put("d", 3)
+
+	trie.Namespace(tr, "a").Walk(func(string, int) bool { return true })
+	tr.GetBatch([]string{"a/b", "x"}, make([]int, 2))
+	var buf bytes.Buffer
+	if _, err := tr.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []recordingSpan{
+		{"trie.Walk", map[string]any{"trie.prefix": "a", "trie.values": 2}, true},
+		{"trie.GetBatch", map[string]any{"trie.paths": 2, "trie.found": 1}, true},
+		{"trie.Encode", map[string]any{"trie.values": 3, "trie.bytes": buf.Len()}, true},
+	}
+	if len(tracer.spans) != len(expected) {
+		t.Fatalf("expected %d spans but got %d", len(expected), len(tracer.spans))
+	}
+	for i, s := range tracer.spans {
+		if !reflect.DeepEqual(expected[i], *s) {
+			t.Errorf("expected '%v' but got '%v'", expected[i], *s)
+		}
+	}
+}
+
+func TestStringLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	tr := trie.New[int]("/", trie.WithLogger(logger))
+	tr.Put("a/b/c", 1)
+	tr.Put("a/b/d", 2)
+	tr.Put("e", 3)
+
+	tr.Delete("a/b")
+	tr.Delete("x")
+	tr.Compact()
+	tr.Clear()
+
+	expected := `level=DEBUG msg="trie: deleted subtree" path=a/b values=2 nodes=3
+level=DEBUG msg="trie: compacted" nodesBefore=3 nodesAfter=2
+level=DEBUG msg="trie: cleared" values=1 nodes=2
+`
+	if buf.String() != expected {
+		t.Errorf("expected '%s' but got '%s'", expected, buf.String())
+	}
+}
+
+func TestStringValidate(t *testing.T) {
+	for name, opts := range map[string][]trie.Option{
+		"default": nil,
+		"options": {trie.WithBloomFilter(100, 0.01), trie.WithCache(4), trie.WithEscape('\\'), trie.WithPooling()},
+	} {
+		tr := trie.New[int]("/", opts...)
+		for i := 0; i < 50; i++ {
+			tr.Put(fmt.Sprintf("a/%d/b/%d", i%7, i), i)
+			tr.Get(fmt.Sprintf("a/%d", i%5))
+		}
+		tr.PutSegments(1, "x/y", "z")
+		tr.Delete("a/3/b")
+		tr.Delete("a/4/b/4")
+		if err := tr.Validate(); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		tr.Compact()
+		if err := tr.Validate(); err != nil {
+			t.Errorf("%s: unexpected error after Compact: %v", name, err)
+		}
+	}
+
+	// Segments read by ReadFrom are not passed through the transformation
+	// of the trie.
+	var b bytes.Buffer
+	src := trie.New[int]("/")
+	src.Put("Foo/bar", 1)
+	_, _ = src.WriteTo(&b)
+
+	tr := trie.New[int]("/", trie.WithCaseFolding())
+	if _, err := tr.ReadFrom(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := tr.Validate()
+	var invErr *trie.InvariantError
+	if !errors.Is(err, trie.ErrInvariant) || !errors.As(err, &invErr) || invErr.Path != "Foo/bar" {
+		t.Errorf("expected an invariant error for Foo/bar but got %v", err)
+	}
+	if err := trie.Namespace(tr, "Foo").Validate(); err == nil {
+		t.Errorf("expected the namespace to validate the whole trie")
+	}
+}
+
+func TestStringScheduledPutDelete(t *testing.T) {
+	ops := []trietest.Op{
+		{Kind: trietest.Put, Path: "a/b/c", Value: 1},
+		{Kind: trietest.Delete, Path: "a/b"},
+		{Kind: trietest.Put, Path: "a/b", Value: 2},
+		{Kind: trietest.Delete, Path: "a"},
+	}
+
+	// Every interleaving must have the same outcome as one of the
+	// sequential orders of the operations.
+	outcomes := make(map[string]bool)
+	permute(len(ops), func(order []int) {
+		m := trietest.NewModel()
+		for _, i := range order {
+			if op := ops[i]; op.Kind == trietest.Put {
+				m.Put(op.Path, op.Value)
+			} else {
+				m.Delete(op.Path)
+			}
+		}
+		outcomes[fmt.Sprint(modelMap(m))] = true
+	})
+
+	for seed := int64(0); seed < 200; seed++ {
+		s := trietest.NewScheduler(seed)
+		tr := trie.New[int]("/", trie.WithScheduler(s))
+		for _, op := range ops {
+			op := op
+			s.Go(func() {
+				if op.Kind == trietest.Put {
+					tr.Put(op.Path, op.Value)
+				} else {
+					tr.Delete(op.Path)
+				}
+			})
+		}
+		s.Run()
+
+		if err := tr.Validate(); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if got := fmt.Sprint(tr.ToMap()); !outcomes[got] {
+			t.Fatalf("seed %d: %s is not the outcome of any sequential order", seed, got)
+		}
+	}
+}
+
+// permute calls fn with every permutation of the numbers 0 to n-1.
+func permute(n int, fn func([]int)) {
+	var rec func(order []int, used []bool)
+	rec = func(order []int, used []bool) {
+		if len(order) == n {
+			fn(order)
+			return
+		}
+		for i := 0; i < n; i++ {
+			if !used[i] {
+				used[i] = true
+				rec(append(order, i), used)
+				used[i] = false
+			}
+		}
+	}
+	rec(nil, make([]bool, n))
+}
+
+func modelMap(m *trietest.Model) map[string]int {
+	values := make(map[string]int)
+	m.Walk(func(path string, value int) bool {
+		values[path] = value
+		return true
+	})
+	return values
+}
+
+func TestRecorder(t *testing.T) {
+	var log bytes.Buffer
+	rec := trie.NewRecorder(trie.New[int]("/"), &log)
+	rec.Put("a/b", 1)
+	rec.Put("a/c", 2)
+	done := make(chan struct{})
+	go func() {
+		rec.Delete("a/b")
+		close(done)
+	}()
+	<-done
+	rec.Clear()
+	rec.PutSegments(3, "d", "e")
+	if err := rec.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var records []trie.Record[int]
+	err := trie.ReadRecords[int](bytes.NewReader(log.Bytes()), nil, func(r trie.Record[int]) bool {
+		records = append(records, r)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ops []string
+	for i, r := range records {
+		ops = append(ops, fmt.Sprintf("%s %s %d", r.Op, r.Path, r.Value))
+		if i > 0 && r.Time.Before(records[i-1].Time) {
+			t.Errorf("expected records in chronological order but got %v", records)
+		}
+	}
+	if exp := []string{"Put a/b 1", "Put a/c 2", "Delete a/b 0", "Clear  0", "Put d/e 3"}; !reflect.DeepEqual(ops, exp) {
+		t.Errorf("expected %v but got %v", exp, ops)
+	}
+	if g := records[0].Goroutine; g == 0 || records[2].Goroutine == g || records[3].Goroutine != g {
+		t.Errorf("unexpected goroutines %d, %d, %d", g, records[2].Goroutine, records[3].Goroutine)
+	}
+
+	replayed := trie.NewRecorder(trie.New[int]("/"), io.Discard)
+	if err := replayed.Replay(bytes.NewReader(log.Bytes())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := replayed.ToMap(); !reflect.DeepEqual(got, map[string]int{"d/e": 3}) {
+		t.Errorf("unexpected state after replay: %v", got)
+	}
+
+	// A truncated recording is replayed up to the last complete record.
+	replayed = trie.NewRecorder(trie.New[int]("/"), io.Discard)
+	err = replayed.Replay(bytes.NewReader(log.Bytes()[:log.Len()-1]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF but got %v", err)
+	}
+	if _, ok := replayed.Get("a"); ok {
+		t.Errorf("expected the trie to be cleared")
+	}
+}
+
+func TestStringDumpCanonical(t *testing.T) {
+	tr := trie.New[string]("/", trie.WithEscape('\\'))
+	tr.Put("b/a", "x")
+	tr.Put("a/b/c", "y")
+	tr.Put("a", "z")
+	tr.PutSegments("w", "a/b", "c")
+	tr.Put("", "root")
+
+	expected := `trie canonical v1 "/"
+"" = root
+"a" = z
+"a/b"
+"a/b/c" = y
+"a\\/b"
+"a\\/b/c" = w
+"b"
+"b/a" = x
+`
+	if got := tr.DumpCanonical(); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+
+	expected = `trie canonical v1 "/"
+"" = z
+"b"
+`
+	tr.Delete("a/b/c")
+	if got := trie.Namespace(tr, "a").DumpCanonical(); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+}
+
+func BenchmarkStringGetGenerated(b *testing.B) {
+	for _, shape := range []trietest.Shape{trietest.Filesystem, trietest.URL, trietest.UUID, trietest.Zipf} {
+		b.Run(shape.String(), func(b *testing.B) {
+			b.ReportAllocs()
+
+			tr := trie.New[int](trietest.Delimiter)
+			keys := trietest.Generate(10000, shape)
+			for i, key := range keys {
+				tr.Put(key, i)
+			}
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				tr.Get(keys[n%len(keys)])
+			}
+		})
+	}
+}
+
+func TestStringGetShortestPrefix(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("a/b", 2)
+	tr.Put("a/b/c/d", 4)
+	tr.Put("x", 1)
+
+	for _, tc := range []struct {
+		path   string
+		value  int
+		prefix string
+		found  bool
+	}{
+		{"a/b/c/d/e", 2, "a/b", true},
+		{"a/b", 2, "a/b", true},
+		{"a/c", 0, "", false},
+		{"a", 0, "", false},
+		{"x/y/z", 1, "x", true},
+		{"", 0, "", false},
+	} {
+		value, prefix, found := tr.GetShortestPrefix(tc.path)
+		if value != tc.value || prefix != tc.prefix || found != tc.found {
+			t.Errorf("%s: expected (%d, %q, %t) but got (%d, %q, %t)", tc.path, tc.value, tc.prefix, tc.found, value, prefix, found)
+		}
+	}
+
+	tr.Put("", 0)
+	if _, prefix, found := tr.GetShortestPrefix("a/b"); prefix != "" || !found {
+		t.Errorf("expected the root to be the shortest prefix")
+	}
+
+	ns := trie.Namespace(tr, "a/b")
+	if value, prefix, found := ns.GetShortestPrefix("c/d/e"); value != 2 || prefix != "" || !found {
+		t.Errorf("expected the root of the namespace but got (%d, %q, %t)", value, prefix, found)
+	}
+}
+
+func TestStringSuffix(t *testing.T) {
+	for name, opts := range map[string][]trie.Option{
+		"scan":  nil,
+		"index": {trie.WithSuffixIndex()},
+	} {
+		tr := trie.New[int]("/", opts...)
+		tr.Put("svc/a/healthz", 1)
+		tr.Put("svc/b/healthz", 2)
+		tr.Put("b/healthz", 3)
+		tr.Put("healthz", 4)
+		tr.Put("svc/a", 5)
+
+		check := func(path string, value int, suffix string, found bool) {
+			t.Helper()
+			v, s, f := tr.GetLongestSuffix(path)
+			if v != value || s != suffix || f != found {
+				t.Errorf("%s: %s: expected (%d, %q, %t) but got (%d, %q, %t)", name, path, value, suffix, found, v, s, f)
+			}
+		}
+		check("x/svc/b/healthz", 2, "svc/b/healthz", true)
+		check("x/b/healthz", 3, "b/healthz", true)
+		check("x/c/healthz", 4, "healthz", true)
+		check("x/healthz/y", 0, "", false)
+
+		if got := tr.SuffixKeys("healthz"); !reflect.DeepEqual(got, []string{"b/healthz", "healthz", "svc/a/healthz", "svc/b/healthz"}) {
+			t.Errorf("%s: unexpected keys %v", name, got)
+		}
+
+		tr.Delete("svc")
+		tr.Delete("healthz")
+		check("svc/b/healthz", 3, "b/healthz", true)
+		check("x/c/healthz", 0, "", false)
+		if got := tr.SuffixKeys("b/healthz"); !reflect.DeepEqual(got, []string{"b/healthz"}) {
+			t.Errorf("%s: unexpected keys %v", name, got)
+		}
+
+		tr.Put("", 0)
+		check("x/c/healthz", 0, "", true)
+		_ = tr.Rekey(func(path string) (string, bool) {
+			return strings.Replace(path, "healthz", "ready", 1), path != ""
+		})
+		check("x/b/ready", 3, "b/ready", true)
+		if got := tr.SuffixKeys("healthz"); len(got) != 0 {
+			t.Errorf("%s: unexpected keys %v", name, got)
+		}
+
+		tr.Clear()
+		if got := tr.SuffixKeys(""); len(got) != 0 {
+			t.Errorf("%s: unexpected keys %v", name, got)
+		}
+	}
+
+	// The index must stay in sync with concurrent writes.
+	tr := trie.New[int]("/", trie.WithSuffixIndex())
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				tr.Put(fmt.Sprintf("a/%d/x", i%7), i)
+				tr.Delete(fmt.Sprintf("a/%d", (i+w)%7))
+			}
+		}(w)
+	}
+	wg.Wait()
+	var expected []string
+	tr.Walk(func(path string, _ int) bool {
+		expected = append(expected, path)
+		return true
+	})
+	sort.Strings(expected)
+	if got := tr.SuffixKeys("x"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	a := trie.FromMap(map[string]int{"a": 1, "a/b": 2, "c": 3, "d/e": 4}, "/")
+	b := trie.FromMap(map[string]int{"a/b": 20, "c/x": 30, "d": 40, "d/e": 50}, "/")
+
+	union := trie.Union(a, b, func(path string, a, b int) int {
+		return a + b
+	})
+	expected := map[string]int{"a": 1, "a/b": 22, "c": 3, "c/x": 30, "d": 40, "d/e": 54}
+	if got := union.ToMap(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("union: expected %v but got %v", expected, got)
+	}
+	expected["a/b"], expected["d/e"] = 20, 50
+	if got := trie.Union(a, b, nil).ToMap(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("union without merge: expected %v but got %v", expected, got)
+	}
+
+	// "c" and "d" exist as nodes without a value in b and a respectively.
+	expected = map[string]int{"a/b": 2, "d/e": 4}
+	if got := trie.Intersect(a, b).ToMap(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("intersect: expected %v but got %v", expected, got)
+	}
+	expected = map[string]int{"a": 1, "c": 3}
+	if got := trie.Subtract(a, b).ToMap(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("subtract: expected %v but got %v", expected, got)
+	}
+
+	if got := trie.Intersect(a, trie.Namespace(b, "a")).ToMap(); len(got) != 0 {
+		t.Errorf("intersect namespace: expected no values but got %v", got)
+	}
+	if got := trie.Intersect(trie.Namespace(a, "a"), trie.Namespace(b, "a")).ToMap(); !reflect.DeepEqual(got, map[string]int{"b": 2}) {
+		t.Errorf("intersect namespaces: expected %v but got %v", map[string]int{"b": 2}, got)
+	}
+}
+
+func TestStringPrefixPredicates(t *testing.T) {
+	tr := trie.New[int]("/")
+	tr.Put("a/b", 2)
+	tr.Put("a/b/c/d", 4)
+	tr.Put("x", 1)
+
+	for _, tc := range []struct {
+		path              string
+		containsPrefixOf  bool
+		hasKeysWithPrefix bool
+	}{
+		{"a/b/c/d/e", true, false},
+		{"a/b/c", true, true},
+		{"a", false, true},
+		{"a/c", false, false},
+		{"x", true, true},
+		{"y", false, false},
+		{"", false, true},
+	} {
+		if got := tr.ContainsPrefixOf(tc.path); got != tc.containsPrefixOf {
+			t.Errorf("ContainsPrefixOf(%q): expected %t but got %t", tc.path, tc.containsPrefixOf, got)
+		}
+		if got := tr.HasKeysWithPrefix(tc.path); got != tc.hasKeysWithPrefix {
+			t.Errorf("HasKeysWithPrefix(%q): expected %t but got %t", tc.path, tc.hasKeysWithPrefix, got)
+		}
+	}
+
+	tr.Delete("a/b/c")
+	tr.Put("", 0)
+	if !tr.ContainsPrefixOf("y") {
+		t.Errorf("expected the root to prefix every path")
+	}
+	if tr.HasKeysWithPrefix("a/b/c") {
+		t.Errorf("expected no keys below a deleted node")
+	}
+
+	ns := trie.Namespace(tr, "a")
+	if ns.ContainsPrefixOf("c") || !ns.ContainsPrefixOf("b/c") || !ns.HasKeysWithPrefix("") || ns.HasKeysWithPrefix("c") {
+		t.Errorf("unexpected results for namespace")
+	}
+}
+
+func TestStringShortestLongestKeys(t *testing.T) {
+	tr := trie.New[int]("/")
+	for _, path := range []string{"a/b/c/d", "a/b", "x", "a/c/e", "a/b/c", "b/c/d", "y"} {
+		tr.Put(path, 0)
+	}
+
+	for _, tc := range []struct {
+		k        int
+		shortest []string
+		longest  []string
+	}{
+		{0, nil, nil},
+		{1, []string{"x"}, []string{"a/b/c/d"}},
+		{3, []string{"x", "y", "a/b"}, []string{"a/b/c/d", "a/b/c", "a/c/e"}},
+		{10, []string{"x", "y", "a/b", "a/b/c", "a/c/e", "b/c/d", "a/b/c/d"}, []string{"a/b/c/d", "a/b/c", "a/c/e", "b/c/d", "a/b", "x", "y"}},
+	} {
+		if got := tr.ShortestKeys(tc.k); !reflect.DeepEqual(got, tc.shortest) {
+			t.Errorf("ShortestKeys(%d): expected %v but got %v", tc.k, tc.shortest, got)
+		}
+		if got := tr.LongestKeys(tc.k); !reflect.DeepEqual(got, tc.longest) {
+			t.Errorf("LongestKeys(%d): expected %v but got %v", tc.k, tc.longest, got)
+		}
+	}
+
+	tr.Put("", 0)
+	if got := tr.ShortestKeys(1); !reflect.DeepEqual(got, []string{""}) {
+		t.Errorf("expected the root but got %v", got)
+	}
+
+	ns := trie.Namespace(tr, "a")
+	if got := ns.ShortestKeys(2); !reflect.DeepEqual(got, []string{"b", "b/c"}) {
+		t.Errorf("namespace: unexpected shortest keys %v", got)
+	}
+	if got := ns.LongestKeys(2); !reflect.DeepEqual(got, []string{"b/c/d", "b/c"}) {
+		t.Errorf("namespace: unexpected longest keys %v", got)
+	}
+}
+
+func TestStringSample(t *testing.T) {
+	for name, opts := range map[string][]trie.Option{
+		"walk":   nil,
+		"counts": {trie.WithSubtreeCounts()},
+	} {
+		tr := trie.New[int]("/", opts...)
+		for i := 0; i < 20; i++ {
+			tr.Put(fmt.Sprintf("a/%d/b", i), i)
+		}
+		tr.Put("a/5", 100)
+		tr.Put("", -1)
+		tr.Delete("a/5")
+		tr.Delete("a/7/b")
+		tr.Compact()
+		if err := tr.Validate(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		var walked []string
+		tr.Walk(func(path string, _ int) bool {
+			walked = append(walked, path)
+			return true
+		})
+		if got := tr.Sample(100); !reflect.DeepEqual(got, walked) {
+			t.Errorf("%s: expected all paths %v but got %v", name, walked, got)
+		}
+		if got := tr.Sample(0); len(got) != 0 {
+			t.Errorf("%s: expected an empty sample but got %v", name, got)
+		}
+
+		counts := make(map[string]int)
+		for i := 0; i < 2000; i++ {
+			sample := tr.Sample(3)
+			if len(sample) != 3 || !sort.StringsAreSorted(sample) || sample[0] == sample[1] || sample[1] == sample[2] {
+				t.Fatalf("%s: unexpected sample %v", name, sample)
+			}
+			for _, path := range sample {
+				counts[path]++
+			}
+		}
+		// Every path is expected to be drawn 2000*3/20 = 300 times.
+		for _, path := range walked {
+			if counts[path] < 200 || counts[path] > 450 {
+				t.Errorf("%s: %q was drawn %d times", name, path, counts[path])
+			}
+		}
+
+		ns := trie.Namespace(tr, "a/3")
+		if got := ns.Sample(5); !reflect.DeepEqual(got, []string{"b"}) {
+			t.Errorf("%s: unexpected namespace sample %v", name, got)
+		}
+
+		_ = tr.Rekey(func(path string) (string, bool) {
+			return path, path != "a/4/b"
+		})
+		tr.Delete("")
+		if err := tr.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if got := tr.Sample(100); len(got) != 17 {
+			t.Errorf("%s: expected 17 paths but got %d", name, len(got))
+		}
+		tr.Clear()
+		if got := tr.Sample(1); len(got) != 0 {
+			t.Errorf("%s: expected an empty sample but got %v", name, got)
+		}
+	}
+
+	// The counts must stay consistent with concurrent writes.
+	tr := trie.New[int]("/", trie.WithSubtreeCounts())
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				tr.Put(fmt.Sprintf("a/%d/x/%d", i%7, w), i)
+				tr.Delete(fmt.Sprintf("a/%d", (i+w)%7))
+				tr.Sample(2)
+				if i%100 == 0 {
+					tr.Compact()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := tr.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStringSampleWeighted(t *testing.T) {
+	tr := trie.New[float64]("/")
+	tr.Put("heavy", 9)
+	tr.Put("light", 1)
+	tr.Put("never", 0)
+
+	heavy := 0
+	for i := 0; i < 1000; i++ {
+		sample := tr.SampleWeighted(1, func(w float64) float64 { return w })
+		if len(sample) != 1 || sample[0] == "never" {
+			t.Fatalf("unexpected sample %v", sample)
+		}
+		if sample[0] == "heavy" {
+			heavy++
+		}
+	}
+	if heavy < 850 || heavy > 950 {
+		t.Errorf("expected heavy to be drawn about 900 times but got %d", heavy)
+	}
+
+	if got := tr.SampleWeighted(5, func(w float64) float64 { return w }); !reflect.DeepEqual(got, []string{"heavy", "light"}) {
+		t.Errorf("unexpected sample %v", got)
+	}
+}
+
+func TestStringWalkFrom(t *testing.T) {
+	tr := trie.New[int]("/")
+	for i, path := range []string{"a", "a/b", "a/c", "b/a", "c"} {
+		tr.Put(path, i)
+	}
+
+	collect := func(tr trie.String[int], from string, limit int) []string {
+		var paths []string
+		tr.WalkFrom(from, func(path string, _ int) bool {
+			paths = append(paths, path)
+			return len(paths) < limit
+		})
+		return paths
+	}
+
+	for _, tc := range []struct {
+		from     string
+		expected []string
+	}{
+		{"", []string{"a", "a/b", "a/c", "b/a", "c"}},
+		{"a", []string{"a", "a/b", "a/c", "b/a", "c"}},
+		{"a/b", []string{"a/b", "a/c", "b/a", "c"}},
+		{"a/bb", []string{"a/c", "b/a", "c"}},
+		{"a/c/d", []string{"b/a", "c"}},
+		{"b", []string{"b/a", "c"}},
+		{"bb", []string{"c"}},
+		{"d", nil},
+	} {
+		if got := collect(tr, tc.from, 100); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%q: expected %v but got %v", tc.from, tc.expected, got)
+		}
+	}
+
+	// Resuming at the last path of an interrupted walk visits it again.
+	paths := collect(tr, "", 2)
+	paths = append(paths, collect(tr, paths[len(paths)-1], 100)[1:]...)
+	if expected := []string{"a", "a/b", "a/c", "b/a", "c"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v but got %v", expected, paths)
+	}
+
+	if got := collect(trie.Namespace(tr, "a"), "bb", 100); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("namespace: unexpected paths %v", got)
+	}
+
+	collated := trie.New[int]("/", trie.WithCollation(language.German))
+	for _, path := range []string{"a", "ä", "b"} {
+		collated.Put(path, 0)
+	}
+	if got := collect(collated, "ä", 100); !reflect.DeepEqual(got, []string{"ä", "b"}) {
+		t.Errorf("collation: unexpected paths %v", got)
+	}
+}
+
+func TestStringIterator(t *testing.T) {
+	tr := trie.New[int]("/")
+	for i, path := range []string{"", "a", "a/b", "a/c", "b/a", "c"} {
+		tr.Put(path, i)
+	}
+
+	collect := func(it *trie.Iterator[int]) []string {
+		var paths []string
+		for it.Next() {
+			paths = append(paths, it.Path())
+		}
+		return paths
+	}
+
+	it := tr.Iterator()
+	defer it.Close()
+	if got, expected := collect(it), []string{"", "a", "a/b", "a/c", "b/a", "c"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+
+	for _, from := range []string{"", "a", "a/b", "a/bb", "a/c/d", "b", "bb", "d"} {
+		var expected []string
+		tr.WalkFrom(from, func(path string, _ int) bool {
+			expected = append(expected, path)
+			return true
+		})
+		it.Seek(from)
+		if got := collect(it); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q: expected %v but got %v", from, expected, got)
+		}
+	}
+
+	it.Seek("a/c")
+	if !it.Next() || it.Path() != "a/c" || it.Value() != 3 {
+		t.Errorf("expected 'a/c' with value 3 but got '%s' with value %d", it.Path(), it.Value())
+	}
+
+	ns := trie.Namespace[int](tr, "a").Iterator()
+	defer ns.Close()
+	if got, expected := collect(ns), []string{"", "b", "c"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("namespace: expected %v but got %v", expected, got)
+	}
+
+	it.Close()
+	it.Seek("")
+	if it.Next() {
+		t.Errorf("expected closed iterator to be exhausted")
+	}
+}
+
+func TestStringIteratorMergeJoin(t *testing.T) {
+	left, right := trie.New[int]("/"), trie.NewPooled[int]("/")
+	for i, path := range []string{"a/a", "a/b", "b", "c/a/a", "d"} {
+		left.Put(path, i)
+	}
+	for i, path := range []string{"a/b", "b/a", "c/a/a", "c/b", "d"} {
+		right.Put(path, i)
+	}
+
+	l, r := left.Iterator(), right.Iterator()
+	defer l.Close()
+	defer r.Close()
+
+	var joined []string
+	ok := l.Next() && r.Next()
+	for ok {
+		switch {
+		case l.Path() < r.Path():
+			l.Seek(r.Path())
+			ok = l.Next()
+		case l.Path() > r.Path():
+			r.Seek(l.Path())
+			ok = r.Next()
+		default:
+			joined = append(joined, l.Path())
+			ok = l.Next() && r.Next()
+		}
+	}
+	if expected := []string{"a/b", "c/a/a", "d"}; !reflect.DeepEqual(joined, expected) {
+		t.Errorf("expected %v but got %v", expected, joined)
+	}
+}
+
+func TestStringTimestamps(t *testing.T) {
+	if _, _, ok := trie.New[int]("/").Meta(""); ok {
+		t.Errorf("expected no timestamps without WithTimestamps")
+	}
+
+	tr := trie.New[int]("/", trie.WithTimestamps())
+	before := time.Now()
+	tr.Put("a/b", 1)
+	created, updated, ok := tr.Meta("a/b")
+	if !ok || created != updated || created.Before(before) || created.After(time.Now()) {
+		t.Fatalf("unexpected timestamps (%v, %v, %t)", created, updated, ok)
+	}
+	if _, _, ok := tr.Meta("a"); ok {
+		t.Errorf("expected no timestamps for a node without a value")
+	}
+
+	time.Sleep(time.Millisecond)
+	tr.Put("a/b", 2)
+	c, u, _ := tr.Meta("a/b")
+	if c != created || !u.After(updated) {
+		t.Errorf("expected (%v, after %v) but got (%v, %v)", created, updated, c, u)
+	}
+
+	_ = tr.Rekey(func(path string) (string, bool) {
+		return "x/" + path, true
+	})
+	if c2, u2, ok := tr.Meta("x/a/b"); !ok || c2 != c || u2 != u {
+		t.Errorf("expected the timestamps to be kept by Rekey but got (%v, %v, %t)", c2, u2, ok)
+	}
+	if c2, _, ok := trie.Namespace(tr, "x/a").Meta("b"); !ok || c2 != c {
+		t.Errorf("namespace: unexpected timestamps (%v, %t)", c2, ok)
+	}
+
+	tr.Delete("x/a")
+	if _, _, ok := tr.Meta("x/a/b"); ok {
+		t.Errorf("expected no timestamps after Delete")
+	}
+
+	// Values that have not been updated since cutoff are purged.
+	tr.Put("stale", 0)
+	tr.Put("", 0)
+	time.Sleep(time.Millisecond)
+	cutoff := time.Now()
+	tr.Put("fresh", 0)
+	var stale []string
+	tr.Walk(func(path string, _ int) bool {
+		if _, updated, ok := tr.Meta(path); ok && updated.Before(cutoff) {
+			stale = append(stale, path)
+		}
+		return true
+	})
+	if !reflect.DeepEqual(stale, []string{"", "stale"}) {
+		t.Errorf("unexpected stale paths %v", stale)
+	}
+	tr.Delete("")
+	if _, _, ok := tr.Meta(""); ok {
+		t.Errorf("expected no timestamps for the root after Delete")
+	}
+}
+
+func TestStringHotKeys(t *testing.T) {
+	if keys := trie.New[int]("/").HotKeys(1); keys != nil {
+		t.Errorf("expected no hot keys without WithAccessStats but got %v", keys)
+	}
+
+	now := time.Unix(1000, 0)
+	tr := trie.New[int]("/", trie.WithAccessStats(10*time.Second, func() time.Time { return now }))
+	tr.Put("api/users", 1)
+	tr.Put("api/orders", 2)
+	tr.Put("health", 3)
+
+	for i := 0; i < 3; i++ {
+		tr.Get("api/users/")
+	}
+	tr.Lookup("api/orders")
+	tr.GetSegments("api", "orders")
+	tr.GetBatch([]string{"health", "api/users", "missing"}, make([]int, 3))
+	tr.Get("missing")
+
+	expected := []trie.HotKey{{"api/users", 4}, {"api/orders", 2}, {"health", 1}}
+	if got := tr.HotKeys(10); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+	if got := tr.HotKeys(1); !reflect.DeepEqual(got, expected[:1]) {
+		t.Errorf("expected %v but got %v", expected[:1], got)
+	}
+	if got := trie.Namespace(tr, "api").HotKeys(10); !reflect.DeepEqual(got, []trie.HotKey{{"users", 4}, {"orders", 2}}) {
+		t.Errorf("namespace: unexpected hot keys %v", got)
+	}
+
+	// Reads leave the window once it has moved past them.
+	now = now.Add(5 * time.Second)
+	tr.Get("health")
+	tr.Get("health")
+	expected = []trie.HotKey{{"api/users", 4}, {"health", 3}, {"api/orders", 2}}
+	if got := tr.HotKeys(10); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+	now = now.Add(6 * time.Second)
+	if got := tr.HotKeys(10); !reflect.DeepEqual(got, []trie.HotKey{{"health", 2}}) {
+		t.Errorf("expected only the recent reads but got %v", got)
+	}
+	now = now.Add(time.Hour)
+	if got := tr.HotKeys(10); len(got) != 0 {
+		t.Errorf("expected no hot keys but got %v", got)
+	}
+
+	now = time.Unix(-1000, 0)
+	tr.Get("health")
+	if got := tr.HotKeys(10); !reflect.DeepEqual(got, []trie.HotKey{{"health", 1}}) {
+		t.Errorf("expected reads before 1970 to be counted but got %v", got)
+	}
+}
+
+func TestWALClear(t *testing.T) {
+	var log bytes.Buffer
+	wal := trie.NewWAL(trie.New[int]("/"), &log)
+	wal.Put("foo/bar", 1)
+	wal.Put("", 2)
+	wal.Clear()
+	wal.Put("qux", 3)
+	if err := wal.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	replayed := trie.NewWAL(trie.New[int]("/"), nil)
+	err := replayed.Replay(&log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]int{"qux": 3}
+	if got := replayed.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestJournalClear(t *testing.T) {
+	j := trie.NewJournal(trie.New[int]("/"))
+	j.Put("foo/bar", 1)
+	j.Put("baz", 2)
+
+	replica := trie.New[int]("/")
+	var buf bytes.Buffer
+	_ = j.ExportSince(&buf, 0)
+	rev, err := trie.ApplyDelta(replica, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	j.Clear()
+	j.Put("qux", 3)
+	if j.Revision() != rev+2 {
+		t.Errorf("expected revision to be '%d' but got '%d'", rev+2, j.Revision())
+	}
+
+	buf.Reset()
+	_ = j.ExportSince(&buf, rev)
+	_, err = trie.ApplyDelta(replica, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]int{"qux": 3}
+	if got := replica.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+
+	// A delta since the clear does not clear the replica again.
+	replica.Put("other", 4)
+	buf.Reset()
+	_ = j.ExportSince(&buf, j.Revision()-1)
+	_, _ = trie.ApplyDelta(replica, &buf)
+	if _, ok := replica.Get("other"); !ok {
+		t.Errorf("expected 'other' to be kept")
+	}
+}
+
+func TestJournalCanonicalPaths(t *testing.T) {
+	j := trie.NewJournal(trie.New[int]("/", trie.WithTrimmedDelimiters(), trie.WithCaseFolding()))
+	j.Put("/Foo/Bar/", 1)
+	j.Put("foo/bar", 2)
+	j.Put("Foo/Baz", 3)
+	j.Delete("FOO/BAZ")
+
+	// The replica does not normalize the paths, it only works if the delta
+	// contains the canonical paths.
+	replica := trie.New[int]("/")
+	replica.Put("foo/baz", 4)
+	var buf bytes.Buffer
+	err := j.ExportSince(&buf, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = trie.ApplyDelta(replica, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]int{"foo/bar": 2}
+	if got := replica.ToMap(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected '%v' but got '%v'", expected, got)
+	}
+}
+
+func TestJournalDeleteThenPut(t *testing.T) {
+	j := trie.NewJournal(trie.New[int]("/"))
+	j.Put("a", 1)
+	j.Put("a/b", 2)
+
+	replica := trie.New[int]("/")
+	var buf bytes.Buffer
+	_ = j.ExportSince(&buf, 0)
+	rev, err := trie.ApplyDelta(replica, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The Put must not hide the Delete of the subtree.
+	j.Delete("a")
+	j.Put("a", 3)
+
+	for _, since := range []uint64{rev, 0} {
+		replica := replica
+		if since == 0 {
+			replica = trie.New[int]("/")
+		}
+		buf.Reset()
+		_ = j.ExportSince(&buf, since)
+		_, err = trie.ApplyDelta(replica, &buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := map[string]int{"a": 3}
+		if got := replica.ToMap(); !reflect.DeepEqual(expected, got) {
+			t.Errorf("since %d: expected '%v' but got '%v'", since, expected, got)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package trie
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// AsFS returns a read-only file system serving the contents of t. Nodes with
+// children are directories, all other nodes are files with their value as
+// content. Paths of the file system are always delimited by "/" and
+// translated to the delimiter of t.
+func AsFS(t String[[]byte]) fs.ReadDirFS {
+	return AsFSFunc(t, func(b []byte) []byte { return b })
+}
+
+// AsFSFunc is like AsFS but uses content to get the content of a file from its
+// value.
+func AsFSFunc[V any](t String[V], content func(V) []byte) fs.ReadDirFS {
+	return &trieFS[V]{t: t, content: content}
+}
+
+type trieFS[V any] struct {
+	t       String[V]
+	content func(V) []byte
+}
+
+func (f *trieFS[V]) Open(name string) (fs.File, error) {
+	info, children, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return &trieFile{info: info, Reader: bytes.NewReader(info.content)}, nil
+	}
+
+	entries, err := f.entries(name, children)
+	if err != nil {
+		return nil, err
+	}
+	return &trieDir{info: info, entries: entries}, nil
+}
+
+func (f *trieFS[V]) ReadDir(name string) ([]fs.DirEntry, error) {
+	info, children, err := f.stat("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.entries(name, children)
+}
+
+// stat returns the file info and the children of the node at name.
+func (f *trieFS[V]) stat(op, name string) (*trieFileInfo, []string, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	path := ""
+	if name != "." {
+		path = strings.ReplaceAll(name, "/", f.t.Delimiter())
+	}
+
+	children, found := f.t.Children(path)
+	if !found {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	info := &trieFileInfo{name: name[strings.LastIndex(name, "/")+1:]}
+	if len(children) > 0 || name == "." {
+		info.mode = fs.ModeDir | 0o555
+	} else {
+		value, _ := f.t.Get(path)
+		info.content = f.content(value)
+		info.mode = 0o444
+	}
+
+	return info, children, nil
+}
+
+func (f *trieFS[V]) entries(name string, children []string) ([]fs.DirEntry, error) {
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		childName := child
+		if name != "." {
+			childName = name + "/" + child
+		}
+
+		info, _, err := f.stat("readdir", childName)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries, nil
+}
+
+type trieFileInfo struct {
+	name    string
+	mode    fs.FileMode
+	content []byte
+}
+
+func (i *trieFileInfo) Name() string       { return i.name }
+func (i *trieFileInfo) Size() int64        { return int64(len(i.content)) }
+func (i *trieFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *trieFileInfo) ModTime() time.Time { return time.Time{} }
+func (i *trieFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *trieFileInfo) Sys() any           { return nil }
+
+type trieFile struct {
+	info *trieFileInfo
+	*bytes.Reader
+}
+
+func (f *trieFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *trieFile) Close() error               { return nil }
+
+type trieDir struct {
+	info    *trieFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *trieDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *trieDir) Close() error               { return nil }
+
+func (d *trieDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *trieDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.entries[d.offset:]
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	d.offset += len(entries)
+	return entries, nil
+}
@@ -0,0 +1,92 @@
+package trietest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"moehl.dev/trie"
+)
+
+// AssertContains reports an error listing every path of want that has no
+// value in tr or a value that differs from the one in want, as determined by
+// reflect.DeepEqual. Other values of tr are ignored.
+func AssertContains[V any](t testing.TB, tr trie.String[V], want map[string]V) {
+	t.Helper()
+
+	var d diff
+	for _, path := range sortedKeys(want) {
+		value, state := tr.Lookup(path)
+		switch {
+		case state != trie.Exists:
+			d.add("-", path, "missing, want %v", want[path])
+		case !reflect.DeepEqual(value, want[path]):
+			d.add("~", path, "got %v, want %v", value, want[path])
+		}
+	}
+	if d.len() > 0 {
+		t.Errorf("trie does not contain %d of %d values:\n%s", d.len(), len(want), d)
+	}
+}
+
+// AssertEqual reports an error listing every path that has a value in only
+// one of the tries or different values in both, as determined by
+// reflect.DeepEqual. Nodes without a value are ignored.
+func AssertEqual[V any](t testing.TB, a, b trie.String[V]) {
+	t.Helper()
+
+	am, bm := a.ToMap(), b.ToMap()
+	paths := sortedKeys(am)
+	for path := range bm {
+		if _, ok := am[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var d diff
+	for _, path := range paths {
+		av, inA := am[path]
+		bv, inB := bm[path]
+		switch {
+		case !inB:
+			d.add("-", path, "%v only in a", av)
+		case !inA:
+			d.add("+", path, "%v only in b", bv)
+		case !reflect.DeepEqual(av, bv):
+			d.add("~", path, "%v in a, %v in b", av, bv)
+		}
+	}
+	if d.len() > 0 {
+		t.Errorf("tries differ in %d paths:\n%s", d.len(), d)
+	}
+}
+
+// diff collects the lines of a readable difference, each line starts with a
+// marker followed by the quoted path.
+type diff struct {
+	lines []string
+}
+
+func (d *diff) add(marker, path, format string, args ...any) {
+	d.lines = append(d.lines, fmt.Sprintf("  %s %q: %s", marker, path, fmt.Sprintf(format, args...)))
+}
+
+func (d *diff) len() int {
+	return len(d.lines)
+}
+
+func (d diff) String() string {
+	return strings.Join(d.lines, "\n")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,134 @@
+package trietest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Shape is the kind of keys returned by Generate.
+type Shape int
+
+const (
+	// Filesystem keys look like file paths: a few levels of directories
+	// with common names followed by a file name with an extension.
+	Filesystem Shape = iota
+	// URL keys consist of a host name followed by the path of a REST API
+	// containing numeric IDs, e.g. api3.example.com/v1/users/42/orders.
+	URL
+	// UUID keys are random UUIDs in their canonical textual form, all of
+	// them are children of the root.
+	UUID
+	// Zipf keys are filesystem keys drawn with a Zipf distribution from a
+	// tenth as many distinct keys, so a few keys make up most of the
+	// result like the lookups of a hot working set. Unlike the other
+	// shapes, the keys are not unique.
+	Zipf
+)
+
+func (s Shape) String() string {
+	switch s {
+	case Filesystem:
+		return "Filesystem"
+	case URL:
+		return "URL"
+	case UUID:
+		return "UUID"
+	case Zipf:
+		return "Zipf"
+	default:
+		return fmt.Sprintf("Shape(%d)", s)
+	}
+}
+
+// Generate returns n synthetic keys of the given shape delimited by
+// Delimiter, e.g. to benchmark implementations with realistic data. The keys
+// are generated from a fixed seed, so the result is the same for every call
+// with the same arguments.
+func Generate(n int, shape Shape) []string {
+	return GenerateRand(rand.New(rand.NewSource(int64(shape)+1)), n, shape)
+}
+
+// GenerateRand is like Generate but uses r as the source of randomness.
+func GenerateRand(r *rand.Rand, n int, shape Shape) []string {
+	switch shape {
+	case Filesystem:
+		return unique(n, func() string { return filesystemKey(r) })
+	case URL:
+		return unique(n, func() string { return urlKey(r) })
+	case UUID:
+		return unique(n, func() string { return uuidKey(r) })
+	case Zipf:
+		universe := unique(n/10+1, func() string { return filesystemKey(r) })
+		z := rand.NewZipf(r, 1.1, 1, uint64(len(universe)-1))
+		keys := make([]string, n)
+		for i := range keys {
+			keys[i] = universe[z.Uint64()]
+		}
+		return keys
+	default:
+		panic(fmt.Sprintf("trietest: unknown shape %v", shape))
+	}
+}
+
+// unique returns n distinct keys returned by next.
+func unique(n int, next func() string) []string {
+	keys := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for len(keys) < n {
+		key := next()
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+var (
+	directories = []string{
+		"home", "usr", "var", "etc", "opt", "src", "lib", "bin", "share",
+		"log", "cache", "tmp", "docs", "internal", "pkg", "cmd", "test",
+		"assets", "images", "config",
+	}
+	extensions = []string{".go", ".txt", ".json", ".md", ".png", ".log", ".yaml", ".c", ".h"}
+	resources  = []string{"users", "orders", "items", "accounts", "invoices", "products", "sessions"}
+)
+
+func filesystemKey(r *rand.Rand) string {
+	depth := 1 + r.Intn(6)
+	segments := make([]string, 0, depth+1)
+	for i := 0; i < depth; i++ {
+		// Lower indices are picked more often, so directories near the
+		// root are shared by many keys.
+		dir := directories[r.Intn(1+r.Intn(len(directories)))]
+		if r.Intn(4) == 0 {
+			dir += fmt.Sprint(r.Intn(100))
+		}
+		segments = append(segments, dir)
+	}
+	name := fmt.Sprintf("file%d%s", r.Intn(10000), extensions[r.Intn(len(extensions))])
+	return strings.Join(append(segments, name), Delimiter)
+}
+
+func urlKey(r *rand.Rand) string {
+	segments := []string{
+		fmt.Sprintf("api%d.example.com", r.Intn(8)),
+		fmt.Sprintf("v%d", 1+r.Intn(3)),
+	}
+	for i := 1 + r.Intn(3); i > 0; i-- {
+		segments = append(segments, resources[r.Intn(len(resources))], fmt.Sprint(r.Intn(100000)))
+	}
+	if r.Intn(2) == 0 {
+		segments = append(segments, resources[r.Intn(len(resources))])
+	}
+	return strings.Join(segments, Delimiter)
+}
+
+func uuidKey(r *rand.Rand) string {
+	var b [16]byte
+	_, _ = r.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,112 @@
+package trietest
+
+import (
+	"slices"
+	"strings"
+)
+
+// Model is the reference implementation of a trie used by Run and Check. It
+// is an ordered map keyed by the full paths of all nodes, including those that
+// only exist as part of a longer path, which are kept sorted segment by
+// segment like the paths passed to the callback of Walk. The paths must not
+// contain empty segments. The zero value is an empty model.
+type Model struct {
+	// paths contains the paths of all nodes except the root in sorted
+	// order, the children of a node directly follow it.
+	paths  []string
+	values map[string]int
+}
+
+// NewModel returns an empty model.
+func NewModel() *Model {
+	return &Model{}
+}
+
+// search returns the index of path in paths and whether it is present.
+func (m *Model) search(path string) (int, bool) {
+	return slices.BinarySearchFunc(m.paths, path, comparePaths)
+}
+
+// Put sets the value at path, creating the nodes of all of its prefixes.
+func (m *Model) Put(path string, value int) {
+	if m.values == nil {
+		m.values = make(map[string]int)
+	}
+	m.values[path] = value
+	for p := path; p != ""; p = parent(p) {
+		i, ok := m.search(p)
+		if ok {
+			break
+		}
+		m.paths = slices.Insert(m.paths, i, p)
+	}
+}
+
+// Get returns the value of the node at path and whether it exists, the root
+// only exists if it has a value. See trie.String.Get.
+func (m *Model) Get(path string) (value int, found bool) {
+	value, found = m.values[path]
+	if !found && path != "" {
+		_, found = m.search(path)
+	}
+	return value, found
+}
+
+// Delete removes the node at path and all of its children, for the root only
+// the value is removed. See trie.String.Delete.
+func (m *Model) Delete(path string) {
+	if path == "" {
+		delete(m.values, path)
+		return
+	}
+	i, ok := m.search(path)
+	if !ok {
+		return
+	}
+	j := i + 1
+	for j < len(m.paths) && strings.HasPrefix(m.paths[j], path+Delimiter) {
+		j++
+	}
+	for _, p := range m.paths[i:j] {
+		delete(m.values, p)
+	}
+	m.paths = slices.Delete(m.paths, i, j)
+}
+
+// Walk calls fn for every path that has a value in sorted order until fn
+// returns false.
+func (m *Model) Walk(fn func(path string, value int) bool) {
+	if value, ok := m.values[""]; ok && !fn("", value) {
+		return
+	}
+	for _, path := range m.paths {
+		if value, ok := m.values[path]; ok && !fn(path, value) {
+			return
+		}
+	}
+}
+
+// Len returns the number of values.
+func (m *Model) Len() int {
+	return len(m.values)
+}
+
+// comparePaths orders paths segment by segment like Walk.
+func comparePaths(a, b string) int {
+	return slices.Compare(split(a), split(b))
+}
+
+func split(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, Delimiter)
+}
+
+func parent(path string) string {
+	i := strings.LastIndex(path, Delimiter)
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
@@ -0,0 +1,115 @@
+package trietest
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Scheduler is a trie.Scheduler that runs the goroutines started by Go one
+// at a time. Whenever the running goroutine yields, Run picks the next one
+// using a random generator seeded with the seed passed to NewScheduler, so
+// the same seed always results in the same interleaving of the operations,
+// as long as the goroutines only synchronize through the trie:
+//
+//	for seed := int64(0); seed < 1000; seed++ {
+//		s := trietest.NewScheduler(seed)
+//		t := trie.New[int]("/", trie.WithScheduler(s))
+//		s.Go(func() { t.Put("a/b", 1) })
+//		s.Go(func() { t.Delete("a") })
+//		s.Run()
+//		// check the state of t
+//	}
+//
+// While Run is running, only the goroutines started by Go may access the
+// trie. Calls to Yield at any other time return immediately.
+type Scheduler struct {
+	rand  *rand.Rand
+	tasks []*task
+	// events receives a task whenever it yields or finishes.
+	events chan *task
+
+	// lock protects current, which is the task that is allowed to run.
+	lock    sync.Mutex
+	current *task
+	steps   int
+}
+
+type task struct {
+	fn     func()
+	resume chan struct{}
+	done   bool
+}
+
+// NewScheduler returns a scheduler whose interleavings are determined by
+// seed.
+func NewScheduler(seed int64) *Scheduler {
+	return &Scheduler{
+		rand:   rand.New(rand.NewSource(seed)),
+		events: make(chan *task),
+	}
+}
+
+// Go adds fn to the goroutines started by the next call to Run.
+func (s *Scheduler) Go(fn func()) {
+	s.tasks = append(s.tasks, &task{fn: fn, resume: make(chan struct{})})
+}
+
+// Run starts all goroutines added by Go and returns once all of them have
+// finished. Only one of them is running at any time.
+func (s *Scheduler) Run() {
+	tasks := s.tasks
+	s.tasks = nil
+	for _, t := range tasks {
+		go func(t *task) {
+			<-t.resume
+			t.fn()
+			t.done = true
+			s.events <- t
+		}(t)
+	}
+
+	for len(tasks) > 0 {
+		i := s.rand.Intn(len(tasks))
+		t := tasks[i]
+
+		s.lock.Lock()
+		s.current = t
+		s.steps++
+		s.lock.Unlock()
+
+		t.resume <- struct{}{}
+		<-s.events
+
+		if t.done {
+			tasks = append(tasks[:i], tasks[i+1:]...)
+		}
+	}
+
+	s.lock.Lock()
+	s.current = nil
+	s.lock.Unlock()
+}
+
+// Yield pauses the calling goroutine until Run resumes it, see
+// trie.Scheduler.
+func (s *Scheduler) Yield() {
+	s.lock.Lock()
+	t := s.current
+	s.lock.Unlock()
+
+	// Only the current task is running, so if there is one, it is the
+	// caller.
+	if t == nil {
+		return
+	}
+	s.events <- t
+	<-t.resume
+}
+
+// Steps returns the number of times a goroutine has been resumed by Run,
+// which is one more than the number of calls to Yield for each goroutine.
+func (s *Scheduler) Steps() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.steps
+}
@@ -0,0 +1,257 @@
+// Package trietest provides helpers to test implementations of trie.String
+// against a simple reference model, see Model. Check compares an
+// implementation with the model for a sequence of operations, e.g. random
+// ones returned by RandomOps:
+//
+//	func TestTrie(t *testing.T) {
+//		r := rand.New(rand.NewSource(1))
+//		for i := 0; i < 100; i++ {
+//			trietest.Check(t, mytrie.New[int]("/"), trietest.RandomOps(r, 1000))
+//		}
+//	}
+//
+// Sequences of operations can be decoded
+// from arbitrary bytes, which makes Run usable as the body of a fuzz test:
+//
+//	func FuzzTrie(f *testing.F) {
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			err := trietest.Run(trie.New[int]("/"), trietest.Decode(data))
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//		})
+//	}
+package trietest
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Delimiter of the paths of all operations, the trie under test must use it.
+const Delimiter = "/"
+
+// Kind is the type of an operation.
+type Kind uint8
+
+const (
+	Put Kind = iota
+	Get
+	Delete
+	Walk
+
+	numKinds
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Put:
+		return "Put"
+	case Get:
+		return "Get"
+	case Delete:
+		return "Delete"
+	case Walk:
+		return "Walk"
+	default:
+		return fmt.Sprintf("Kind(%d)", k)
+	}
+}
+
+// Op is a single operation on a trie. Path is ignored by Walk and Value is
+// only used by Put.
+type Op struct {
+	Kind  Kind
+	Path  string
+	Value int
+}
+
+func (op Op) String() string {
+	switch op.Kind {
+	case Put:
+		return fmt.Sprintf("Put(%q, %d)", op.Path, op.Value)
+	case Walk:
+		return "Walk()"
+	default:
+		return fmt.Sprintf("%s(%q)", op.Kind, op.Path)
+	}
+}
+
+// alphabet contains the segments of the paths created by Decode. It is kept
+// small so that the paths of random operations share prefixes, and contains
+// a segment that is a prefix of another one.
+var alphabet = [4]string{"a", "b", "c", "ab"}
+
+// Decode turns arbitrary bytes into a sequence of operations. Each operation
+// is encoded in two bytes, or three for Put: the kind, the path and the value.
+// The lowest two bits of the path byte are the number of segments, each
+// following pair of bits selects one segment. Incomplete operations at the end
+// of data are dropped.
+func Decode(data []byte) []Op {
+	var ops []Op
+	for len(data) >= 2 {
+		op := Op{Kind: Kind(data[0] % byte(numKinds)), Path: decodePath(data[1])}
+		data = data[2:]
+		if op.Kind == Put {
+			if len(data) == 0 {
+				break
+			}
+			op.Value = int(data[0])
+			data = data[1:]
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+func decodePath(b byte) string {
+	segments := make([]string, b&3)
+	for i := range segments {
+		b >>= 2
+		segments[i] = alphabet[b&3]
+	}
+	return strings.Join(segments, Delimiter)
+}
+
+// Encode is the inverse of Decode for operations whose paths consist of at
+// most three segments of the alphabet used by Decode and whose values fit in a
+// byte. It can be used to add sequences to the seed corpus of a fuzz test.
+func Encode(ops []Op) ([]byte, error) {
+	var data []byte
+	for _, op := range ops {
+		if op.Kind >= numKinds {
+			return nil, fmt.Errorf("trietest: %v: unknown kind", op)
+		}
+		b, err := encodePath(op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("trietest: %v: %w", op, err)
+		}
+		data = append(data, byte(op.Kind), b)
+		if op.Kind == Put {
+			if op.Value < 0 || op.Value > 255 {
+				return nil, fmt.Errorf("trietest: %v: value does not fit in a byte", op)
+			}
+			data = append(data, byte(op.Value))
+		}
+	}
+	return data, nil
+}
+
+func encodePath(path string) (byte, error) {
+	if path == "" {
+		return 0, nil
+	}
+	segments := strings.Split(path, Delimiter)
+	if len(segments) > 3 {
+		return 0, fmt.Errorf("more than 3 segments")
+	}
+	b := byte(len(segments))
+	for i, segment := range segments {
+		j := 0
+		for j < len(alphabet) && alphabet[j] != segment {
+			j++
+		}
+		if j == len(alphabet) {
+			return 0, fmt.Errorf("segment %q is not in the alphabet", segment)
+		}
+		b |= byte(j) << (2 * (i + 1))
+	}
+	return b, nil
+}
+
+// Trie contains the methods used by Run, it is implemented by trie.String[int]
+// and by wrappers of it like trie.WAL.
+type Trie interface {
+	Put(path string, value int)
+	Get(path string) (value int, found bool)
+	Delete(path string)
+	Walk(fn func(path string, value int) bool)
+}
+
+// Run applies ops to t, which must be empty and use Delimiter, and to a
+// Model. It returns an error describing the first result of t
+// that differs from the model, or, if t has a Validate method like
+// trie.String, the error of Validate after the last operation. The paths of
+// ops must not contain empty segments.
+func Run(t Trie, ops []Op) error {
+	m := NewModel()
+	for i, op := range ops {
+		var err error
+		switch op.Kind {
+		case Put:
+			t.Put(op.Path, op.Value)
+			m.Put(op.Path, op.Value)
+		case Get:
+			value, found := t.Get(op.Path)
+			expValue, expFound := m.Get(op.Path)
+			if value != expValue || found != expFound {
+				err = fmt.Errorf("got (%d, %t), expected (%d, %t)", value, found, expValue, expFound)
+			}
+		case Delete:
+			t.Delete(op.Path)
+			m.Delete(op.Path)
+		case Walk:
+			got, exp := entries(t.Walk), entries(m.Walk)
+			if !slices.Equal(got, exp) {
+				err = fmt.Errorf("got %v, expected %v", got, exp)
+			}
+		default:
+			err = fmt.Errorf("unknown kind")
+		}
+		if err != nil {
+			return fmt.Errorf("trietest: op %d: %v: %w", i, op, err)
+		}
+	}
+	if v, ok := t.(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// entries returns the values reported by walk formatted as path=value.
+func entries(walk func(func(path string, value int) bool)) []string {
+	var entries []string
+	walk(func(path string, value int) bool {
+		entries = append(entries, fmt.Sprintf("%s=%d", path, value))
+		return true
+	})
+	return entries
+}
+
+// Check fails the test if the results of t differ from the ones of the Model
+// for ops, see Run.
+func Check(t testing.TB, tr Trie, ops []Op) {
+	t.Helper()
+	if err := Run(tr, ops); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// RandomOps returns n random operations, two out of five are Put and Walk is
+// rare. The paths have up to three segments of the alphabet used by Decode so
+// that they share many prefixes.
+func RandomOps(r *rand.Rand, n int) []Op {
+	ops := make([]Op, n)
+	for i := range ops {
+		var op Op
+		switch x := r.Intn(20); {
+		case x < 8:
+			op.Kind = Put
+			op.Value = r.Intn(256)
+		case x < 14:
+			op.Kind = Get
+		case x < 19:
+			op.Kind = Delete
+		default:
+			op.Kind = Walk
+		}
+		if op.Kind != Walk {
+			op.Path = decodePath(byte(r.Intn(256)))
+		}
+		ops[i] = op
+	}
+	return ops
+}
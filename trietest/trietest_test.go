@@ -0,0 +1,240 @@
+package trietest_test
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"moehl.dev/trie"
+	"moehl.dev/trie/trietest"
+)
+
+func FuzzRun(f *testing.F) {
+	seed, err := trietest.Encode([]trietest.Op{
+		{Kind: trietest.Put, Path: "a/b/c", Value: 1},
+		{Kind: trietest.Put, Path: "a", Value: 2},
+		{Kind: trietest.Get, Path: "a/b"},
+		{Kind: trietest.Delete, Path: "a/b"},
+		{Kind: trietest.Put, Path: "", Value: 3},
+		{Kind: trietest.Walk},
+		{Kind: trietest.Delete, Path: ""},
+		{Kind: trietest.Get, Path: ""},
+	})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(seed)
+	f.Add([]byte("arbitrary input"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		err := trietest.Run(trie.New[int](trietest.Delimiter), trietest.Decode(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// noDelete ignores all deletes.
+type noDelete struct {
+	strie
+}
+
+// strie allows trie.String to be embedded despite its String method.
+type strie = trie.String[int]
+
+func (noDelete) Delete(string) {}
+
+func TestRunDetectsDivergence(t *testing.T) {
+	ops := []trietest.Op{
+		{Kind: trietest.Put, Path: "a/ab", Value: 1},
+		{Kind: trietest.Delete, Path: "a"},
+		{Kind: trietest.Get, Path: "a/ab"},
+	}
+	if err := trietest.Run(trie.New[int]("/"), ops); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := trietest.Run(noDelete{trie.New[int]("/")}, ops)
+	if err == nil || !strings.Contains(err.Error(), `op 2: Get("a/ab")`) {
+		t.Errorf("expected an error for op 2 but got %v", err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	ops := []trietest.Op{
+		{Kind: trietest.Put, Path: "ab/c/a", Value: 255},
+		{Kind: trietest.Walk},
+		{Kind: trietest.Get, Path: "b"},
+	}
+	data, err := trietest.Encode(ops)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := trietest.Decode(data)
+	if len(got) != len(ops) {
+		t.Fatalf("expected %v but got %v", ops, got)
+	}
+	for i := range ops {
+		if got[i] != ops[i] {
+			t.Errorf("expected %v but got %v", ops[i], got[i])
+		}
+	}
+
+	if _, err := trietest.Encode([]trietest.Op{{Kind: trietest.Get, Path: "d"}}); err == nil {
+		t.Errorf("expected an error for a segment outside of the alphabet")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		trietest.Check(t, trie.New[int](trietest.Delimiter), trietest.RandomOps(r, 200))
+		trietest.Check(t, trie.New[int](trietest.Delimiter, trie.WithPooling(), trie.WithCache(8)), trietest.RandomOps(r, 200))
+		// Wrappers are checked without Validate.
+		trietest.Check(t, trie.NewWAL(trie.New[int](trietest.Delimiter), io.Discard), trietest.RandomOps(r, 200))
+	}
+}
+
+func TestModel(t *testing.T) {
+	m := trietest.NewModel()
+	m.Put("a/ab", 1)
+	m.Put("a/b", 2)
+	m.Put("ab", 3)
+	m.Put("", 4)
+	m.Put("a/ab/c", 5)
+
+	var got []string
+	m.Walk(func(path string, value int) bool {
+		got = append(got, fmt.Sprintf("%s=%d", path, value))
+		return true
+	})
+	if exp := []string{"=4", "a/ab=1", "a/ab/c=5", "a/b=2", "ab=3"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v but got %v", exp, got)
+	}
+
+	if value, found := m.Get("a"); value != 0 || !found {
+		t.Errorf("expected the implicit node a to exist")
+	}
+	m.Delete("a/ab")
+	if _, found := m.Get("a/ab/c"); found || m.Len() != 3 {
+		t.Errorf("expected a/ab/c to be deleted, %d values left", m.Len())
+	}
+	m.Delete("")
+	if _, found := m.Get(""); found {
+		t.Errorf("expected the root to be deleted")
+	}
+	if _, found := m.Get("a/b"); !found {
+		t.Errorf("expected a/b to be kept")
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	// order returns the order in which the goroutines finished for seed.
+	order := func(seed int64) string {
+		s := trietest.NewScheduler(seed)
+		tr := trie.New[int](trietest.Delimiter, trie.WithScheduler(s))
+		var b strings.Builder
+		for _, path := range []string{"a/b/c", "a/b", "a/c/a"} {
+			path := path
+			s.Go(func() {
+				tr.Put(path, 1)
+				tr.Get(path)
+				b.WriteString(path + " ")
+			})
+		}
+		s.Run()
+		return b.String()
+	}
+
+	orders := make(map[string]bool)
+	for seed := int64(0); seed < 50; seed++ {
+		o := order(seed)
+		if again := order(seed); again != o {
+			t.Fatalf("seed %d: expected %q but got %q", seed, o, again)
+		}
+		orders[o] = true
+	}
+	if len(orders) < 2 {
+		t.Errorf("expected different interleavings but got %v", orders)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	for _, shape := range []trietest.Shape{trietest.Filesystem, trietest.URL, trietest.UUID, trietest.Zipf} {
+		keys := trietest.Generate(1000, shape)
+		if len(keys) != 1000 {
+			t.Fatalf("%v: expected 1000 keys but got %d", shape, len(keys))
+		}
+		if again := trietest.Generate(1000, shape); !reflect.DeepEqual(keys, again) {
+			t.Errorf("%v: expected the same keys for every call", shape)
+		}
+
+		distinct := make(map[string]int)
+		for _, key := range keys {
+			if key == "" || strings.Contains(key, "//") {
+				t.Errorf("%v: unexpected key %q", shape, key)
+			}
+			distinct[key]++
+		}
+		if shape == trietest.Zipf {
+			if len(distinct) > 101 {
+				t.Errorf("%v: expected at most 101 distinct keys but got %d", shape, len(distinct))
+			}
+			if distinct[keys[0]] < 2 && len(distinct) > 50 {
+				t.Errorf("%v: expected a skewed distribution", shape)
+			}
+		} else if len(distinct) != len(keys) {
+			t.Errorf("%v: expected unique keys", shape)
+		}
+	}
+
+	if key := trietest.Generate(1, trietest.UUID)[0]; len(key) != 36 || key[14] != '4' {
+		t.Errorf("unexpected UUID %q", key)
+	}
+}
+
+// recorder captures the errors reported by the assertions.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssert(t *testing.T) {
+	a := trie.New[int]("/")
+	a.Put("a/b", 1)
+	a.Put("a/c", 2)
+	a.Put("d", 3)
+
+	r := &recorder{TB: t}
+	trietest.AssertContains(r, a, map[string]int{"a/b": 1, "d": 3})
+	trietest.AssertEqual(r, a, trie.FromMap(map[string]int{"a/b": 1, "a/c": 2, "d": 3}, "/"))
+	if len(r.errors) != 0 {
+		t.Errorf("unexpected errors: %v", r.errors)
+	}
+
+	trietest.AssertContains(r, a, map[string]int{"a/b": 1, "a": 0, "d": 4})
+	expected := "trie does not contain 2 of 3 values:\n" +
+		"  - \"a\": missing, want 0\n" +
+		"  ~ \"d\": got 3, want 4"
+	if len(r.errors) != 1 || r.errors[0] != expected {
+		t.Errorf("expected\n%s\nbut got\n%v", expected, r.errors)
+	}
+
+	r.errors = nil
+	trietest.AssertEqual(r, a, trie.FromMap(map[string]int{"a/b": 1, "a/c": 5, "e": 6}, "/"))
+	expected = "tries differ in 3 paths:\n" +
+		"  ~ \"a/c\": 2 in a, 5 in b\n" +
+		"  - \"d\": 3 only in a\n" +
+		"  + \"e\": 6 only in b"
+	if len(r.errors) != 1 || r.errors[0] != expected {
+		t.Errorf("expected\n%s\nbut got\n%v", expected, r.errors)
+	}
+}
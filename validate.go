@@ -0,0 +1,217 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"strings"
+)
+
+// ErrInvariant is wrapped by the errors returned by Validate.
+var ErrInvariant = errors.New("trie: invariant violated")
+
+// InvariantError is returned by Validate for the first node that violates an
+// invariant of the trie. It matches ErrInvariant when checked using errors.Is.
+type InvariantError struct {
+	// Path of the node, segments are escaped if escaping is enabled.
+	Path   string
+	Reason string
+}
+
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("trie: invariant violated at %q: %s", e.Path, e.Reason)
+}
+
+func (e *InvariantError) Unwrap() error {
+	return ErrInvariant
+}
+
+// Validate walks the whole trie and checks its internal invariants. The
+// nodes are locked one at a time, so the trie should not be modified
+// concurrently to get a meaningful result.
+func (t *stringTrie[V]) Validate() error {
+	defer t.unpin(t.pin())
+	if t.delimiter == "" {
+		return &InvariantError{Reason: "empty delimiter"}
+	}
+	if t.escape != 0 && strings.ContainsRune(t.delimiter, t.escape) {
+		return &InvariantError{Reason: fmt.Sprintf("escape character %q is part of the delimiter", t.escape)}
+	}
+
+	err := t.validateNode(t.root, nil, make(map[*node[string, V]]struct{}))
+	if err != nil {
+		return err
+	}
+	if t.counts != nil {
+		t.counts.Lock()
+		_, err = t.validateCount(t.root, nil)
+		t.counts.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	if t.cache != nil {
+		if reason := t.cache.check(); reason != "" {
+			return &InvariantError{Reason: "lookup cache: " + reason}
+		}
+	}
+	return nil
+}
+
+// validateNode checks the children of n, which is located at path, and
+// descends into them.
+func (t *stringTrie[V]) validateNode(n *node[string, V], path []string, seen map[*node[string, V]]struct{}) error {
+	type child struct {
+		key      string
+		n        *node[string, V]
+		segments []string
+	}
+
+	n.lock.RLock()
+	reason := n.children.check()
+	children := make([]child, 0, n.children.len())
+	n.children.each(func(key string, c *node[string, V]) {
+		var segments []string
+		if c != nil {
+			segments = c.segments
+		}
+		children = append(children, child{key, c, segments})
+	})
+	n.lock.RUnlock()
+
+	if reason != "" {
+		return t.invariantError(path, reason)
+	}
+
+	for _, c := range children {
+		childPath := append(path[:len(path):len(path)], c.segments...)
+		switch {
+		case c.n == nil:
+			return t.invariantError(append(path, c.key), "nil child")
+		case len(c.segments) == 0:
+			return t.invariantError(append(path, c.key), "child without segments")
+		case c.segments[0] != c.key:
+			return t.invariantError(childPath, fmt.Sprintf("child stored under key %q", c.key))
+		}
+		if _, ok := seen[c.n]; ok {
+			return t.invariantError(childPath, "node is reachable more than once")
+		}
+		seen[c.n] = struct{}{}
+
+		if err := t.checkDepth(len(childPath)); err != nil {
+			return t.invariantError(childPath, err.Error())
+		}
+		for i, key := range c.segments {
+			if err := t.checkSegment(key); err != nil {
+				return t.invariantError(childPath, err.Error())
+			}
+			if t.transform != nil && t.transform(key) != key {
+				return t.invariantError(childPath, fmt.Sprintf("segment %q is not normalized", key))
+			}
+			if !t.bloomContains(childPath[:len(path)+i+1]) {
+				return t.invariantError(childPath, "node is missing from the Bloom filter")
+			}
+		}
+
+		if err := t.validateNode(c.n, childPath, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCount checks that the count of n, which is located at path, and of
+// all of its descendants matches their number of values and returns it. The
+// counts lock must be held by the caller.
+func (t *stringTrie[V]) validateCount(n *node[string, V], path []string) (int, error) {
+	n.lock.RLock()
+	count := 0
+	if n.hasValue {
+		count++
+	}
+	var (
+		children []*node[string, V]
+		paths    [][]string
+	)
+	n.children.each(func(_ string, c *node[string, V]) {
+		children = append(children, c)
+		paths = append(paths, append(path[:len(path):len(path)], c.segments...))
+	})
+	n.lock.RUnlock()
+
+	for i, c := range children {
+		childCount, err := t.validateCount(c, paths[i])
+		if err != nil {
+			return 0, err
+		}
+		count += childCount
+	}
+	if n.count != count {
+		return 0, t.invariantError(path, fmt.Sprintf("subtree count is %d but contains %d values", n.count, count))
+	}
+	return count, nil
+}
+
+func (t *stringTrie[V]) invariantError(segments []string, reason string) error {
+	return &InvariantError{Path: t.joinSegments(segments), Reason: reason}
+}
+
+// bloomContains returns whether the Bloom filter, if any, may contain the node
+// at segments.
+func (t *stringTrie[V]) bloomContains(segments []string) bool {
+	if t.bloom == nil {
+		return true
+	}
+
+	var h maphash.Hash
+	h.SetSeed(t.bloom.seed)
+	for i, key := range segments {
+		if i > 0 {
+			h.WriteString(t.delimiter)
+		}
+		h.WriteString(key)
+	}
+	return t.bloom.mayContain(h.Sum64())
+}
+
+// check returns a description of the first inconsistency of the storage of
+// the children or the empty string. The caller must hold the lock of the node.
+func (c *nodeChildren[K, V]) check() string {
+	switch {
+	case c.m != nil && (c.keys != nil || c.nodes != nil):
+		return "children stored in both the map and the slices"
+	case len(c.keys) != len(c.nodes):
+		return fmt.Sprintf("%d keys but %d children", len(c.keys), len(c.nodes))
+	case len(c.keys) > maxSmallFanout:
+		return fmt.Sprintf("%d children stored in the slices", len(c.keys))
+	}
+	for i, k := range c.keys {
+		for _, other := range c.keys[:i] {
+			if k == other {
+				return fmt.Sprintf("duplicate key %v", k)
+			}
+		}
+	}
+	return ""
+}
+
+// check returns a description of the first inconsistency of the cache or the
+// empty string.
+func (c *lookupCache[V]) check() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.entries) != c.lru.Len() {
+		return fmt.Sprintf("%d entries but %d elements in the LRU list", len(c.entries), c.lru.Len())
+	}
+	if c.lru.Len() > c.size {
+		return fmt.Sprintf("%d entries exceed the size of %d", c.lru.Len(), c.size)
+	}
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		path := e.Value.(*cacheEntry[V]).path
+		if c.entries[path] != e {
+			return fmt.Sprintf("entry %q is not indexed", path)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package trie
+
+import (
+	"encoding"
+	"encoding/json"
+)
+
+// ValueCodec encodes and decodes values for the serialization formats of this
+// package: the binary format, snapshots, the write-ahead log, deltas, the
+// mapped format and segments. The codec of a trie can be set using
+// NewWithCodec.
+type ValueCodec[V any] interface {
+	Encode(value V) ([]byte, error)
+	Decode(b []byte) (V, error)
+}
+
+// DefaultCodec is the ValueCodec used if none has been specified. It uses
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler if implemented by V
+// and falls back to JSON otherwise.
+type DefaultCodec[V any] struct{}
+
+func (DefaultCodec[V]) Encode(v V) ([]byte, error) {
+	if m, ok := any(v).(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return json.Marshal(v)
+}
+
+func (DefaultCodec[V]) Decode(b []byte) (v V, err error) {
+	if u, ok := any(&v).(encoding.BinaryUnmarshaler); ok {
+		err = u.UnmarshalBinary(b)
+		return v, err
+	}
+	err = json.Unmarshal(b, &v)
+	return v, err
+}
+
+// codecOrDefault returns c or DefaultCodec if c is nil.
+func codecOrDefault[V any](c ValueCodec[V]) ValueCodec[V] {
+	if c == nil {
+		return DefaultCodec[V]{}
+	}
+	return c
+}
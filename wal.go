@@ -0,0 +1,270 @@
+package trie
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Operations stored in the write-ahead log. Every record starts with one of
+// these bytes followed by its arguments:
+//
+//	put:   'P' | string path | string value
+//	del:   'D' | string path
+//	clear: 'C'
+//	merge: 'M' | trie in the binary format
+const (
+	walPut    byte = 'P'
+	walDelete byte = 'D'
+	walClear  byte = 'C'
+	walMerge  byte = 'M'
+)
+
+// WAL wraps a String trie and appends a record to a log for every Put, Delete,
+// Clear and ReadFrom before it is applied to the trie, so that the state of the trie
+// can be rebuilt using Replay after a crash. Checkpoint compacts the log by
+// writing a snapshot of the trie to a new log.
+//
+// Operations that cannot be written to the log are not applied to the trie,
+// the first error that occurred is returned by Err.
+type WAL[V any] struct {
+	String[V]
+
+	lock *sync.Mutex
+	w    io.Writer
+	err  error
+}
+
+// NewWAL wraps t and logs every mutation to w. If w is nil, mutations are not
+// logged until Checkpoint is called.
+func NewWAL[V any](t String[V], w io.Writer) *WAL[V] {
+	return &WAL[V]{
+		String: t,
+		lock:   new(sync.Mutex),
+		w:      w,
+	}
+}
+
+// Err returns the first error that occurred while writing to the log.
+func (l *WAL[V]) Err() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.err
+}
+
+func (l *WAL[V]) Put(path string, value V) {
+	if l.CheckPath(path) != nil {
+		return
+	}
+
+	v, err := l.Codec().Encode(value)
+	if err != nil {
+		l.fail(fmt.Errorf("trie: encode value: %w", err))
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.log(walPut, []byte(path), v) {
+		l.String.Put(path, value)
+	}
+}
+
+// Rekey is applied as a sequence of logged Delete and Put operations, unlike
+// the Rekey of the String trie it is not atomic.
+func (l *WAL[V]) Rekey(fn func(oldPath string) (newPath string, keep bool)) error {
+	return rekeyEach[V](l, fn)
+}
+
+// PutSegments is logged as a Put of the joined path.
+func (l *WAL[V]) PutSegments(value V, segments ...string) {
+	l.Put(joinPath(l.String, segments), value)
+}
+
+// PutE is like Put but returns the error of the key validator if the path is
+// rejected.
+func (l *WAL[V]) PutE(path string, value V) error {
+	err := l.CheckPath(path)
+	if err != nil {
+		return err
+	}
+	l.Put(path, value)
+	return nil
+}
+
+func (l *WAL[V]) Delete(path string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.log(walDelete, []byte(path)) {
+		l.String.Delete(path)
+	}
+}
+
+// DeleteE is like Delete but returns an error wrapping ErrNotFound if the
+// node does not exist.
+func (l *WAL[V]) DeleteE(path string) error {
+	_, err := l.GetE(path)
+	if err != nil {
+		return err
+	}
+	l.Delete(path)
+	return nil
+}
+
+// Root returns a handle to the root of the trie, its Set is logged just
+// like Put.
+func (l *WAL[V]) Root() Node[V] {
+	return rootWithPut(l.String, l.Put)
+}
+
+func (l *WAL[V]) Clear() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.log(walClear) {
+		l.String.Clear()
+	}
+}
+
+// ReadFrom reads the whole input into memory before it is logged and merged
+// into the trie.
+func (l *WAL[V]) ReadFrom(r io.Reader) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(data)), err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.w != nil {
+		_, err = l.w.Write(append([]byte{walMerge}, data...))
+		if err != nil {
+			l.setErr(err)
+			return int64(len(data)), err
+		}
+	}
+
+	return l.String.ReadFrom(bytes.NewReader(data))
+}
+
+// Replay applies all records read from r to the trie without logging them. If
+// the last record is incomplete, e.g. because the process crashed while it was
+// written, all previous records are applied and io.ErrUnexpectedEOF is
+// returned.
+func (l *WAL[V]) Replay(r io.Reader) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	br := &binaryReader{r: bufio.NewReader(r)}
+	for {
+		op, err := br.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case walPut:
+			path, err := br.readBytes()
+			if err != nil {
+				return err
+			}
+			b, err := br.readBytes()
+			if err != nil {
+				return err
+			}
+			value, err := l.Codec().Decode(b)
+			if err != nil {
+				return fmt.Errorf("trie: decode value: %w", err)
+			}
+			l.String.Put(string(path), value)
+		case walDelete:
+			path, err := br.readBytes()
+			if err != nil {
+				return err
+			}
+			l.String.Delete(string(path))
+		case walClear:
+			l.String.Clear()
+		case walMerge:
+			// The binary decoder reuses br.r because it is a *bufio.Reader
+			// of the default size, so it does not consume any bytes of
+			// the following records.
+			_, err := l.String.ReadFrom(br.r)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: unknown log record %x", ErrInvalidFormat, op)
+		}
+	}
+}
+
+// Checkpoint writes a snapshot of the trie to w and continues logging to w.
+// Once Checkpoint returns successfully, the previous log is no longer required
+// and can be removed.
+func (l *WAL[V]) Checkpoint(w io.Writer) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var buf bytes.Buffer
+	buf.WriteByte(walMerge)
+	_, err := l.String.WriteTo(&buf)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	l.w = w
+	l.err = nil
+
+	return nil
+}
+
+// log writes a single record to the log and reports whether it was
+// successful. The lock must be held by the caller.
+func (l *WAL[V]) log(op byte, args ...[]byte) bool {
+	if l.w == nil {
+		return true
+	}
+
+	var buf bytes.Buffer
+	bw := &binaryWriter{w: bufio.NewWriter(&buf)}
+	_, _ = bw.Write([]byte{op})
+	for _, arg := range args {
+		_ = bw.writeBytes(arg)
+	}
+	_ = bw.w.Flush()
+
+	_, err := l.w.Write(buf.Bytes())
+	if err != nil {
+		l.setErr(err)
+		return false
+	}
+
+	return true
+}
+
+func (l *WAL[V]) fail(err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.setErr(err)
+}
+
+func (l *WAL[V]) setErr(err error) {
+	if l.err == nil {
+		l.err = err
+	}
+}
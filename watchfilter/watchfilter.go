@@ -0,0 +1,81 @@
+// Package watchfilter decides which events of a file system watcher are of
+// interest. Directories are included or excluded by their path, which also
+// applies to everything below them, and the most specific pattern decides.
+// The patterns are stored in a String trie, so checking an event takes time
+// proportional to the length of its path regardless of the number of
+// patterns.
+package watchfilter
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"moehl.dev/trie"
+)
+
+// Filter contains include and exclude patterns. A Filter is safe for
+// concurrent use.
+type Filter struct {
+	// lock serializes changes to keep includes, the number of included
+	// paths, in sync with patterns.
+	lock sync.Mutex
+	// patterns contains true for included and false for excluded paths.
+	patterns trie.String[bool]
+	includes atomic.Int64
+}
+
+// New creates a Filter without any patterns, which handles all events.
+func New() *Filter {
+	return &Filter{patterns: trie.New[bool]("/", trie.WithTrimmedDelimiters())}
+}
+
+// Include events for path and everything below it, unless a more specific
+// path is excluded.
+func (f *Filter) Include(path string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if old, state := f.patterns.Lookup(clean(path)); state != trie.Exists || !old {
+		f.includes.Add(1)
+	}
+	f.patterns.Put(clean(path), true)
+}
+
+// Exclude events for path and everything below it, unless a more specific
+// path is included.
+func (f *Filter) Exclude(path string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if old, state := f.patterns.Lookup(clean(path)); state == trie.Exists && old {
+		f.includes.Add(-1)
+	}
+	f.patterns.Put(clean(path), false)
+}
+
+// ShouldHandle reports whether an event for the path should be handled. The
+// most specific pattern that is a prefix of the path decides, in segments. If
+// no pattern matches, the event is only handled if no path has been included,
+// i.e. if the filter only contains excludes.
+func (f *Filter) ShouldHandle(eventPath string) bool {
+	handle, matched := false, false
+	f.patterns.WalkPath(clean(eventPath), func(_ string, include bool) bool {
+		handle, matched = include, true
+		return true
+	})
+	if !matched {
+		return f.includes.Load() == 0
+	}
+	return handle
+}
+
+// clean returns the path with forward slashes and without redundant
+// elements.
+func clean(path string) string {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if path == "." {
+		return ""
+	}
+	return path
+}
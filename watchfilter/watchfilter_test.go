@@ -0,0 +1,39 @@
+package watchfilter_test
+
+import (
+	"testing"
+
+	"moehl.dev/trie/watchfilter"
+)
+
+func TestFilter(t *testing.T) {
+	f := watchfilter.New()
+	f.Exclude("/repo/.git")
+	if !f.ShouldHandle("/repo/main.go") || f.ShouldHandle("/repo/.git/index") {
+		t.Errorf("expected only .git to be excluded without includes")
+	}
+
+	f.Include("/repo/src")
+	f.Exclude("/repo/src/vendor")
+	f.Include("/repo/src/vendor/moehl.dev/")
+
+	for path, expected := range map[string]bool{
+		"/repo/src/main.go":                  true,
+		"/repo/src/../src/main.go":           true,
+		"/repo/src/vendor/x/y.go":            false,
+		"/repo/src/vendor":                   false,
+		"/repo/src/vendor/moehl.dev/trie.go": true,
+		"/repo/srcs/main.go":                 false,
+		"/repo/.git/HEAD":                    false,
+		"/other":                             false,
+	} {
+		if got := f.ShouldHandle(path); got != expected {
+			t.Errorf("%s: expected %v but got %v", path, expected, got)
+		}
+	}
+
+	f.Exclude("/repo/src")
+	if f.ShouldHandle("/repo/src/main.go") {
+		t.Errorf("expected /repo/src to be excluded")
+	}
+}